@@ -20,9 +20,16 @@ import (
 	"linda-salon-api/internal/database"
 	"linda-salon-api/internal/handler"
 	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/notify"
+	"linda-salon-api/internal/phone"
 	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
 )
 
+// oauthHTTPTimeout bounds calls to OAuth providers so a hung endpoint can't
+// block login callbacks indefinitely
+const oauthHTTPTimeout = 10 * time.Second
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -32,6 +39,11 @@ func main() {
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.GinMode)
+	handler.SetStrictJSON(cfg.Server.StrictJSON)
+	// 需在資料庫遷移前設定，電話號碼正規化的遷移會用到
+	if err := phone.SetDefaultRegion(cfg.Server.PhoneRegion); err != nil {
+		log.Fatalf("❌ Invalid PHONE_DEFAULT_REGION: %v", err)
+	}
 
 	// Initialize database
 	db, err := database.New(&cfg.Database)
@@ -45,22 +57,25 @@ func main() {
 		log.Fatalf("❌ Failed to run migrations: %v", err)
 	}
 
-	// Initialize AWS S3 client
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
-		awsconfig.WithRegion(cfg.AWS.Region),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+	// Initialize AWS S3 client, with the static keys when they're set and the
+	// default credential chain (IAM role, etc.) otherwise
+	awsOpts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(cfg.AWS.Region)}
+	if cfg.AWS.AccessKeyID != "" && cfg.AWS.SecretAccessKey != "" {
+		awsOpts = append(awsOpts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
 			cfg.AWS.AccessKeyID,
 			cfg.AWS.SecretAccessKey,
 			"",
-		)),
-	)
+		)))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsOpts...)
 	if err != nil {
 		log.Fatalf("❌ Failed to load AWS config: %v", err)
 	}
 	s3Client := s3.NewFromConfig(awsCfg)
+	s3Service := service.NewS3ServiceWithClient(s3Client, cfg.AWS.S3Bucket, cfg.AWS.Region)
 
 	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(&cfg.JWT)
+	jwtManager := auth.NewJWTManager(&cfg.JWT, auth.NewMemoryBlacklist(10*time.Minute, cfg.JWT.Leeway))
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.DB)
@@ -68,19 +83,52 @@ func main() {
 	stylistRepo := repository.NewStylistRepository(db.DB)
 	bookingRepo := repository.NewBookingRepository(db.DB)
 	settingsRepo := repository.NewSettingsRepository(db.DB)
+	holdRepo := repository.NewBookingHoldRepository(db.DB)
+	reviewRepo := repository.NewReviewRepository(db.DB)
+	auditRepo := repository.NewAuditLogRepository(db.DB)
+	loyaltyRepo := repository.NewLoyaltyRepository(db.DB)
+
+	// Seed default settings (existing values are kept)
+	seeded, err := service.SeedDefaultSettings(settingsRepo)
+	if err != nil {
+		log.Fatalf("❌ Failed to seed default settings: %v", err)
+	}
+	if seeded > 0 {
+		log.Printf("✅ Seeded %d default settings", seeded)
+	}
+
+	// Initialize services
+	availabilityService := service.NewAvailabilityService(stylistRepo, bookingRepo, holdRepo, settingsRepo, cfg.Server.Location())
+	notificationService := service.NewNotificationService(settingsRepo, notify.NewEmailSender(&cfg.SMTP))
+	reminderService := service.NewReminderService(bookingRepo, settingsRepo, notificationService, cfg.Server.Location())
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(userRepo, jwtManager)
-	serviceHandler := handler.NewServiceHandler(serviceRepo)
-	stylistHandler := handler.NewStylistHandlerWithBooking(stylistRepo, bookingRepo)
-	bookingHandler := handler.NewBookingHandler(bookingRepo, serviceRepo, stylistRepo, userRepo)
-	statsHandler := handler.NewStatisticsHandler(bookingRepo, stylistRepo)
-	uploadHandler := handler.NewUploadHandler(s3Client, &cfg.AWS)
-	userHandler := handler.NewUserHandler(userRepo, bookingRepo)
+	authHandler := handler.NewAuthHandler(userRepo, stylistRepo, loyaltyRepo, jwtManager, s3Service, notificationService, &cfg.Auth, &cfg.Google, &http.Client{Timeout: oauthHTTPTimeout})
+	serviceHandler := handler.NewServiceHandler(serviceRepo, availabilityService, auditRepo)
+	stylistHandler := handler.NewStylistHandlerWithBooking(stylistRepo, bookingRepo, serviceRepo, settingsRepo, availabilityService, auditRepo)
+	bookingHandler := handler.NewBookingHandler(bookingRepo, serviceRepo, stylistRepo, userRepo, holdRepo, settingsRepo, reviewRepo, auditRepo, loyaltyRepo, notificationService, availabilityService, &cfg.Booking)
+	statsHandler := handler.NewStatisticsHandler(bookingRepo, stylistRepo, cfg.Server.Location())
+	uploadHandler := handler.NewUploadHandler(s3Client, &cfg.AWS, &cfg.Upload)
+	userHandler := handler.NewUserHandler(userRepo, bookingRepo, loyaltyRepo, auditRepo)
 	settingsHandler := handler.NewSettingsHandler(settingsRepo)
+	activityHandler := handler.NewActivityHandler(bookingRepo, userRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	maintenanceHandler := handler.NewMaintenanceHandler(userRepo, bookingRepo, serviceRepo, auditRepo)
+	auditLogHandler := handler.NewAuditLogHandler(auditRepo, cfg.Server.Location())
+
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		log.Fatalf("❌ Failed to get database instance: %v", err)
+	}
+	requestStats := middleware.NewRequestStats()
+	opsHandler := handler.NewOpsHandler(db, sqlDB, requestStats)
+
+	// Start background jobs
+	go sweepExpiredHolds(holdRepo)
+	go sendBookingReminders(reminderService, cfg.Reminder)
 
 	// Setup router
-	router := setupRouter(cfg, jwtManager, authHandler, serviceHandler, stylistHandler, bookingHandler, statsHandler, uploadHandler, userHandler, settingsHandler)
+	router := setupRouter(cfg, jwtManager, requestStats, authHandler, serviceHandler, stylistHandler, bookingHandler, statsHandler, uploadHandler, userHandler, settingsHandler, opsHandler, activityHandler, notificationHandler, maintenanceHandler, auditLogHandler)
 
 	// Start server
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
@@ -120,6 +168,7 @@ func main() {
 func setupRouter(
 	cfg *config.Config,
 	jwtManager *auth.JWTManager,
+	requestStats *middleware.RequestStats,
 	authHandler *handler.AuthHandler,
 	serviceHandler *handler.ServiceHandler,
 	stylistHandler *handler.StylistHandler,
@@ -128,21 +177,30 @@ func setupRouter(
 	uploadHandler *handler.UploadHandler,
 	userHandler *handler.UserHandler,
 	settingsHandler *handler.SettingsHandler,
+	opsHandler *handler.OpsHandler,
+	activityHandler *handler.ActivityHandler,
+	notificationHandler *handler.NotificationHandler,
+	maintenanceHandler *handler.MaintenanceHandler,
+	auditLogHandler *handler.AuditLogHandler,
 ) *gin.Engine {
 	router := gin.New()
 
 	// Middleware
-	router.Use(middleware.Logger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Logger(cfg.Server.LogFormat))
+	router.Use(middleware.Stats(requestStats))
 	router.Use(middleware.CORS(&cfg.CORS))
-	router.Use(gin.Recovery())
+	router.Use(middleware.Recovery())
 
-	// Health check
+	// Health check (liveness: the process is up, no dependencies checked)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "ok",
 			"time":   time.Now().Format(time.RFC3339),
 		})
 	})
+	// Readiness: database reachable and migrations applied
+	router.GET("/health/ready", opsHandler.Ready)
 
 	// PWA Manifest (public)
 	router.GET("/manifest.json", settingsHandler.GetManifest)
@@ -153,59 +211,101 @@ func setupRouter(
 		// Public settings routes
 		settings := v1.Group("/settings")
 		{
+			settings.GET("", settingsHandler.GetPublicSettings)
+			settings.GET("/contact", settingsHandler.GetSalonContact)
 			settings.GET("/branding", settingsHandler.GetBranding)
 			settings.GET("/pwa/icons", settingsHandler.GetPWAIcons)
+			settings.GET("/pwa/sw-config", settingsHandler.GetServiceWorkerConfig)
 		}
 
 		// Public routes
 		auth := v1.Group("/auth")
+		auth.Use(middleware.RateLimit(middleware.NewTokenBucketLimiter(cfg.Auth.RateLimitPerMinute)))
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/logout", authHandler.Logout)
 			auth.POST("/refresh", authHandler.RefreshToken)
-			auth.GET("/google/login", authHandler.GoogleLoginURL)
-			auth.GET("/google/callback", authHandler.GoogleCallback)
-			auth.GET("/line/login", authHandler.LineLoginURL)
-			auth.GET("/line/callback", authHandler.LineCallback)
+			auth.POST("/forgot-password", middleware.RateLimit(middleware.NewRateLimiter(5, 15*time.Minute)), authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			if cfg.Google.Enabled() {
+				auth.GET("/google/login", authHandler.GoogleLoginURL)
+				auth.GET("/google/callback", authHandler.GoogleCallback)
+			}
+			if cfg.Auth.LineEnabled() {
+				auth.GET("/line/login", authHandler.LineLoginURL)
+				auth.GET("/line/callback", authHandler.LineCallback)
+			}
 		}
 
 		// Public service routes
 		services := v1.Group("/services")
 		{
 			services.GET("", serviceHandler.ListServices)
+			services.GET("/blackouts", serviceHandler.ListActiveBlackouts)
 			services.GET("/:id", serviceHandler.GetService)
+			services.GET("/:id/availability", serviceHandler.GetAvailability)
 		}
 
+		// Public category routes
+		v1.GET("/categories", serviceHandler.ListCategories)
+
 		// Public stylist routes
 		stylists := v1.Group("/stylists")
 		{
 			stylists.GET("", stylistHandler.ListStylists)
+			stylists.GET("/specialties", stylistHandler.GetSpecialties)
 			stylists.GET("/:id", stylistHandler.GetStylist)
 			stylists.GET("/:id/schedules", stylistHandler.GetSchedules)
-			stylists.GET("/:id/available-slots", stylistHandler.GetAvailableSlots)
+			stylists.GET("/:id/working-days", stylistHandler.GetWorkingDays)
+			stylists.GET("/:id/services", stylistHandler.GetServices)
+			// Optional auth: exclude_booking_id is honoured only for the booking's owner or an admin
+			stylists.GET("/:id/available-slots", middleware.OptionalAuth(jwtManager), stylistHandler.GetAvailableSlots)
+			stylists.GET("/:id/next-available", stylistHandler.GetNextAvailable)
+			stylists.GET("/:id/suggested-slots", stylistHandler.GetSuggestedSlots)
 		}
 
+		// Services, stylists and rules for the booking screen in one call
+		v1.GET("/bookings/bootstrap", bookingHandler.GetBootstrap)
+
+		// Guest booking lookup by reference + phone; rate-limited against guessing references
+		v1.GET("/bookings/lookup", middleware.RateLimit(middleware.NewRateLimiter(10, 15*time.Minute)), bookingHandler.LookupBooking)
+
 		// Protected routes (require authentication)
 		protected := v1.Group("")
 		protected.Use(middleware.AuthRequired(jwtManager))
 		{
 			// User profile
 			protected.GET("/auth/profile", authHandler.GetProfile)
+			protected.PATCH("/auth/profile", authHandler.UpdateProfile)
+			protected.DELETE("/auth/profile/avatar", authHandler.DeleteAvatar)
+			protected.GET("/auth/loyalty/history", authHandler.GetLoyaltyHistory)
 
 			// Bookings
 			bookings := protected.Group("/bookings")
 			{
 				bookings.GET("", bookingHandler.ListBookings)
 				bookings.GET("/:id", bookingHandler.GetBooking)
+				bookings.GET("/:id/ics", bookingHandler.GetBookingICS)
 				bookings.POST("", bookingHandler.CreateBooking)
+				bookings.POST("/quote", bookingHandler.QuoteBooking)
+				bookings.GET("/end-time", bookingHandler.GetEndTime)
 				bookings.POST("/:id/cancel", bookingHandler.CancelBooking)
+				bookings.POST("/:id/review", bookingHandler.CreateReview)
+				bookings.PATCH("/:id/reschedule", bookingHandler.RescheduleBooking)
+				// Deprecated: POST alias of PATCH /:id/reschedule kept for older clients; remove once they've updated
+				bookings.POST("/:id/reschedule", bookingHandler.RescheduleBooking)
+				bookings.GET("/:id/reschedule/preview", bookingHandler.PreviewReschedule)
+				bookings.POST("/hold", bookingHandler.CreateHold)
+				bookings.POST("/:id/confirm", bookingHandler.ConfirmHold)
+				bookings.POST("/:id/release", bookingHandler.ReleaseHold)
 			}
 
 			// Upload
 			upload := protected.Group("/upload")
 			{
 				upload.POST("/image", uploadHandler.UploadImage)
+				upload.POST("/presign", uploadHandler.PresignUpload)
 			}
 		}
 
@@ -214,28 +314,74 @@ func setupRouter(
 		admin.Use(middleware.AdminRequired(jwtManager))
 		{
 			// Service management
+			admin.GET("/services", serviceHandler.ListServices)
 			admin.POST("/services", serviceHandler.CreateService)
+			admin.GET("/services/availability", serviceHandler.GetServicesAvailability)
 			admin.PUT("/services/:id", serviceHandler.UpdateService)
 			admin.DELETE("/services/:id", serviceHandler.DeleteService)
+			admin.POST("/services/:id/restore", serviceHandler.RestoreService)
+			admin.GET("/services/:id/variants", serviceHandler.ListVariants)
+			admin.POST("/services/:id/variants", serviceHandler.CreateVariant)
+			admin.PUT("/services/:id/variants/:variantId", serviceHandler.UpdateVariant)
+			admin.DELETE("/services/:id/variants/:variantId", serviceHandler.DeleteVariant)
+			admin.GET("/services/:id/blackouts", serviceHandler.ListBlackouts)
+			admin.POST("/services/:id/blackouts", serviceHandler.CreateBlackout)
+			admin.PUT("/services/:id/blackouts/:blackoutId", serviceHandler.UpdateBlackout)
+			admin.DELETE("/services/:id/blackouts/:blackoutId", serviceHandler.DeleteBlackout)
+			admin.PATCH("/services/category/:category/active", serviceHandler.SetCategoryActive)
+			admin.GET("/categories", serviceHandler.ListCategories)
+			admin.POST("/categories", serviceHandler.CreateCategory)
+			admin.PUT("/categories/:id", serviceHandler.UpdateCategory)
+			admin.DELETE("/categories/:id", serviceHandler.DeleteCategory)
 
 			// Stylist management
+			admin.GET("/stylists", stylistHandler.ListStylists)
 			admin.POST("/stylists", stylistHandler.CreateStylist)
 			admin.PUT("/stylists/:id", stylistHandler.UpdateStylist)
 			admin.DELETE("/stylists/:id", stylistHandler.DeleteStylist)
+			admin.POST("/stylists/:id/restore", stylistHandler.RestoreStylist)
 			admin.POST("/stylists/:id/schedules", stylistHandler.CreateSchedule)
+			admin.POST("/stylists/:id/schedules/apply-template", stylistHandler.ApplyScheduleTemplate)
+			admin.POST("/stylists/:id/schedules/bulk", stylistHandler.CreateSchedulesBulk)
 			admin.DELETE("/stylists/schedules/:id", stylistHandler.DeleteSchedule)
+			admin.GET("/stylists/:id/time-off", stylistHandler.ListTimeOff)
+			admin.POST("/stylists/:id/time-off", stylistHandler.CreateTimeOff)
+			admin.PUT("/stylists/:id/time-off/:timeOffId", stylistHandler.UpdateTimeOff)
+			admin.DELETE("/stylists/:id/time-off/:timeOffId", stylistHandler.DeleteTimeOff)
+			admin.GET("/stylists/:id/exceptions", stylistHandler.GetExceptions)
+			admin.POST("/stylists/:id/services", stylistHandler.AddServices)
+			admin.DELETE("/stylists/:id/services/:serviceId", stylistHandler.RemoveService)
 
 			// Booking management
+			admin.GET("/bookings/conflicts", bookingHandler.GetBookingConflicts)
+			admin.GET("/bookings/status-counts", bookingHandler.GetBookingStatusCounts)
+			admin.GET("/day-sheet", bookingHandler.GetDaySheet)
+			admin.GET("/walkin-availability", stylistHandler.GetWalkInAvailability)
 			admin.PATCH("/bookings/:id/status", bookingHandler.UpdateBookingStatus)
+			admin.PATCH("/bookings/:id/admin-notes", bookingHandler.UpdateAdminNotes)
+			admin.PUT("/bookings/:id/tags", bookingHandler.SetBookingTags)
+			admin.PATCH("/bookings/:id/schedule", bookingHandler.RescheduleBooking)
 
 			// Statistics
 			admin.GET("/statistics/dashboard", statsHandler.GetDashboardStats)
 			admin.GET("/statistics/revenue", statsHandler.GetRevenueReport)
+			admin.GET("/statistics/summary", statsHandler.GetSummary)
+			admin.GET("/statistics/compare", statsHandler.GetPeriodComparison)
+			admin.GET("/statistics/no-shows", statsHandler.GetNoShowStats)
+			admin.GET("/statistics/weekday-revenue", statsHandler.GetWeekdayRevenue)
+			admin.GET("/statistics/stylists", statsHandler.GetStylistStats)
+			admin.GET("/activity", activityHandler.GetActivity)
+			admin.GET("/audit-logs", auditLogHandler.ListAuditLogs)
 
 			// User management
 			admin.GET("/users", userHandler.ListUsers)
+			admin.GET("/users/blocked", userHandler.ListBlockedUsers)
 			admin.GET("/users/:id", userHandler.GetUser)
 			admin.GET("/users/:id/bookings", userHandler.GetUserBookings)
+			admin.GET("/users/:id/ltv", userHandler.GetUserLifetimeValue)
+			admin.POST("/users/:id/block", userHandler.BlockUser)
+			admin.POST("/users/:id/unblock", userHandler.UnblockUser)
+			admin.POST("/users/:id/loyalty", userHandler.AdjustLoyaltyPoints)
 
 			// Upload management
 			admin.DELETE("/upload/image", uploadHandler.DeleteImage)
@@ -243,8 +389,89 @@ func setupRouter(
 			// Settings management
 			admin.PUT("/settings/branding", settingsHandler.UpdateBranding)
 			admin.PUT("/settings/pwa/icons", settingsHandler.UpdatePWAIcons)
+			admin.PUT("/settings/pwa/sw-config", settingsHandler.UpdateServiceWorkerConfig)
+			admin.PUT("/settings/contact", settingsHandler.UpdateSalonContact)
+			admin.GET("/settings/rules", settingsHandler.GetRules)
+			admin.PUT("/settings/rules", settingsHandler.UpdateRules)
+			admin.POST("/notifications/test", middleware.RateLimit(middleware.NewRateLimiter(5, time.Minute)), notificationHandler.SendTestNotification)
+			admin.GET("/settings/notifications/recipients", settingsHandler.GetNotificationRecipients)
+			admin.PUT("/settings/notifications/recipients", settingsHandler.UpdateNotificationRecipients)
+			admin.GET("/settings/schedule-templates", settingsHandler.GetScheduleTemplates)
+			admin.PUT("/settings/schedule-templates", settingsHandler.UpdateScheduleTemplates)
+
+			// Ops
+			admin.GET("/ops/stats", opsHandler.GetStats)
+			admin.POST("/maintenance/purge", maintenanceHandler.PurgeDeleted)
 		}
 	}
 
 	return router
 }
+
+// sweepExpiredHolds periodically deletes expired booking holds. Availability
+// checks already ignore expired holds; this just keeps the table small.
+func sweepExpiredHolds(holdRepo *repository.BookingHoldRepository) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := holdRepo.DeleteExpired()
+		if err != nil {
+			log.Printf("⚠️  Failed to sweep expired holds: %v", err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("🧹 Released %d expired booking hold(s)", count)
+		}
+	}
+}
+
+// sendBookingReminders emails booking reminders: once a day at the configured
+// send time for the bookings DaysAhead days out, or otherwise periodically
+// for bookings that have entered each customer's reminder window
+func sendBookingReminders(reminderService *service.ReminderService, cfg config.ReminderConfig) {
+	if cfg.Daily() {
+		sendDailyBookingReminders(reminderService, cfg)
+		return
+	}
+
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := reminderService.SendDue(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Failed to send booking reminders: %v", err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("⏰ Sent %d booking reminder(s)", count)
+		}
+	}
+}
+
+// sendDailyBookingReminders runs SendForDate at cfg.SendTime every day. If
+// the process starts after today's send time it runs once right away, since
+// bookings already reminded are skipped.
+func sendDailyBookingReminders(reminderService *service.ReminderService, cfg config.ReminderConfig) {
+	now := time.Now()
+	next := reminderService.NextDailyRun(now, cfg.SendTime)
+	if !reminderService.SalonDate(next, 0).Equal(reminderService.SalonDate(now, 0)) {
+		runDailyBookingReminders(reminderService, cfg, now)
+	}
+
+	for {
+		next := reminderService.NextDailyRun(time.Now(), cfg.SendTime)
+		time.Sleep(time.Until(next))
+		runDailyBookingReminders(reminderService, cfg, time.Now())
+	}
+}
+
+func runDailyBookingReminders(reminderService *service.ReminderService, cfg config.ReminderConfig, now time.Time) {
+	count, err := reminderService.SendForDate(reminderService.SalonDate(now, cfg.DaysAhead), now)
+	if err != nil {
+		log.Printf("⚠️  Failed to send booking reminders: %v", err)
+		return
+	}
+	log.Printf("⏰ Sent %d booking reminder(s) for %d day(s) ahead", count, cfg.DaysAhead)
+}
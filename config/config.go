@@ -2,10 +2,15 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata" // 容器映像可能沒有系統時區資料
 
 	"github.com/joho/godotenv"
+	"linda-salon-api/internal/phone"
 )
 
 type Config struct {
@@ -13,12 +18,43 @@ type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
 	AWS      AWSConfig
+	Upload   UploadConfig
 	CORS     CORSConfig
+	Booking  BookingConfig
+	Auth     AuthConfig
+	Google   GoogleOAuthConfig
+	SMTP     SMTPConfig
+	Reminder ReminderConfig
 }
 
 type ServerConfig struct {
-	Port    string
-	GinMode string
+	Port      string
+	GinMode   string
+	Timezone  string // 店家時區 (IANA)，例如 Asia/Taipei
+	LogFormat string // 請求日誌格式：text（預設，適合本機開發）或 json（給日誌收集系統）
+
+	// StrictJSON 建立/更新預約、服務、設計師時拒絕未知的 JSON 欄位，
+	// 方便整合時及早發現欄位名稱打錯（預設關閉以相容舊用戶端）
+	StrictJSON bool
+
+	// PhoneRegion 沒有國碼的電話號碼視為哪個地區 (ISO 3166-1)，例如 TW
+	PhoneRegion string
+
+	location *time.Location
+}
+
+// Location returns the salon's timezone, falling back to UTC (with a logged
+// warning) when Timezone isn't a valid zone name
+func (c *ServerConfig) Location() *time.Location {
+	if c.location == nil {
+		location, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			log.Printf("⚠️  Invalid SALON_TIMEZONE %q, falling back to UTC: %v", c.Timezone, err)
+			location = time.UTC
+		}
+		c.location = location
+	}
+	return c.location
 }
 
 type DatabaseConfig struct {
@@ -34,6 +70,8 @@ type JWTConfig struct {
 	Secret                string
 	Expiration            time.Duration
 	RefreshTokenExpiration time.Duration
+	Leeway                 time.Duration // tolerated clock skew when validating exp/nbf/iat
+	ResetTokenExpiration   time.Duration
 }
 
 type AWSConfig struct {
@@ -43,18 +81,161 @@ type AWSConfig struct {
 	S3Bucket        string
 }
 
+// UploadConfig 圖片上傳設定
+type UploadConfig struct {
+	ThumbnailSize int // 縮圖最長邊的像素，上傳圖片時一併產生
+
+	MaxBytes          int64            // 單一檔案大小上限
+	FolderMaxBytes    map[string]int64 // 個別資料夾的大小上限，優先於 MaxBytes
+	AllowedExtensions []string         // 允許的副檔名（如 .jpg），空白表示伺服器支援的全部格式
+	// AvatarMinDimension 頭像（avatars、stylists 資料夾）寬高至少需要的像素，0 表示不檢查
+	AvatarMinDimension int
+}
+
+// avatarFolders hold profile pictures, which get the minimum dimension check
+var avatarFolders = map[string]bool{"avatars": true, "stylists": true}
+
+// MaxBytesFor returns the upload size limit for a folder
+func (c *UploadConfig) MaxBytesFor(folder string) int64 {
+	if limit, ok := c.FolderMaxBytes[folder]; ok {
+		return limit
+	}
+	return c.MaxBytes
+}
+
+// MinDimensionFor returns the smallest width and height allowed in a
+// folder, or 0 when images there aren't checked
+func (c *UploadConfig) MinDimensionFor(folder string) int {
+	if avatarFolders[folder] {
+		return c.AvatarMinDimension
+	}
+	return 0
+}
+
+// ExtensionAllowed reports whether files with ext (lowercase, with the dot)
+// may be uploaded
+func (c *UploadConfig) ExtensionAllowed(ext string) bool {
+	if len(c.AllowedExtensions) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedExtensions {
+		if allowed == ext {
+			return true
+		}
+	}
+	return false
+}
+
 type CORSConfig struct {
 	AllowedOrigins []string
 }
 
+type BookingConfig struct {
+	HoldMinutes    int            // 暫時保留時段的有效分鐘數
+	MinLeadMinutes int            // 至少需提前多少分鐘預約
+	Location       *time.Location // 店家時區 (同 ServerConfig.Location)，判斷「今天」與「現在」用
+}
+
+// GoogleOAuthConfig holds the Google OAuth client. Login with Google is
+// enabled only when ClientID is set.
+type GoogleOAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	// Google endpoints, overridable for tests
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+}
+
+// Enabled reports whether Google login is configured
+func (c *GoogleOAuthConfig) Enabled() bool {
+	return c.ClientID != ""
+}
+
+// Validate checks that an enabled Google client is fully configured
+func (c *GoogleOAuthConfig) Validate() error {
+	if !c.Enabled() {
+		if c.ClientSecret != "" || c.RedirectURL != "" {
+			return fmt.Errorf("GOOGLE_CLIENT_ID is required when Google OAuth is configured")
+		}
+		return nil
+	}
+	if c.ClientSecret == "" {
+		return fmt.Errorf("GOOGLE_CLIENT_SECRET is required when GOOGLE_CLIENT_ID is set")
+	}
+	if c.RedirectURL == "" {
+		return fmt.Errorf("GOOGLE_REDIRECT_URL is required when GOOGLE_CLIENT_ID is set")
+	}
+	return nil
+}
+
+// SMTPConfig 寄送通知信用的 SMTP 伺服器，未設定 Host 時只記錄到 log
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+// Enabled reports whether an SMTP server is configured
+func (c *SMTPConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// ReminderConfig 預約提醒排程。設定 SendTime 時每天固定時間寄出
+// DaysAhead 天後的預約提醒；未設定時每隔 CheckInterval 依各顧客的
+// 提前小時數寄送。
+type ReminderConfig struct {
+	SendTime      string        // HH:MM (店家時區)，空字串表示不使用每日排程
+	DaysAhead     int           // 每日排程提醒幾天後的預約
+	CheckInterval time.Duration // 依提前小時數寄送時的檢查間隔
+}
+
+// Daily reports whether reminders go out once a day at SendTime
+func (c *ReminderConfig) Daily() bool {
+	return c.SendTime != ""
+}
+
+type AuthConfig struct {
+	BootstrapAdminEmail string // 尚無管理員時，以此信箱註冊的帳號會成為第一位管理員
+
+	FrontendURL string // OAuth 完成後導回、以及信件連結使用的前端網址
+
+	RateLimitPerMinute int // 每個 IP 每分鐘可呼叫 /auth 端點的次數
+
+	LineChannelID     string
+	LineChannelSecret string
+	LineRedirectURL   string
+
+	// LINE endpoints, overridable for tests
+	LineAuthURL    string
+	LineTokenURL   string
+	LineVerifyURL  string
+	LineProfileURL string
+}
+
+// LineEnabled reports whether LINE login is fully configured. A partial
+// configuration is rejected at startup in release mode and leaves LINE
+// login off otherwise.
+func (c *AuthConfig) LineEnabled() bool {
+	return c.LineChannelID != "" && c.LineChannelSecret != "" && c.LineRedirectURL != ""
+}
+
 func Load() (*Config, error) {
 	// Load .env file if exists (for local development)
 	godotenv.Load()
 
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:      getEnv("PORT", "8080"),
+			GinMode:   getEnv("GIN_MODE", "debug"),
+			Timezone:  getEnv("SALON_TIMEZONE", "Asia/Taipei"),
+			LogFormat: getEnv("LOG_FORMAT", "text"),
+			// 正規化為大寫，與 phone.SetDefaultRegion 一致
+			PhoneRegion: strings.ToUpper(getEnv("PHONE_DEFAULT_REGION", phone.DefaultRegion)),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -65,25 +246,214 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
-			Secret:                getEnv("JWT_SECRET", "change-this-secret-key"),
+			Secret:                getEnv("JWT_SECRET", defaultJWTSecret),
 			Expiration:            parseDuration(getEnv("JWT_EXPIRATION", "24h")),
 			RefreshTokenExpiration: parseDuration(getEnv("REFRESH_TOKEN_EXPIRATION", "168h")),
+			ResetTokenExpiration:   parseDuration(getEnv("PASSWORD_RESET_EXPIRATION", "30m")),
 		},
 		AWS: AWSConfig{
 			Region:          getEnv("AWS_REGION", "ap-northeast-1"),
 			AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
 			SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
-			S3Bucket:        getEnv("S3_BUCKET", "linda-salon-uploads"),
+			S3Bucket:        getEnv("S3_BUCKET", os.Getenv("AWS_S3_BUCKET")),
+		},
+		Upload: UploadConfig{
+			ThumbnailSize:      parseInt(getEnv("UPLOAD_THUMBNAIL_SIZE", "300"), 300),
+			AvatarMinDimension: parseInt(getEnv("UPLOAD_AVATAR_MIN_DIMENSION", "64"), 64),
+		},
+		Booking: BookingConfig{
+			HoldMinutes:    parseInt(getEnv("BOOKING_HOLD_MINUTES", "10"), 10),
+			MinLeadMinutes: parseInt(getEnv("BOOKING_MIN_LEAD_MINUTES", "60"), 60),
 		},
+		Auth: AuthConfig{
+			BootstrapAdminEmail: getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+			FrontendURL:         strings.TrimRight(getEnv("FRONTEND_URL", ""), "/"),
+			RateLimitPerMinute:  parseInt(getEnv("AUTH_RATE_LIMIT_PER_MINUTE", "20"), 20),
+			LineChannelID:       getEnv("LINE_CHANNEL_ID", ""),
+			LineChannelSecret:   getEnv("LINE_CHANNEL_SECRET", ""),
+			LineRedirectURL:     getEnv("LINE_REDIRECT_URL", ""),
+			LineAuthURL:         "https://access.line.me/oauth2/v2.1/authorize",
+			LineTokenURL:        "https://api.line.me/oauth2/v2.1/token",
+			LineVerifyURL:       "https://api.line.me/oauth2/v2.1/verify",
+			LineProfileURL:      "https://api.line.me/v2/profile",
+		},
+		Google: GoogleOAuthConfig{
+			ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", ""),
+			AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL:     "https://oauth2.googleapis.com/token",
+			UserInfoURL:  "https://www.googleapis.com/oauth2/v2/userinfo",
+		},
+		Reminder: ReminderConfig{
+			SendTime:      getEnv("REMINDER_SEND_TIME", ""),
+			DaysAhead:     parseInt(getEnv("REMINDER_DAYS_AHEAD", "1"), 1),
+			CheckInterval: parseDuration(getEnv("REMINDER_CHECK_INTERVAL", "5m")),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "587"),
+			User:     getEnv("SMTP_USER", ""),
+			Password: getEnv("SMTP_PASS", ""),
+			From:     getEnv("SMTP_FROM", ""),
+		},
+	}
+
+	var problems []string
+	if cfg.Server.LogFormat != "text" && cfg.Server.LogFormat != "json" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be text or json, got %q", cfg.Server.LogFormat))
+	}
+	strictJSON, err := strconv.ParseBool(getEnv("STRICT_JSON", "false"))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("STRICT_JSON must be true or false, got %q", getEnv("STRICT_JSON", "")))
+	}
+	cfg.Server.StrictJSON = strictJSON
+	// 解析失敗時不可退回 parseDuration 的 24h，否則過期一天的 token 仍會被接受
+	cfg.JWT.Leeway, err = time.ParseDuration(getEnv("JWT_LEEWAY", defaultJWTLeeway.String()))
+	if err != nil || cfg.JWT.Leeway < 0 || cfg.JWT.Leeway > maxJWTLeeway {
+		problems = append(problems, fmt.Sprintf("JWT_LEEWAY must be a duration between 0s and %s, got %q", maxJWTLeeway, getEnv("JWT_LEEWAY", "")))
+	}
+	if !phone.IsSupportedRegion(cfg.Server.PhoneRegion) {
+		problems = append(problems, fmt.Sprintf("PHONE_DEFAULT_REGION must be a two-letter region code such as TW, got %q", cfg.Server.PhoneRegion))
+	}
+	if err := cfg.Google.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if cfg.SMTP.Enabled() && cfg.SMTP.From == "" {
+		problems = append(problems, "SMTP_FROM is required when SMTP_HOST is set")
+	}
+	if cfg.Upload.ThumbnailSize < 16 || cfg.Upload.ThumbnailSize > 2000 {
+		problems = append(problems, "UPLOAD_THUMBNAIL_SIZE must be between 16 and 2000")
+	}
+	cfg.Upload.MaxBytes, err = strconv.ParseInt(getEnv("UPLOAD_MAX_BYTES", strconv.Itoa(defaultUploadMaxBytes)), 10, 64)
+	if err != nil || cfg.Upload.MaxBytes < 1 {
+		problems = append(problems, fmt.Sprintf("UPLOAD_MAX_BYTES must be a positive number of bytes, got %q", getEnv("UPLOAD_MAX_BYTES", "")))
+	}
+	cfg.Upload.FolderMaxBytes, err = parseFolderLimits(getEnv("UPLOAD_FOLDER_MAX_BYTES", ""))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("UPLOAD_FOLDER_MAX_BYTES: %v", err))
+	}
+	for _, ext := range parseCSV(strings.ToLower(strings.ReplaceAll(getEnv("UPLOAD_ALLOWED_EXTENSIONS", ""), " ", ""))) {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		cfg.Upload.AllowedExtensions = append(cfg.Upload.AllowedExtensions, ext)
+	}
+	if cfg.Upload.AvatarMinDimension < 0 {
+		problems = append(problems, "UPLOAD_AVATAR_MIN_DIMENSION must not be negative")
+	}
+	if cfg.Auth.RateLimitPerMinute < 1 {
+		problems = append(problems, "AUTH_RATE_LIMIT_PER_MINUTE must be at least 1")
+	}
+	if cfg.Reminder.CheckInterval <= 0 {
+		problems = append(problems, "REMINDER_CHECK_INTERVAL must be positive")
+	}
+	if cfg.Reminder.Daily() {
+		if t, err := time.Parse("15:04", cfg.Reminder.SendTime); err != nil || t.Format("15:04") != cfg.Reminder.SendTime {
+			problems = append(problems, fmt.Sprintf("REMINDER_SEND_TIME must be HH:MM, got %q", cfg.Reminder.SendTime))
+		}
+		if cfg.Reminder.DaysAhead < 1 {
+			problems = append(problems, "REMINDER_DAYS_AHEAD must be at least 1")
+		}
+	}
+
+	// 不安全或缺少的關鍵設定：正式環境 (GIN_MODE=release) 拒絕啟動，開發時只警告
+	if critical := cfg.criticalProblems(); len(critical) > 0 {
+		if cfg.Server.GinMode == releaseMode {
+			problems = append(problems, critical...)
+		} else {
+			for _, problem := range critical {
+				log.Printf("⚠️  Config: %s (fatal when GIN_MODE=release)", problem)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
 
 	// Parse allowed origins
 	originsStr := getEnv("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:3001")
 	cfg.CORS.AllowedOrigins = parseCSV(originsStr)
 
+	// Salon timezone (the database clock runs in UTC)
+	cfg.Booking.Location = cfg.Server.Location()
+
 	return cfg, nil
 }
 
+// defaultJWTLeeway is the clock skew tolerated on token exp/nbf/iat, and
+// maxJWTLeeway caps JWT_LEEWAY so a typo can't keep expired tokens valid
+const (
+	defaultJWTLeeway = 30 * time.Second
+	maxJWTLeeway     = 5 * time.Minute
+)
+
+// defaultUploadMaxBytes is the default upload size limit (5MB)
+const defaultUploadMaxBytes = 5 * 1024 * 1024
+
+// parseFolderLimits parses per-folder size limits given as
+// folder=bytes pairs separated by commas, e.g. "stylists=10485760"
+func parseFolderLimits(s string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, pair := range parseCSV(s) {
+		folder, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || folder == "" {
+			return nil, fmt.Errorf("expected folder=bytes, got %q", pair)
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("limit for %s must be a positive number of bytes, got %q", folder, value)
+		}
+		limits[folder] = limit
+	}
+	return limits, nil
+}
+
+// releaseMode is Gin's production mode (gin.ReleaseMode)
+const releaseMode = "release"
+
+// defaultJWTSecret is the placeholder JWT_SECRET used when none is set
+const defaultJWTSecret = "change-this-secret-key"
+
+// ValidationError lists every problem found in the configuration, so a
+// misconfigured deployment can be fixed in one pass
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// criticalProblems reports settings that leave a production instance
+// insecure or with a broken feature: the placeholder JWT secret, an empty
+// database password, no S3 bucket (uploads always need one), and incomplete
+// LINE login or front-end URL settings for enabled features. S3 credentials
+// aren't required: without static keys the default credential chain (e.g.
+// an IAM role) is used.
+func (c *Config) criticalProblems() []string {
+	var problems []string
+	if c.JWT.Secret == defaultJWTSecret {
+		problems = append(problems, "JWT_SECRET must be set to a secret value")
+	}
+	if c.Database.Password == "" {
+		problems = append(problems, "DB_PASSWORD is empty")
+	}
+	if c.AWS.S3Bucket == "" {
+		problems = append(problems, "S3_BUCKET is required for image uploads")
+	}
+
+	lineEnabled := c.Auth.LineChannelID != "" || c.Auth.LineChannelSecret != "" || c.Auth.LineRedirectURL != ""
+	if lineEnabled && !c.Auth.LineEnabled() {
+		problems = append(problems, "LINE_CHANNEL_ID, LINE_CHANNEL_SECRET and LINE_REDIRECT_URL must all be set for LINE login")
+	}
+	if c.Auth.FrontendURL == "" && (c.Google.Enabled() || lineEnabled || c.SMTP.Enabled()) {
+		problems = append(problems, "FRONTEND_URL is required for OAuth redirects and email links")
+	}
+	return problems
+}
+
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
@@ -106,6 +476,14 @@ func parseDuration(s string) time.Duration {
 	return d
 }
 
+func parseInt(s string, defaultValue int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
 func parseCSV(s string) []string {
 	var result []string
 	for i := 0; i < len(s); {
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadJWTLeeway(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", "", 30 * time.Second, false},
+		{"custom", "10s", 10 * time.Second, false},
+		{"zero", "0s", 0, false},
+		{"at the cap", "5m", 5 * time.Minute, false},
+		{"malformed", "30 seconds", 0, true},
+		{"missing unit", "30", 0, true},
+		{"negative", "-1s", 0, true},
+		{"over the cap", "24h", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIN_MODE", "debug")
+			t.Setenv("JWT_LEEWAY", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Load() error = %v, want a ValidationError", err)
+				}
+				if !strings.Contains(err.Error(), "JWT_LEEWAY") {
+					t.Errorf("Load() error = %v, want it to mention JWT_LEEWAY", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.JWT.Leeway != tt.want {
+				t.Errorf("Leeway = %v, want %v", cfg.JWT.Leeway, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthConfigLineEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AuthConfig
+		want bool
+	}{
+		{"not configured", AuthConfig{}, false},
+		{"fully configured", AuthConfig{LineChannelID: "id", LineChannelSecret: "secret", LineRedirectURL: "https://api.example.com/line/callback"}, true},
+		{"missing secret", AuthConfig{LineChannelID: "id", LineRedirectURL: "https://api.example.com/line/callback"}, false},
+		{"missing redirect URL", AuthConfig{LineChannelID: "id", LineChannelSecret: "secret"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.LineEnabled(); got != tt.want {
+				t.Errorf("LineEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPhoneRegion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "TW", false},
+		{"JP", "JP", false},
+		{"us", "US", false},
+		{"XX", "", true},
+		{"Taiwan", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("GIN_MODE", "debug")
+			t.Setenv("PHONE_DEFAULT_REGION", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "PHONE_DEFAULT_REGION") {
+					t.Fatalf("Load() error = %v, want it to mention PHONE_DEFAULT_REGION", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Server.PhoneRegion != tt.want {
+				t.Errorf("PhoneRegion = %q, want %q", cfg.Server.PhoneRegion, tt.want)
+			}
+		})
+	}
+}
+
+func TestCriticalProblemsS3(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantS3Err bool
+	}{
+		{"static keys and bucket", map[string]string{"AWS_ACCESS_KEY_ID": "key", "AWS_SECRET_ACCESS_KEY": "secret", "S3_BUCKET": "uploads"}, false},
+		{"IAM role without static keys", map[string]string{"S3_BUCKET": "uploads"}, false},
+		{"legacy bucket variable", map[string]string{"AWS_S3_BUCKET": "uploads"}, false},
+		{"no bucket", map[string]string{"AWS_ACCESS_KEY_ID": "key", "AWS_SECRET_ACCESS_KEY": "secret"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIN_MODE", "debug")
+			for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "S3_BUCKET", "AWS_S3_BUCKET"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			var s3Problem bool
+			for _, problem := range cfg.criticalProblems() {
+				if strings.Contains(problem, "S3") || strings.Contains(problem, "AWS") {
+					s3Problem = true
+				}
+			}
+			if s3Problem != tt.wantS3Err {
+				t.Errorf("criticalProblems() = %v, want an S3 problem: %v", cfg.criticalProblems(), tt.wantS3Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package apierror defines the JSON error body returned by the API.
+//
+// Every error carries a human-readable message under "error" (unchanged for
+// older clients) plus a machine-readable "code" frontends can switch on.
+package apierror
+
+import (
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/requestid"
+)
+
+// Error codes
+const (
+	CodeBadRequest       = "BAD_REQUEST"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodeRateLimited      = "RATE_LIMITED"
+	CodeInternal         = "INTERNAL_ERROR"
+
+	// Auth
+	CodeInvalidCredentials  = "INVALID_CREDENTIALS"
+	CodeInvalidToken        = "INVALID_TOKEN"
+	CodeEmailTaken          = "EMAIL_TAKEN"
+	CodePhoneTaken          = "PHONE_TAKEN"
+	CodeInvalidPhone        = "INVALID_PHONE"
+	CodeAccountBlocked      = "ACCOUNT_BLOCKED"
+	CodeWrongPassword       = "WRONG_PASSWORD"
+	CodeInvalidResetToken   = "INVALID_RESET_TOKEN"
+	CodeInvalidRefreshToken = "INVALID_REFRESH_TOKEN"
+
+	// Bookings
+	CodeBookingNotFound      = "BOOKING_NOT_FOUND"
+	CodeBookingSlotTaken     = "BOOKING_SLOT_TAKEN"
+	CodeBookingTooSoon       = "BOOKING_TOO_SOON"
+	CodeBookingTooFarAhead   = "BOOKING_TOO_FAR_AHEAD"
+	CodeBookingNotModifiable = "BOOKING_NOT_MODIFIABLE"
+	CodeCancellationTooLate  = "CANCELLATION_TOO_LATE"
+	CodeInvalidService       = "INVALID_SERVICE"
+	CodeInvalidStylist       = "INVALID_STYLIST"
+	CodeStylistNotQualified  = "STYLIST_NOT_QUALIFIED"
+	CodeServiceBlackedOut    = "SERVICE_BLACKED_OUT"
+	CodeDurationOutOfRange   = "BOOKING_DURATION_OUT_OF_RANGE"
+	CodeInvalidStatus        = "INVALID_STATUS"
+	CodeHoldNotFound         = "HOLD_NOT_FOUND"
+	CodeHoldExpired          = "HOLD_EXPIRED"
+)
+
+// APIError is the JSON body of an error response
+type APIError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+	// RequestID 讓顧客回報問題時可提供給客服對照日誌
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func New(code, message string) *APIError {
+	return &APIError{Code: code, Message: message}
+}
+
+// WithDetails attaches extra structured context, e.g. offending IDs
+func (e *APIError) WithDetails(details interface{}) *APIError {
+	e.Details = details
+	return e
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Abort writes the error as the response and stops the handler chain. The
+// request ID, if any, is included in the body.
+func Abort(c *gin.Context, status int, err *APIError) {
+	if err.RequestID == "" {
+		err.RequestID = requestid.FromContext(c.Request.Context())
+	}
+	c.AbortWithStatusJSON(status, err)
+}
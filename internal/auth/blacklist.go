@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBlacklist stores the IDs (jti) of revoked tokens until they expire.
+// The in-memory implementation only covers a single instance; to share
+// revocations across instances, implement this interface on top of Redis
+// (e.g. SET jti 1 with an expiry of the token's remaining lifetime plus the
+// JWT leeway) and pass it to NewJWTManager instead.
+type TokenBlacklist interface {
+	Revoke(jti string, expiresAt time.Time)
+	IsRevoked(jti string) bool
+}
+
+// MemoryBlacklist is an in-process TokenBlacklist
+type MemoryBlacklist struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+	leeway  time.Duration
+}
+
+// NewMemoryBlacklist creates a blacklist that prunes expired entries every
+// pruneInterval in the background. leeway must be the JWT leeway (JWT_LEEWAY):
+// tokens are still accepted that long after they expire, so their entries
+// are kept until then.
+func NewMemoryBlacklist(pruneInterval, leeway time.Duration) *MemoryBlacklist {
+	b := &MemoryBlacklist{revoked: make(map[string]time.Time), leeway: leeway}
+	go func() {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			b.prune(now)
+		}
+	}()
+	return b
+}
+
+// Revoke blacklists a token until its expiry
+func (b *MemoryBlacklist) Revoke(jti string, expiresAt time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.revoked[jti] = expiresAt
+}
+
+// IsRevoked reports whether a token has been revoked
+func (b *MemoryBlacklist) IsRevoked(jti string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.revoked[jti]
+	return ok
+}
+
+// prune drops entries whose tokens are no longer accepted anyway, i.e.
+// expired by more than the leeway as of now
+func (b *MemoryBlacklist) prune(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for jti, expiresAt := range b.revoked {
+		if now.After(expiresAt.Add(b.leeway)) {
+			delete(b.revoked, jti)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// Entries are kept while an expired token is still accepted within the leeway
+func TestMemoryBlacklistPruneKeepsLeeway(t *testing.T) {
+	leeway := 30 * time.Second
+	b := NewMemoryBlacklist(time.Hour, leeway)
+	expiresAt := time.Now()
+	b.Revoke("jti", expiresAt)
+
+	b.prune(expiresAt.Add(leeway / 2))
+	if !b.IsRevoked("jti") {
+		t.Fatal("entry pruned while the token is still within the leeway")
+	}
+
+	b.prune(expiresAt.Add(leeway + time.Second))
+	if b.IsRevoked("jti") {
+		t.Error("entry kept after the token expired beyond the leeway")
+	}
+}
+
+// A revoked token that has just expired stays rejected after a prune, even
+// though the leeway would otherwise still accept it
+func TestRevokedTokenStaysRevokedWithinLeeway(t *testing.T) {
+	manager := newTestManager(30 * time.Second)
+	token := signToken(t, -10*time.Second, -time.Hour)
+	if _, err := manager.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() error = %v, want the token accepted within the leeway", err)
+	}
+
+	manager.RevokeToken(token)
+	manager.blacklist.(*MemoryBlacklist).prune(time.Now())
+	if _, err := manager.ValidateToken(token); err == nil {
+		t.Error("ValidateToken() accepted a revoked token after pruning")
+	}
+}
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -9,10 +12,18 @@ import (
 	"linda-salon-api/config"
 )
 
+// TokenTypePasswordReset marks tokens that may only be used to reset a password
+const TokenTypePasswordReset = "password_reset"
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// TokenType is empty for access/refresh tokens
+	TokenType string `json:"token_type,omitempty"`
+	// PasswordFingerprint ties a reset token to the password it replaces, so
+	// the token stops working once the password has been changed
+	PasswordFingerprint string `json:"pwd,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -23,11 +34,12 @@ type TokenPair struct {
 }
 
 type JWTManager struct {
-	config *config.JWTConfig
+	config    *config.JWTConfig
+	blacklist TokenBlacklist
 }
 
-func NewJWTManager(cfg *config.JWTConfig) *JWTManager {
-	return &JWTManager{config: cfg}
+func NewJWTManager(cfg *config.JWTConfig, blacklist TokenBlacklist) *JWTManager {
+	return &JWTManager{config: cfg, blacklist: blacklist}
 }
 
 // GenerateTokenPair generates access and refresh tokens
@@ -59,6 +71,7 @@ func (j *JWTManager) generateToken(userID uint, email, role string, duration tim
 		Email:  email,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -69,14 +82,92 @@ func (j *JWTManager) generateToken(userID uint, email, role string, duration tim
 	return token.SignedString([]byte(j.config.Secret))
 }
 
-// ValidateToken validates and parses a JWT token
+// ValidateToken validates and parses an access or refresh token, rejecting
+// tokens that have been revoked
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := j.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "" {
+		return nil, errors.New("invalid token")
+	}
+	if claims.ID != "" && j.blacklist.IsRevoked(claims.ID) {
+		return nil, errors.New("token has been revoked")
+	}
+	return claims, nil
+}
+
+// RevokeToken blacklists a token until it is no longer accepted, i.e. its
+// expiry plus the leeway. Tokens that are already invalid or carry no ID
+// are ignored.
+func (j *JWTManager) RevokeToken(tokenString string) {
+	claims, err := j.parseToken(tokenString)
+	if err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return
+	}
+	j.blacklist.Revoke(claims.ID, claims.ExpiresAt.Time)
+}
+
+// GenerateResetToken creates a short-lived password reset token for a user
+func (j *JWTManager) GenerateResetToken(userID uint, email, passwordHash string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:              userID,
+		Email:               email,
+		TokenType:           TokenTypePasswordReset,
+		PasswordFingerprint: passwordFingerprint(passwordHash),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.config.ResetTokenExpiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(j.config.Secret))
+}
+
+// ValidateResetToken validates a password reset token
+func (j *JWTManager) ValidateResetToken(tokenString string) (*Claims, error) {
+	claims, err := j.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypePasswordReset {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// MatchesPassword reports whether a reset token was issued for the given
+// password hash, i.e. the password hasn't been changed since
+func (c *Claims) MatchesPassword(passwordHash string) bool {
+	return c.PasswordFingerprint != "" && c.PasswordFingerprint == passwordFingerprint(passwordHash)
+}
+
+// newTokenID returns a random token ID (jti)
+func newTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func passwordFingerprint(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseToken validates and parses a JWT token, tolerating the configured
+// clock skew between server and clients on exp/nbf/iat
+func (j *JWTManager) parseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(j.config.Secret), nil
-	})
+	}, jwt.WithLeeway(j.config.Leeway))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"linda-salon-api/config"
+)
+
+const testSecret = "test-secret"
+
+func newTestManager(leeway time.Duration) *JWTManager {
+	return NewJWTManager(&config.JWTConfig{
+		Secret:     testSecret,
+		Expiration: time.Hour,
+		Leeway:     leeway,
+	}, NewMemoryBlacklist(time.Hour, leeway))
+}
+
+// signToken signs an access token whose exp and nbf are offset from now
+func signToken(t *testing.T, expIn, nbfIn time.Duration) string {
+	t.Helper()
+	now := time.Now()
+	claims := Claims{
+		UserID: 1,
+		Email:  "customer@example.com",
+		Role:   "customer",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now.Add(nbfIn)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestValidateTokenLeeway(t *testing.T) {
+	tests := []struct {
+		name      string
+		leeway    time.Duration
+		expIn     time.Duration
+		nbfIn     time.Duration
+		wantValid bool
+	}{
+		{"valid token", 30 * time.Second, time.Hour, 0, true},
+		{"expired within leeway", 30 * time.Second, -10 * time.Second, -time.Hour, true},
+		{"expired beyond leeway", 30 * time.Second, -2 * time.Minute, -time.Hour, false},
+		{"expired without leeway", 0, -10 * time.Second, -time.Hour, false},
+		{"not yet valid within leeway", 30 * time.Second, time.Hour, 10 * time.Second, true},
+		{"not yet valid beyond leeway", 30 * time.Second, time.Hour, 2 * time.Minute, false},
+		{"not yet valid without leeway", 0, time.Hour, 10 * time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTestManager(tt.leeway)
+			_, err := manager.ValidateToken(signToken(t, tt.expIn, tt.nbfIn))
+			if tt.wantValid && err != nil {
+				t.Errorf("ValidateToken() error = %v, want valid", err)
+			}
+			if !tt.wantValid && err == nil {
+				t.Error("ValidateToken() accepted the token, want an error")
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsRevoked(t *testing.T) {
+	manager := newTestManager(30 * time.Second)
+	token := signToken(t, time.Hour, 0)
+
+	if _, err := manager.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() error = %v before revoking", err)
+	}
+	manager.RevokeToken(token)
+	if _, err := manager.ValidateToken(token); err == nil {
+		t.Error("ValidateToken() accepted a revoked token")
+	}
+}
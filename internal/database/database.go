@@ -50,11 +50,21 @@ func (d *Database) AutoMigrate() error {
 
 	err := d.DB.AutoMigrate(
 		&model.User{},
+		&model.Category{},
 		&model.Service{},
+		&model.ServiceVariant{},
+		&model.ServiceBlackout{},
 		&model.Stylist{},
 		&model.StylistSchedule{},
+		&model.StylistTimeOff{},
+		&model.StylistService{},
+		&model.BookingGroup{},
 		&model.Booking{},
+		&model.BookingHold{},
 		&model.Settings{},
+		&model.Review{},
+		&model.AuditLog{},
+		&model.LoyaltyEntry{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run auto-migrations: %w", err)
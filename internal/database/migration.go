@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -36,6 +37,31 @@ var migrationList = []struct {
 		name:    "migrate_services_to_jsonb",
 		fn:      migrations.V2MigrateServicesToJSONB,
 	},
+	{
+		version: "v3",
+		name:    "backfill_booking_tax_totals",
+		fn:      migrations.V3BackfillBookingTaxTotals,
+	},
+	{
+		version: "v4",
+		name:    "backfill_occupied_end_time",
+		fn:      migrations.V4BackfillOccupiedEndTime,
+	},
+	{
+		version: "v5",
+		name:    "normalize_phone_numbers",
+		fn:      migrations.V5NormalizePhoneNumbers,
+	},
+	{
+		version: "v6",
+		name:    "backfill_booking_references",
+		fn:      migrations.V6BackfillBookingReferences,
+	},
+	{
+		version: "v7",
+		name:    "backfill_service_categories",
+		fn:      migrations.V7BackfillServiceCategories,
+	},
 	// Add new migrations here in order
 }
 
@@ -105,3 +131,25 @@ func (d *Database) RunMigrations() error {
 
 	return nil
 }
+
+// PendingMigrations returns the versions in migrationList that haven't been
+// recorded as applied yet, in order
+func (d *Database) PendingMigrations(ctx context.Context) ([]string, error) {
+	var applied []string
+	if err := d.DB.WithContext(ctx).Model(&Migration{}).Pluck("version", &applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	appliedMap := make(map[string]bool, len(applied))
+	for _, version := range applied {
+		appliedMap[version] = true
+	}
+
+	pending := []string{}
+	for _, migration := range migrationList {
+		if !appliedMap[migration.version] {
+			pending = append(pending, migration.version)
+		}
+	}
+	return pending, nil
+}
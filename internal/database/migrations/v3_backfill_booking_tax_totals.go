@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// V3BackfillBookingTaxTotals sets total_with_tax for bookings created before
+// tax calculation existed, which were all untaxed
+func V3BackfillBookingTaxTotals(tx *gorm.DB) error {
+	log.Println("  [V3] Backfilling booking tax totals...")
+
+	result := tx.Exec("UPDATE bookings SET total_with_tax = price + tax_amount WHERE total_with_tax = 0 AND price > 0")
+	if result.Error != nil {
+		return result.Error
+	}
+
+	log.Printf("    - Updated %d booking(s)", result.RowsAffected)
+	return nil
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// V4BackfillOccupiedEndTime sets occupied_end_time for bookings and holds
+// created before per-service buffers existed, which had no buffer
+func V4BackfillOccupiedEndTime(tx *gorm.DB) error {
+	log.Println("  [V4] Backfilling occupied end times...")
+
+	for _, table := range []string{"bookings", "booking_holds"} {
+		result := tx.Exec("UPDATE " + table + " SET occupied_end_time = end_time WHERE occupied_end_time = ''")
+		if result.Error != nil {
+			return result.Error
+		}
+		log.Printf("    - Updated %d row(s) in %s", result.RowsAffected, table)
+	}
+
+	return nil
+}
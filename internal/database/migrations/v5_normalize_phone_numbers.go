@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+	"linda-salon-api/internal/phone"
+)
+
+// V5NormalizePhoneNumbers rewrites stored phone numbers in E.164 form.
+// Numbers that can't be normalized, or whose normalized form already belongs
+// to another user, are logged and left as they are.
+func V5NormalizePhoneNumbers(tx *gorm.DB) error {
+	log.Println("  [V5] Normalizing phone numbers to E.164...")
+
+	var users []struct {
+		ID    uint
+		Phone string
+	}
+	if err := tx.Table("users").Select("id, phone").
+		Where("phone IS NOT NULL AND phone <> ''").
+		Order("id").
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	// 先記錄所有現有號碼，避免正規化後與其他用戶重複
+	owner := make(map[string]uint, len(users))
+	for _, u := range users {
+		owner[u.Phone] = u.ID
+	}
+
+	updated, skipped := 0, 0
+	for _, u := range users {
+		normalized, err := phone.Normalize(u.Phone)
+		if err != nil {
+			log.Printf("    - User #%d: can't normalize phone %q, left unchanged", u.ID, u.Phone)
+			skipped++
+			continue
+		}
+		if normalized == u.Phone {
+			continue
+		}
+		if id, taken := owner[normalized]; taken && id != u.ID {
+			log.Printf("    - User #%d: phone %q normalizes to %s, already used by user #%d; left unchanged", u.ID, u.Phone, normalized, id)
+			skipped++
+			continue
+		}
+		if err := tx.Table("users").Where("id = ?", u.ID).Update("phone", normalized).Error; err != nil {
+			return err
+		}
+		delete(owner, u.Phone)
+		owner[normalized] = u.ID
+		updated++
+	}
+	log.Printf("    - Updated %d user phone(s), skipped %d", updated, skipped)
+
+	var bookings []struct {
+		ID            uint
+		CustomerPhone string
+	}
+	if err := tx.Table("bookings").Select("id, customer_phone").
+		Where("customer_phone <> ''").
+		Find(&bookings).Error; err != nil {
+		return err
+	}
+	updated, skipped = 0, 0
+	for _, b := range bookings {
+		normalized, err := phone.Normalize(b.CustomerPhone)
+		if err != nil {
+			skipped++
+			continue
+		}
+		if normalized == b.CustomerPhone {
+			continue
+		}
+		if err := tx.Table("bookings").Where("id = ?", b.ID).Update("customer_phone", normalized).Error; err != nil {
+			return err
+		}
+		updated++
+	}
+	log.Printf("    - Updated %d booking phone(s), %d could not be normalized", updated, skipped)
+
+	return nil
+}
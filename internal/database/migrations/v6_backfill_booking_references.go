@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+	"linda-salon-api/internal/model"
+)
+
+// V6BackfillBookingReferences gives bookings created before references
+// existed a lookup reference
+func V6BackfillBookingReferences(tx *gorm.DB) error {
+	log.Println("  [V6] Backfilling booking references...")
+
+	var ids []uint
+	if err := tx.Table("bookings").
+		Where("reference IS NULL OR reference = ''").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := tx.Table("bookings").Where("id = ?", id).
+			Update("reference", model.NewBookingReference()).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("    - Assigned references to %d booking(s)", len(ids))
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// V7BackfillServiceCategories creates a category for every distinct
+// free-text category on services (including soft-deleted ones) and links
+// the services to it. The existing string becomes both the slug and the
+// name; admins can rename categories afterwards.
+func V7BackfillServiceCategories(tx *gorm.DB) error {
+	log.Println("  [V7] Backfilling service categories...")
+
+	result := tx.Exec(`
+		INSERT INTO categories (name, slug, display_order, is_active, created_at, updated_at)
+		SELECT DISTINCT category, category, 0, true, NOW(), NOW()
+		FROM services
+		WHERE category <> ''
+		ON CONFLICT (slug) DO NOTHING`)
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("    - Created %d categor(ies)", result.RowsAffected)
+
+	result = tx.Exec(`
+		UPDATE services SET category_id = categories.id
+		FROM categories
+		WHERE categories.slug = services.category AND services.category_id IS NULL`)
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("    - Linked %d service(s)", result.RowsAffected)
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+const (
+	// activityWindow 動態牆只顯示最近這段期間的事件
+	activityWindow = 30 * 24 * time.Hour
+	// maxActivityLimit 動態牆單次最多回傳筆數
+	maxActivityLimit = 100
+)
+
+// Activity event types
+const (
+	ActivityBookingCreated       = "booking_created"
+	ActivityBookingCancelled     = "booking_cancelled"
+	ActivityBookingStatusChanged = "booking_status_changed"
+	ActivityUserSignup           = "user_signup"
+)
+
+type ActivityHandler struct {
+	bookingRepo *repository.BookingRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewActivityHandler(bookingRepo *repository.BookingRepository, userRepo *repository.UserRepository) *ActivityHandler {
+	return &ActivityHandler{
+		bookingRepo: bookingRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// ActivityActor identifies who caused an activity event
+type ActivityActor struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// ActivityEvent is a single entry in the admin activity feed
+type ActivityEvent struct {
+	Type       string         `json:"type"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Actor      *ActivityActor `json:"actor,omitempty"`
+	EntityType string         `json:"entity_type"` // booking, user
+	EntityID   uint           `json:"entity_id"`
+	Summary    string         `json:"summary"`
+}
+
+// GetActivity godoc
+// @Summary Get the recent activity feed (admin only)
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Max events (max 100)" default(20)
+// @Success 200 {array} ActivityEvent
+// @Router /admin/activity [get]
+func (h *ActivityHandler) GetActivity(c *gin.Context) {
+	limit, err := queryLimit(c, 20, maxActivityLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	since := time.Now().Add(-activityWindow)
+
+	created, err := h.bookingRepo.GetRecentlyCreated(since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	updated, err := h.bookingRepo.GetRecentStatusChanges(since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	signups, err := h.userRepo.GetRecentSignups(since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	// Resolve actor names in one query
+	actorIDs := []uint{}
+	for _, booking := range created {
+		if booking.CreatedByID != nil {
+			actorIDs = append(actorIDs, *booking.CreatedByID)
+		}
+	}
+	for _, booking := range updated {
+		if booking.StatusChangedByID != nil {
+			actorIDs = append(actorIDs, *booking.StatusChangedByID)
+		}
+	}
+	actors, err := h.userRepo.GetByIDs(actorIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	actorByID := make(map[uint]*ActivityActor, len(actors))
+	for _, user := range actors {
+		actorByID[user.ID] = &ActivityActor{ID: user.ID, Name: user.Name, Role: user.Role}
+	}
+	actorFor := func(id *uint) *ActivityActor {
+		if id == nil {
+			return nil
+		}
+		return actorByID[*id]
+	}
+
+	events := make([]ActivityEvent, 0, len(created)+len(updated)+len(signups))
+	for i := range created {
+		booking := &created[i]
+		events = append(events, ActivityEvent{
+			Type:       ActivityBookingCreated,
+			OccurredAt: booking.CreatedAt,
+			Actor:      actorFor(booking.CreatedByID),
+			EntityType: "booking",
+			EntityID:   booking.ID,
+			Summary:    activityBookingSummary(booking),
+		})
+	}
+	for i := range updated {
+		booking := &updated[i]
+		eventType := ActivityBookingStatusChanged
+		if booking.Status == model.BookingStatusCancelled {
+			eventType = ActivityBookingCancelled
+		}
+		events = append(events, ActivityEvent{
+			Type:       eventType,
+			OccurredAt: *booking.StatusChangedAt,
+			Actor:      actorFor(booking.StatusChangedByID),
+			EntityType: "booking",
+			EntityID:   booking.ID,
+			Summary:    fmt.Sprintf("%s → %s", activityBookingSummary(booking), booking.Status),
+		})
+	}
+	for _, user := range signups {
+		events = append(events, ActivityEvent{
+			Type:       ActivityUserSignup,
+			OccurredAt: user.CreatedAt,
+			Actor:      &ActivityActor{ID: user.ID, Name: user.Name, Role: user.Role},
+			EntityType: "user",
+			EntityID:   user.ID,
+			Summary:    fmt.Sprintf("%s (%s)", user.Name, user.Email),
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].OccurredAt.After(events[j].OccurredAt)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func activityBookingSummary(booking *model.Booking) string {
+	summary := fmt.Sprintf("%s %s %s", booking.CustomerName, booking.BookingDate.Format("2006-01-02"), booking.StartTime)
+	if booking.Stylist.Name != "" {
+		summary += " with " + booking.Stylist.Name
+	}
+	return summary
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/requestid"
+)
+
+// maxAuditLogLimit 操作紀錄單次最多回傳筆數
+const maxAuditLogLimit = 100
+
+type AuditLogHandler struct {
+	auditRepo *repository.AuditLogRepository
+	location  *time.Location
+}
+
+func NewAuditLogHandler(auditRepo *repository.AuditLogRepository, location *time.Location) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditRepo: auditRepo,
+		location:  location,
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary List admin audit log entries, newest first (admin only)
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param actor_id query int false "Filter by acting admin's user ID"
+// @Param entity_type query string false "service, stylist, booking, category, user or maintenance"
+// @Param entity_id query int false "Filter by entity ID"
+// @Param start_date query string false "From date, inclusive (YYYY-MM-DD)"
+// @Param end_date query string false "To date, inclusive (YYYY-MM-DD)"
+// @Param limit query int false "Limit (max 100)" default(20)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/audit-logs [get]
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	limit, offset, err := queryPagination(c, 20, maxAuditLogLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.AuditLogFilter{EntityType: c.Query("entity_type")}
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actor_id"})
+			return
+		}
+		filter.ActorUserID = uint(id)
+	}
+	if v := c.Query("entity_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity_id"})
+			return
+		}
+		filter.EntityID = uint(id)
+	}
+
+	// 日期以店家時區解讀，end_date 包含當天
+	if v := c.Query("start_date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, h.location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		filter.From = &t
+	}
+	if v := c.Query("end_date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, h.location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format, use YYYY-MM-DD"})
+			return
+		}
+		t = t.AddDate(0, 0, 1)
+		filter.To = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	logs, total, err := h.auditRepo.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": logs,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// auditIgnoredFields change on every save and would only add noise to diffs
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+// recordAudit writes an audit log entry for the admin in the request
+// context. It's called after the change succeeded; a failure to write the
+// entry is logged but doesn't fail the request.
+func recordAudit(c *gin.Context, repo *repository.AuditLogRepository, action, entityType string, entityID uint, changes map[string]interface{}) {
+	if repo == nil {
+		return
+	}
+	actorID, ok := middleware.GetUserID(c)
+	if !ok {
+		return
+	}
+	entry := &model.AuditLog{
+		ActorUserID: actorID,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Changes:     changes,
+	}
+	if err := repo.Create(entry); err != nil {
+		log.Printf("%s⚠️  Failed to write audit log (%s %s #%d): %v", requestid.LogPrefix(c.Request.Context()), action, entityType, entityID, err)
+	}
+}
+
+// auditSnapshot returns v's JSON fields, for logging a created entity
+func auditSnapshot(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	for key := range auditIgnoredFields {
+		delete(fields, key)
+	}
+	return fields
+}
+
+// auditDiff compares the JSON fields of before and after and returns the
+// ones that changed as {"field": {"from": old, "to": new}}
+func auditDiff(before, after interface{}) map[string]interface{} {
+	from, to := auditSnapshot(before), auditSnapshot(after)
+	changes := map[string]interface{}{}
+	for key, value := range to {
+		if !reflect.DeepEqual(from[key], value) {
+			changes[key] = gin.H{"from": from[key], "to": value}
+		}
+	}
+	for key, value := range from {
+		if _, ok := to[key]; !ok {
+			changes[key] = gin.H{"from": value, "to": nil}
+		}
+	}
+	return changes
+}
@@ -1,31 +1,53 @@
 package handler
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"linda-salon-api/config"
+	"linda-salon-api/internal/apierror"
 	"linda-salon-api/internal/auth"
+	"linda-salon-api/internal/middleware"
 	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/phone"
 	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
 )
 
 type AuthHandler struct {
-	userRepo   *repository.UserRepository
-	jwtManager *auth.JWTManager
+	userRepo    *repository.UserRepository
+	stylistRepo *repository.StylistRepository
+	loyaltyRepo *repository.LoyaltyRepository
+	jwtManager  *auth.JWTManager
+	s3Service  *service.S3Service
+	notifier   *service.NotificationService
+	cfg        *config.AuthConfig
+	google     *config.GoogleOAuthConfig
+	httpClient *http.Client // for OAuth provider calls; must have a timeout
 }
 
-func NewAuthHandler(userRepo *repository.UserRepository, jwtManager *auth.JWTManager) *AuthHandler {
+func NewAuthHandler(userRepo *repository.UserRepository, stylistRepo *repository.StylistRepository, loyaltyRepo *repository.LoyaltyRepository, jwtManager *auth.JWTManager, s3Service *service.S3Service, notifier *service.NotificationService, cfg *config.AuthConfig, google *config.GoogleOAuthConfig, httpClient *http.Client) *AuthHandler {
 	return &AuthHandler{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
+		userRepo:    userRepo,
+		stylistRepo: stylistRepo,
+		loyaltyRepo: loyaltyRepo,
+		jwtManager:  jwtManager,
+		s3Service:  s3Service,
+		notifier:   notifier,
+		cfg:        cfg,
+		google:     google,
+		httpClient: httpClient,
 	}
 }
 
@@ -52,6 +74,30 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type UpdateProfileRequest struct {
+	Name            *string `json:"name" binding:"omitempty,min=1"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	Phone           *string `json:"phone" binding:"omitempty,min=1"`
+	Avatar          *string `json:"avatar" binding:"omitempty,url"`
+	// 預約提醒提前小時數 (1~72)，0 表示改回店家預設值
+	ReminderHoursBefore *int   `json:"reminder_hours_before" binding:"omitempty,min=0,max=72"`
+	// 常用設計師，0 表示清除
+	DefaultStylistID *uint `json:"default_stylist_id"`
+	// 給設計師參考的長期備註（過敏、偏好等），最多 500 字，空字串表示清除
+	ProfileNote     *string `json:"profile_note" binding:"omitempty,max=500"`
+	CurrentPassword     string `json:"current_password"`
+	NewPassword     string  `json:"new_password" binding:"omitempty,min=6"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -75,29 +121,43 @@ type GoogleLoginRequest struct {
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
 		return
 	}
 
+	// 電話統一存成 E.164，重複檢查才不會因格式不同而漏掉
+	normalizedPhone, err := phone.Normalize(req.Phone)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidPhone, "Invalid phone number")
+		return
+	}
+	req.Phone = normalizedPhone
+
 	// Check if email already exists
 	existingUser, err := h.userRepo.GetByEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check email")
 		return
 	}
 	if existingUser != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		respondError(c, http.StatusConflict, apierror.CodeEmailTaken, "Email already registered")
 		return
 	}
 
 	// Check if phone already exists
 	existingUser, err = h.userRepo.GetByPhone(req.Phone)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check phone"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check phone")
 		return
 	}
 	if existingUser != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Phone number already registered"})
+		respondError(c, http.StatusConflict, apierror.CodePhoneTaken, "Phone number already registered")
+		return
+	}
+
+	role, err := h.registrationRole(req.Email)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check admin accounts")
 		return
 	}
 
@@ -106,23 +166,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Name:  req.Name,
 		Email: req.Email,
 		Phone: &req.Phone, // 轉換為指標
-		Role:  "customer",
+		Role:  role,
 	}
 
 	if err := user.HashPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
 		return
 	}
 
 	if err := h.userRepo.Create(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
 		return
 	}
+	if user.Role == "admin" {
+		log.Printf("👑 [Bootstrap] Created first admin account %s (ID: %d) via BOOTSTRAP_ADMIN_EMAIL", user.Email, user.ID)
+	}
 
 	// Generate tokens
 	tokens, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate tokens")
 		return
 	}
 
@@ -132,6 +195,24 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	})
 }
 
+// registrationRole returns the role for a newly registered email. Public
+// registration always creates customers, except for the one-time bootstrap:
+// while no admin exists, BOOTSTRAP_ADMIN_EMAIL registers as admin.
+func (h *AuthHandler) registrationRole(email string) (string, error) {
+	if h.cfg.BootstrapAdminEmail == "" || !strings.EqualFold(email, h.cfg.BootstrapAdminEmail) {
+		return "customer", nil
+	}
+
+	hasAdmin, err := h.userRepo.HasAdmin()
+	if err != nil {
+		return "", err
+	}
+	if hasAdmin {
+		return "customer", nil
+	}
+	return "admin", nil
+}
+
 // Login godoc
 // @Summary Login user
 // @Tags auth
@@ -143,31 +224,31 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
 		return
 	}
 
 	// Find user
 	user, err := h.userRepo.GetByEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to find user")
 		return
 	}
 	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
 	// Check password
 	if !user.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
 	// Generate tokens
 	tokens, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate tokens")
 		return
 	}
 
@@ -177,6 +258,90 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// ForgotPassword godoc
+// @Summary Request a password reset email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	// 不論帳號是否存在都回傳相同訊息，避免被用來探測註冊信箱
+	response := gin.H{"message": "If an account exists for this email, a reset link has been sent"}
+
+	user, err := h.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to find user")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := h.jwtManager.GenerateResetToken(user.ID, user.Email, user.PasswordHash)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate reset token")
+		return
+	}
+
+	resetURL := h.cfg.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	h.notifier.SendPasswordReset(c.Request.Context(), user.Email, resetURL)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword godoc
+// @Summary Set a new password using a reset token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	claims, err := h.jwtManager.ValidateResetToken(req.Token)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidResetToken, "Invalid or expired reset token")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to find user")
+		return
+	}
+	// The token is single-use: once the password changes it no longer matches
+	if user == nil || user.Email != claims.Email || !claims.MatchesPassword(user.PasswordHash) {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidResetToken, "Invalid or expired reset token")
+		return
+	}
+
+	if err := user.HashPassword(req.Password); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
+		return
+	}
+	if err := h.userRepo.Update(user); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
 // RefreshToken godoc
 // @Summary Refresh access token
 // @Tags auth
@@ -188,13 +353,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
 		return
 	}
 
 	accessToken, err := h.jwtManager.RefreshAccessToken(req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		respondError(c, http.StatusUnauthorized, apierror.CodeInvalidRefreshToken, "Invalid refresh token")
 		return
 	}
 
@@ -203,6 +368,29 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	})
 }
 
+// profileView is the current user's profile. DefaultStylistAvailable is
+// set when a default stylist is chosen, and false once that stylist has
+// been deactivated or deleted.
+type profileView struct {
+	*model.User
+	DefaultStylistAvailable *bool `json:"default_stylist_available,omitempty"`
+}
+
+// respondProfile responds with the user as a profileView
+func (h *AuthHandler) respondProfile(c *gin.Context, user *model.User) {
+	view := profileView{User: user}
+	if user.DefaultStylistID != nil {
+		stylist, err := h.stylistRepo.GetByID(*user.DefaultStylistID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch default stylist")
+			return
+		}
+		available := stylist != nil && stylist.IsActive
+		view.DefaultStylistAvailable = &available
+	}
+	c.JSON(http.StatusOK, view)
+}
+
 // GetProfile godoc
 // @Summary Get current user profile
 // @Tags auth
@@ -213,31 +401,269 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not found")
 		return
 	}
 
 	user, err := h.userRepo.GetByID(userID.(uint))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get user")
+		return
+	}
+	if user == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+		return
+	}
+
+	h.respondProfile(c, user)
+}
+
+// GetLoyaltyHistory godoc
+// @Summary List the current user's loyalty point history, newest first
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Limit" default(20)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/loyalty/history [get]
+func (h *AuthHandler) GetLoyaltyHistory(c *gin.Context) {
+	limit, offset, err := queryPagination(c, 20, 0)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get user")
 		return
 	}
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+		return
+	}
+
+	entries, total, err := h.loyaltyRepo.ListByUser(user.ID, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch loyalty history")
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, gin.H{
+		"balance": user.LoyaltyPoints,
+		"entries": entries,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// UpdateProfile godoc
+// @Summary Update the current user's profile
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateProfileRequest true "Fields to update"
+// @Success 200 {object} model.User
+// @Router /auth/profile [patch]
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not found")
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID.(uint))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get user")
+		return
+	}
+	if user == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+		return
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		existingUser, err := h.userRepo.GetByEmail(*req.Email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check email")
+			return
+		}
+		if existingUser != nil {
+			respondError(c, http.StatusConflict, apierror.CodeEmailTaken, "Email already registered")
+			return
+		}
+		user.Email = *req.Email
+	}
+
+	// OAuth 用戶註冊時沒有電話，可在此補填
+	if req.Phone != nil {
+		normalizedPhone, err := phone.Normalize(*req.Phone)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeInvalidPhone, "Invalid phone number")
+			return
+		}
+		req.Phone = &normalizedPhone
+	}
+	if req.Phone != nil && (user.Phone == nil || *req.Phone != *user.Phone) {
+		existingUser, err := h.userRepo.GetByPhone(*req.Phone)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check phone")
+			return
+		}
+		if existingUser != nil {
+			respondError(c, http.StatusConflict, apierror.CodePhoneTaken, "Phone number already registered")
+			return
+		}
+		user.Phone = req.Phone
+	}
+
+	if req.NewPassword != "" {
+		// OAuth-only users never chose a password; they can set one through
+		// the forgot-password flow instead
+		if !user.CheckPassword(req.CurrentPassword) {
+			respondError(c, http.StatusUnauthorized, apierror.CodeWrongPassword, "Current password is incorrect")
+			return
+		}
+		if err := user.HashPassword(req.NewPassword); err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
+			return
+		}
+	}
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Avatar != nil {
+		// 指向本 bucket 的頭像必須是本人上傳到 avatars/<userID>/ 的檔案，
+		// 否則之後刪除頭像時會刪到別人的圖片
+		if key := h.s3Service.KeyFromURL(*req.Avatar); key != "" && !service.IsUserAvatarKey(user.ID, key) {
+			respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Avatar must be an image you uploaded to the avatars folder")
+			return
+		}
+		user.Avatar = *req.Avatar
+	}
+	if req.ReminderHoursBefore != nil {
+		if *req.ReminderHoursBefore == 0 {
+			user.ReminderHoursBefore = nil
+		} else {
+			user.ReminderHoursBefore = req.ReminderHoursBefore
+		}
+	}
+	if req.ProfileNote != nil {
+		user.ProfileNote = strings.TrimSpace(*req.ProfileNote)
+	}
+	if req.DefaultStylistID != nil {
+		if *req.DefaultStylistID == 0 {
+			user.DefaultStylistID = nil
+		} else {
+			stylist, err := h.stylistRepo.GetByID(*req.DefaultStylistID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch stylist")
+				return
+			}
+			if stylist == nil || !stylist.IsActive {
+				respondError(c, http.StatusBadRequest, apierror.CodeInvalidStylist, "Stylist not found or not active")
+				return
+			}
+			user.DefaultStylistID = req.DefaultStylistID
+		}
+	}
+
+	if err := h.userRepo.Update(user); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update user")
+		return
+	}
+
+	h.respondProfile(c, user)
+}
+
+// DeleteAvatar godoc
+// @Summary Remove the current user's avatar
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} model.User
+// @Router /auth/profile/avatar [delete]
+func (h *AuthHandler) DeleteAvatar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not found")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID.(uint))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get user")
+		return
+	}
+	if user == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+		return
+	}
+
+	if user.Avatar == "" {
+		h.respondProfile(c, user)
+		return
+	}
+
+	// 只刪除本人上傳到 avatars/<userID>/ 的檔案；OAuth 提供的外部頭像（如 Google）
+	// 與其他位置的網址僅清除欄位
+	if key := h.s3Service.KeyFromURL(user.Avatar); service.IsUserAvatarKey(user.ID, key) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := h.s3Service.DeleteFile(ctx, user.Avatar); err != nil {
+			log.Printf("⚠️  Failed to delete avatar %s for user %d: %v", user.Avatar, user.ID, err)
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete avatar")
+			return
+		}
+		if err := h.s3Service.DeleteFile(ctx, service.ThumbnailURL(user.Avatar)); err != nil {
+			log.Printf("⚠️  Failed to delete avatar thumbnail for user %d: %v", user.ID, err)
+		}
+	}
+
+	user.Avatar = ""
+	if err := h.userRepo.Update(user); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update user")
+		return
+	}
+
+	h.respondProfile(c, user)
 }
 
 // Logout godoc
-// @Summary Logout user
+// @Summary Logout user and revoke their tokens
 // @Tags auth
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {object} map[string]string
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
+	// Revoke the access token and, if sent, the refresh token so a stolen
+	// copy stops working before it expires
+	if token := middleware.ExtractToken(c); token != "" {
+		h.jwtManager.RevokeToken(token)
+	}
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		h.jwtManager.RevokeToken(refreshToken)
+	}
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.Request.ContentLength > 0 && c.ShouldBindJSON(&req) == nil && req.RefreshToken != "" {
+		h.jwtManager.RevokeToken(req.RefreshToken)
+	}
+
 	// Clear cookies by setting Max-Age to 0
 	c.Writer.Header().Add("Set-Cookie", "access_token=; Path=/; Max-Age=0; HttpOnly; Secure; SameSite=None")
 	c.Writer.Header().Add("Set-Cookie", "refresh_token=; Path=/; Max-Age=0; HttpOnly; Secure; SameSite=None")
@@ -262,8 +688,8 @@ func (h *AuthHandler) GoogleLoginURL(c *gin.Context) {
 	log.Printf("🔑 [OAuth] Generated state: %s", state)
 
 	// Build Google OAuth URL manually
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	redirectURI := os.Getenv("GOOGLE_REDIRECT_URL")
+	clientID := h.google.ClientID
+	redirectURI := h.google.RedirectURL
 
 	params := url.Values{}
 	params.Add("client_id", clientID)
@@ -273,7 +699,7 @@ func (h *AuthHandler) GoogleLoginURL(c *gin.Context) {
 	params.Add("state", state)
 	params.Add("access_type", "offline")
 
-	authURL := fmt.Sprintf("https://accounts.google.com/o/oauth2/v2/auth?%s", params.Encode())
+	authURL := fmt.Sprintf("%s?%s", h.google.AuthURL, params.Encode())
 
 	log.Printf("✅ [OAuth] Returning Google OAuth URL")
 
@@ -298,7 +724,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	state := c.Query("state")
 	if state == "" {
 		log.Printf("❌ [OAuth] State parameter is missing")
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=invalid_state")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=invalid_state")
 		return
 	}
 
@@ -313,7 +739,12 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	accessToken, err := h.exchangeCodeForToken(code)
 	if err != nil {
 		log.Printf("❌ [OAuth] Token exchange failed: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=token_exchange_failed")
+		redirectURL := h.cfg.FrontendURL + "/login?error=token_exchange_failed"
+		var tokenErr *oauthTokenError
+		if errors.As(err, &tokenErr) && tokenErr.Code != "" {
+			redirectURL += "&reason=" + url.QueryEscape(tokenErr.Code)
+		}
+		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 		return
 	}
 	log.Println("✅ [OAuth] Access token obtained")
@@ -323,7 +754,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	googleUser, err := h.getGoogleUserInfo(accessToken)
 	if err != nil {
 		log.Printf("❌ [OAuth] Failed to get user info: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=userinfo_failed")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=userinfo_failed")
 		return
 	}
 	log.Printf("✅ [OAuth] User info received: email=%s, name=%s", googleUser.Email, googleUser.Name)
@@ -333,7 +764,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	user, err := h.userRepo.GetByGoogleID(googleUser.ID)
 	if err != nil {
 		log.Printf("❌ [OAuth] Database error checking Google ID: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=db_error")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=db_error")
 		return
 	}
 
@@ -343,7 +774,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 		user, err = h.userRepo.GetByEmail(googleUser.Email)
 		if err != nil {
 			log.Printf("❌ [OAuth] Database error checking email: %v", err)
-			c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=db_error")
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=db_error")
 			return
 		}
 
@@ -354,7 +785,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 			user.Avatar = googleUser.Picture
 			if err := h.userRepo.Update(user); err != nil {
 				log.Printf("❌ [OAuth] Failed to link Google account: %v", err)
-				c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=update_failed")
+				c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=update_failed")
 				return
 			}
 			log.Printf("✅ [OAuth] Google account linked successfully")
@@ -377,13 +808,13 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 		// For OAuth users, set a random unguessable password hash
 		if err := user.HashPassword("oauth_" + googleUser.ID + "_" + googleUser.Email); err != nil {
 			log.Printf("❌ [OAuth] Failed to hash password: %v", err)
-			c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=hash_failed")
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=hash_failed")
 			return
 		}
 
 		if err := h.userRepo.Create(user); err != nil {
 			log.Printf("❌ [OAuth] Failed to create user: %v", err)
-			c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=create_failed")
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=create_failed")
 			return
 		}
 		log.Printf("✅ [OAuth] New user created with ID: %d", user.ID)
@@ -396,7 +827,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	tokens, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email, user.Role)
 	if err != nil {
 		log.Printf("❌ [OAuth] Failed to generate tokens: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=token_failed")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=token_failed")
 		return
 	}
 	log.Println("✅ [OAuth] JWT tokens generated")
@@ -408,14 +839,14 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	log.Println("✅ [OAuth] Cookies set, redirecting to frontend")
 
 	// Redirect to frontend
-	c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/?login=success")
+	c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/?login=success")
 }
 
 // Helper function to exchange authorization code for access token
 func (h *AuthHandler) exchangeCodeForToken(code string) (string, error) {
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
-	redirectURI := os.Getenv("GOOGLE_REDIRECT_URL")
+	clientID := h.google.ClientID
+	clientSecret := h.google.ClientSecret
+	redirectURI := h.google.RedirectURL
 
 	data := url.Values{}
 	data.Set("code", code)
@@ -424,7 +855,7 @@ func (h *AuthHandler) exchangeCodeForToken(code string) (string, error) {
 	data.Set("redirect_uri", redirectURI)
 	data.Set("grant_type", "authorization_code")
 
-	resp, err := http.PostForm("https://oauth2.googleapis.com/token", data)
+	resp, err := h.httpClient.PostForm(h.google.TokenURL, data)
 	if err != nil {
 		return "", err
 	}
@@ -436,28 +867,59 @@ func (h *AuthHandler) exchangeCodeForToken(code string) (string, error) {
 	}
 
 	var tokenResp struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	// Google 錯誤時回傳 4xx 與 {"error": "...", "error_description": "..."}
+	if resp.StatusCode != http.StatusOK {
+		json.Unmarshal(body, &tokenResp)
+		return "", &oauthTokenError{
+			StatusCode:  resp.StatusCode,
+			Code:        tokenResp.Error,
+			Description: tokenResp.ErrorDescription,
+		}
 	}
 
 	if err := json.Unmarshal(body, &tokenResp); err != nil {
 		return "", err
 	}
+	if tokenResp.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
 
 	return tokenResp.AccessToken, nil
 }
 
+// oauthTokenError is an error response from an OAuth token endpoint
+type oauthTokenError struct {
+	StatusCode  int
+	Code        string // e.g. invalid_grant
+	Description string
+}
+
+func (e *oauthTokenError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("token endpoint returned status %d", e.StatusCode)
+	}
+	if e.Description == "" {
+		return fmt.Sprintf("token endpoint returned status %d: %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("token endpoint returned status %d: %s (%s)", e.StatusCode, e.Code, e.Description)
+}
+
 // Helper function to get user info from Google
 func (h *AuthHandler) getGoogleUserInfo(accessToken string) (*GoogleUserInfo, error) {
-	req, err := http.NewRequest("GET", "https://www.googleapis.com/oauth2/v2/userinfo", nil)
+	req, err := http.NewRequest("GET", h.google.UserInfoURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -499,8 +961,8 @@ func (h *AuthHandler) LineLoginURL(c *gin.Context) {
 	log.Printf("🔑 [LINE OAuth] Generated state: %s", state)
 
 	// Build LINE OAuth URL
-	clientID := os.Getenv("LINE_CHANNEL_ID")
-	redirectURI := os.Getenv("LINE_REDIRECT_URL")
+	clientID := h.cfg.LineChannelID
+	redirectURI := h.cfg.LineRedirectURL
 
 	params := url.Values{}
 	params.Add("response_type", "code")
@@ -509,7 +971,7 @@ func (h *AuthHandler) LineLoginURL(c *gin.Context) {
 	params.Add("state", state)
 	params.Add("scope", "profile openid email")
 
-	authURL := fmt.Sprintf("https://access.line.me/oauth2/v2.1/authorize?%s", params.Encode())
+	authURL := fmt.Sprintf("%s?%s", h.cfg.LineAuthURL, params.Encode())
 
 	log.Printf("✅ [LINE OAuth] Returning LINE OAuth URL")
 
@@ -534,7 +996,7 @@ func (h *AuthHandler) LineCallback(c *gin.Context) {
 	state := c.Query("state")
 	if state == "" {
 		log.Printf("❌ [LINE OAuth] State parameter is missing")
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=invalid_state")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=invalid_state")
 		return
 	}
 
@@ -543,10 +1005,10 @@ func (h *AuthHandler) LineCallback(c *gin.Context) {
 	// Exchange code for token
 	code := c.Query("code")
 	log.Printf("🔄 [LINE OAuth] Exchanging code for access token...")
-	accessToken, err := h.exchangeLineCodeForToken(code)
+	accessToken, idToken, err := h.exchangeLineCodeForToken(code)
 	if err != nil {
 		log.Printf("❌ [LINE OAuth] Token exchange failed: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=token_exchange_failed")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=token_exchange_failed")
 		return
 	}
 	log.Println("✅ [LINE OAuth] Access token obtained")
@@ -556,26 +1018,64 @@ func (h *AuthHandler) LineCallback(c *gin.Context) {
 	lineUser, err := h.getLineUserInfo(accessToken)
 	if err != nil {
 		log.Printf("❌ [LINE OAuth] Failed to get user info: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=userinfo_failed")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=userinfo_failed")
 		return
 	}
 	log.Printf("✅ [LINE OAuth] User info received: displayName=%s, userId=%s", lineUser.DisplayName, lineUser.UserID)
 
+	// Email is only available from the ID token, and only if the user granted it
+	lineEmail := ""
+	if idToken != "" {
+		lineEmail, err = h.getLineEmail(idToken)
+		if err != nil {
+			log.Printf("⚠️  [LINE OAuth] Failed to read email from ID token: %v", err)
+		}
+	}
+
 	// Check if user already exists by LINE ID
 	log.Printf("🔍 [LINE OAuth] Checking if user exists with LINE ID: %s", lineUser.UserID)
 	user, err := h.userRepo.GetByLineID(lineUser.UserID)
 	if err != nil {
 		log.Printf("❌ [LINE OAuth] Database error checking LINE ID: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=db_error")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=db_error")
 		return
 	}
 
-	// If user doesn't exist, create new user
+	// If user doesn't exist, check by email
+	if user == nil && lineEmail != "" {
+		log.Printf("🔍 [LINE OAuth] User not found by LINE ID, checking email: %s", lineEmail)
+		user, err = h.userRepo.GetByEmail(lineEmail)
+		if err != nil {
+			log.Printf("❌ [LINE OAuth] Database error checking email: %v", err)
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=db_error")
+			return
+		}
+
+		// If user exists with same email but no LINE ID, link the account
+		if user != nil {
+			log.Printf("🔗 [LINE OAuth] Linking existing user account (ID: %d) with LINE ID", user.ID)
+			user.LineID = &lineUser.UserID
+			if user.Avatar == "" {
+				user.Avatar = lineUser.PictureURL
+			}
+			if err := h.userRepo.Update(user); err != nil {
+				log.Printf("❌ [LINE OAuth] Failed to link LINE account: %v", err)
+				c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=update_failed")
+				return
+			}
+			log.Printf("✅ [LINE OAuth] LINE account linked successfully")
+		}
+	}
+
+	// If user still doesn't exist, create new user
 	if user == nil {
 		log.Printf("➕ [LINE OAuth] Creating new user: %s (LINE ID: %s)", lineUser.DisplayName, lineUser.UserID)
 
-		// LINE 不一定有 email，所以使用 LINE ID 建立假的 email
-		email := fmt.Sprintf("line_%s@lineid.local", lineUser.UserID)
+		// LINE 不一定有 email，沒有授權時使用 LINE ID 建立假的 email
+		email := lineEmail
+		if email == "" {
+			email = fmt.Sprintf("line_%s@lineid.local", lineUser.UserID)
+		}
 
 		user = &model.User{
 			Name:     lineUser.DisplayName,
@@ -589,13 +1089,13 @@ func (h *AuthHandler) LineCallback(c *gin.Context) {
 		// For OAuth users, set a random unguessable password hash
 		if err := user.HashPassword("oauth_line_" + lineUser.UserID); err != nil {
 			log.Printf("❌ [LINE OAuth] Failed to hash password: %v", err)
-			c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=hash_failed")
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=hash_failed")
 			return
 		}
 
 		if err := h.userRepo.Create(user); err != nil {
 			log.Printf("❌ [LINE OAuth] Failed to create user: %v", err)
-			c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=create_failed")
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=create_failed")
 			return
 		}
 		log.Printf("✅ [LINE OAuth] New user created with ID: %d", user.ID)
@@ -608,7 +1108,7 @@ func (h *AuthHandler) LineCallback(c *gin.Context) {
 	tokens, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email, user.Role)
 	if err != nil {
 		log.Printf("❌ [LINE OAuth] Failed to generate tokens: %v", err)
-		c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/login?error=token_failed")
+		c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/login?error=token_failed")
 		return
 	}
 	log.Println("✅ [LINE OAuth] JWT tokens generated")
@@ -619,14 +1119,14 @@ func (h *AuthHandler) LineCallback(c *gin.Context) {
 	log.Println("✅ [LINE OAuth] Cookies set, redirecting to frontend")
 
 	// Redirect to frontend
-	c.Redirect(http.StatusTemporaryRedirect, os.Getenv("FRONTEND_URL")+"/?login=success")
+	c.Redirect(http.StatusTemporaryRedirect, h.cfg.FrontendURL+"/?login=success")
 }
 
-// Helper function to exchange LINE authorization code for access token
-func (h *AuthHandler) exchangeLineCodeForToken(code string) (string, error) {
-	clientID := os.Getenv("LINE_CHANNEL_ID")
-	clientSecret := os.Getenv("LINE_CHANNEL_SECRET")
-	redirectURI := os.Getenv("LINE_REDIRECT_URL")
+// Helper function to exchange LINE authorization code for access and ID tokens
+func (h *AuthHandler) exchangeLineCodeForToken(code string) (string, string, error) {
+	clientID := h.cfg.LineChannelID
+	clientSecret := h.cfg.LineChannelSecret
+	redirectURI := h.cfg.LineRedirectURL
 
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
@@ -635,15 +1135,15 @@ func (h *AuthHandler) exchangeLineCodeForToken(code string) (string, error) {
 	data.Set("client_id", clientID)
 	data.Set("client_secret", clientSecret)
 
-	resp, err := http.PostForm("https://api.line.me/oauth2/v2.1/token", data)
+	resp, err := h.httpClient.PostForm(h.cfg.LineTokenURL, data)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	var tokenResp struct {
@@ -656,23 +1156,53 @@ func (h *AuthHandler) exchangeLineCodeForToken(code string) (string, error) {
 	}
 
 	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", "", err
+	}
+
+	return tokenResp.AccessToken, tokenResp.IDToken, nil
+}
+
+// Helper function to read the email claim from a LINE ID token. LINE verifies
+// the token signature for us.
+func (h *AuthHandler) getLineEmail(idToken string) (string, error) {
+	data := url.Values{}
+	data.Set("id_token", idToken)
+	data.Set("client_id", h.cfg.LineChannelID)
+
+	resp, err := h.httpClient.PostForm(h.cfg.LineVerifyURL, data)
+	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 
-	return tokenResp.AccessToken, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("verify failed: %s", body)
+	}
+
+	var claims struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return "", err
+	}
+
+	return claims.Email, nil
 }
 
 // Helper function to get user info from LINE
 func (h *AuthHandler) getLineUserInfo(accessToken string) (*LineUserInfo, error) {
-	req, err := http.NewRequest("GET", "https://api.line.me/v2/profile", nil)
+	req, err := http.NewRequest("GET", h.cfg.LineProfileURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/config"
+)
+
+// newOAuthTestHandler returns an AuthHandler whose Google endpoints point at
+// server. Only the OAuth helpers can be called on it.
+func newOAuthTestHandler(server *httptest.Server, timeout time.Duration) *AuthHandler {
+	return &AuthHandler{
+		cfg: &config.AuthConfig{FrontendURL: "https://salon.example.com"},
+		google: &config.GoogleOAuthConfig{
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+			RedirectURL:  "https://api.example.com/api/v1/auth/google/callback",
+			TokenURL:     server.URL + "/token",
+			UserInfoURL:  server.URL + "/userinfo",
+		},
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func TestExchangeCodeForToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		want := map[string]string{
+			"code":          "auth-code",
+			"client_id":     "client-id",
+			"client_secret": "client-secret",
+			"redirect_uri":  "https://api.example.com/api/v1/auth/google/callback",
+			"grant_type":    "authorization_code",
+		}
+		for field, value := range want {
+			if got := r.PostForm.Get(field); got != value {
+				t.Errorf("%s = %q, want %q", field, got, value)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "access-token", "token_type": "Bearer", "expires_in": 3599}`))
+	}))
+	defer server.Close()
+
+	token, err := newOAuthTestHandler(server, time.Second).exchangeCodeForToken("auth-code")
+	if err != nil {
+		t.Fatalf("exchangeCodeForToken() error = %v", err)
+	}
+	if token != "access-token" {
+		t.Errorf("exchangeCodeForToken() = %q, want access-token", token)
+	}
+}
+
+// A hung Google endpoint must fail the call once the client times out rather
+// than blocking the request
+func TestOAuthCallsTimeOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	h := newOAuthTestHandler(server, 50*time.Millisecond)
+	calls := map[string]func() error{
+		"token exchange": func() error {
+			_, err := h.exchangeCodeForToken("auth-code")
+			return err
+		},
+		"user info": func() error {
+			_, err := h.getGoogleUserInfo("access-token")
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			err := call()
+			if err == nil {
+				t.Fatal("call succeeded against a hung endpoint")
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("call took %v, want it to give up after the client timeout", elapsed)
+			}
+		})
+	}
+}
+
+// invalidGrantServer answers token requests the way Google does for a reused
+// or expired authorization code
+func invalidGrantServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant", "error_description": "Bad Request"}`))
+	}))
+}
+
+func TestExchangeCodeForTokenInvalidGrant(t *testing.T) {
+	server := invalidGrantServer()
+	defer server.Close()
+
+	_, err := newOAuthTestHandler(server, time.Second).exchangeCodeForToken("used-code")
+	var tokenErr *oauthTokenError
+	if !errors.As(err, &tokenErr) {
+		t.Fatalf("exchangeCodeForToken() error = %v, want an oauthTokenError", err)
+	}
+	if tokenErr.StatusCode != http.StatusBadRequest || tokenErr.Code != "invalid_grant" || tokenErr.Description != "Bad Request" {
+		t.Errorf("exchangeCodeForToken() error = %+v, want 400 invalid_grant (Bad Request)", tokenErr)
+	}
+}
+
+func TestGoogleCallbackInvalidGrant(t *testing.T) {
+	server := invalidGrantServer()
+	defer server.Close()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/auth/google/callback?state=abc&code=used-code", nil)
+
+	newOAuthTestHandler(server, time.Second).GoogleCallback(c)
+
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, want 307", w.Code)
+	}
+	want := "https://salon.example.com/login?error=token_exchange_failed&reason=invalid_grant"
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("redirect = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// strictJSON makes bindJSON reject request bodies with unknown fields
+// (STRICT_JSON). Set once at startup with SetStrictJSON.
+var strictJSON bool
+
+// SetStrictJSON turns strict JSON binding on or off for the create/update
+// handlers that use bindJSON
+func SetStrictJSON(enabled bool) {
+	strictJSON = enabled
+}
+
+// bindJSON is c.ShouldBindJSON, except that in strict mode a field the
+// request type doesn't declare (e.g. stylistId for stylist_id) is an error
+// naming that field instead of being silently ignored
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if !strictJSON {
+		return c.ShouldBindJSON(obj)
+	}
+
+	if c.Request.Body == nil {
+		return errors.New("invalid request: empty body")
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("invalid request: empty body")
+		}
+		// encoding/json reports these as: json: unknown field "stylistId"
+		if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+			return fmt.Errorf("unknown field %s", field)
+		}
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
+)
+
+// bootstrapMaxAge is how long clients may reuse a bootstrap payload before
+// revalidating it with its ETag
+const bootstrapMaxAge = 60 * time.Second
+
+// BookingBootstrap is everything the booking screen needs on load
+type BookingBootstrap struct {
+	ServiceCategories []ServiceCategoryGroup `json:"service_categories"`
+	Stylists          []bootstrapStylist     `json:"stylists"`
+	Rules             BookingRules           `json:"rules"`
+}
+
+// ServiceCategoryGroup is the active services of one category
+type ServiceCategoryGroup struct {
+	Category string          `json:"category"`
+	Services []model.Service `json:"services"`
+}
+
+// bootstrapStylist is an active stylist with their working days and the
+// IDs of the active services they can perform. The raw schedules are left
+// out in favour of WorkingDays.
+type bootstrapStylist struct {
+	*model.Stylist
+
+	Schedules   []model.StylistSchedule `json:"schedules,omitempty"`
+	DaysOfWeek  []int                   `json:"days_of_week"`
+	WorkingDays []WorkingDay            `json:"working_days"`
+	ServiceIDs  []uint                  `json:"service_ids"`
+}
+
+// BookingRules are the booking settings the client checks before submitting
+type BookingRules struct {
+	Timezone                string  `json:"timezone"`
+	MinLeadMinutes          int     `json:"min_lead_minutes"`
+	HoldMinutes             int     `json:"hold_minutes"`
+	MaxAdvanceDays          int     `json:"max_advance_days"`
+	LastBookableDate        string  `json:"last_bookable_date"`
+	CancellationNoticeHours float64 `json:"cancellation_notice_hours"`
+	MinDurationMinutes      int     `json:"min_duration_minutes"` // 0 means no limit
+	MaxDurationMinutes      int     `json:"max_duration_minutes"` // 0 means no limit
+	AutoConfirm             bool    `json:"auto_confirm"`
+	TaxRate                 float64 `json:"tax_rate"`
+}
+
+// GetBootstrap godoc
+// @Summary Services, stylists and booking rules for the booking screen in one call
+// @Description Active services grouped by category, active stylists with their
+// @Description working days and the services they can perform, and the booking
+// @Description rules. Supports If-None-Match; unchanged payloads return 304.
+// @Tags bookings
+// @Produce json
+// @Success 200 {object} BookingBootstrap
+// @Success 304
+// @Router /bookings/bootstrap [get]
+func (h *BookingHandler) GetBootstrap(c *gin.Context) {
+	services, _, err := h.serviceRepo.List(repository.ServiceListOptions{ActiveOnly: true})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch services")
+		return
+	}
+
+	stylists, err := h.stylistRepo.List(true)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch stylists")
+		return
+	}
+
+	bootstrapStylists, err := h.bootstrapStylists(stylists, services)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch stylist services")
+		return
+	}
+
+	rules, err := h.bookingRules()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+		return
+	}
+
+	body, err := json.Marshal(BookingBootstrap{
+		ServiceCategories: groupServicesByCategory(services),
+		Stylists:          bootstrapStylists,
+		Rules:             rules,
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to encode bootstrap data")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(bootstrapMaxAge.Seconds())))
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// bootstrapStylists adds working days and service IDs to the stylists. It
+// follows checkQualified: until mappings are configured, every stylist can
+// perform every active service.
+func (h *BookingHandler) bootstrapStylists(stylists []model.Stylist, services []model.Service) ([]bootstrapStylist, error) {
+	result := make([]bootstrapStylist, 0, len(stylists))
+	if len(stylists) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uint, 0, len(stylists))
+	for _, stylist := range stylists {
+		ids = append(ids, stylist.ID)
+	}
+
+	activeServiceIDs := make([]uint, 0, len(services))
+	active := make(map[uint]bool, len(services))
+	for _, svc := range services {
+		activeServiceIDs = append(activeServiceIDs, svc.ID)
+		active[svc.ID] = true
+	}
+
+	qualifiedForAll, err := h.availability.QualifiedForAll()
+	if err != nil {
+		return nil, err
+	}
+
+	serviceIDs := make(map[uint][]uint, len(stylists))
+	if !qualifiedForAll {
+		mappings, err := h.stylistRepo.GetServiceMappings(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, mapping := range mappings {
+			if active[mapping.ServiceID] {
+				serviceIDs[mapping.StylistID] = append(serviceIDs[mapping.StylistID], mapping.ServiceID)
+			}
+		}
+	}
+
+	schedules, err := h.stylistRepo.GetSchedulesByStylistIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	schedulesByStylist := make(map[uint][]model.StylistSchedule, len(stylists))
+	for _, schedule := range schedules {
+		schedulesByStylist[schedule.StylistID] = append(schedulesByStylist[schedule.StylistID], schedule)
+	}
+
+	for i := range stylists {
+		stylist := &stylists[i]
+		days, workingDays := groupWorkingDays(schedulesByStylist[stylist.ID])
+		stylistServiceIDs := serviceIDs[stylist.ID]
+		if qualifiedForAll {
+			stylistServiceIDs = activeServiceIDs
+		}
+		if stylistServiceIDs == nil {
+			stylistServiceIDs = []uint{}
+		}
+		result = append(result, bootstrapStylist{
+			Stylist:     stylist,
+			DaysOfWeek:  days,
+			WorkingDays: workingDays,
+			ServiceIDs:  stylistServiceIDs,
+		})
+	}
+	return result, nil
+}
+
+// bookingRules reads the booking settings the client needs
+func (h *BookingHandler) bookingRules() (BookingRules, error) {
+	now := time.Now().In(h.cfg.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	lastDate, days, err := service.LastBookableDate(h.settingsRepo, today)
+	if err != nil {
+		return BookingRules{}, err
+	}
+	notice, err := h.settingsRepo.GetFloat(model.SettingsKeyCancellationNoticeHours, model.DefaultCancellationNoticeHours)
+	if err != nil {
+		return BookingRules{}, err
+	}
+	autoConfirm, err := h.settingsRepo.GetBool(model.SettingsKeyAutoConfirm, false)
+	if err != nil {
+		return BookingRules{}, err
+	}
+	taxRate, err := h.settingsRepo.GetFloat(model.SettingsKeyTaxRate, 0)
+	if err != nil {
+		return BookingRules{}, err
+	}
+	minDuration, maxDuration, err := h.durationLimits()
+	if err != nil {
+		return BookingRules{}, err
+	}
+
+	return BookingRules{
+		Timezone:                h.cfg.Location.String(),
+		MinLeadMinutes:          h.cfg.MinLeadMinutes,
+		HoldMinutes:             h.cfg.HoldMinutes,
+		MaxAdvanceDays:          days,
+		LastBookableDate:        lastDate.Format("2006-01-02"),
+		CancellationNoticeHours: notice,
+		MinDurationMinutes:      minDuration,
+		MaxDurationMinutes:      maxDuration,
+		AutoConfirm:             autoConfirm,
+		TaxRate:                 taxRate,
+	}, nil
+}
+
+// groupServicesByCategory groups services, already ordered by category,
+// keeping each category's first position
+func groupServicesByCategory(services []model.Service) []ServiceCategoryGroup {
+	groups := []ServiceCategoryGroup{}
+	index := make(map[string]int)
+	for _, svc := range services {
+		i, ok := index[svc.Category]
+		if !ok {
+			i = len(groups)
+			index[svc.Category] = i
+			groups = append(groups, ServiceCategoryGroup{Category: svc.Category})
+		}
+		groups[i].Services = append(groups[i].Services, svc)
+	}
+	return groups
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// BookingSegmentRequest is one stylist's part of a group booking
+type BookingSegmentRequest struct {
+	StylistID  uint   `json:"stylist_id" binding:"required"`
+	ServiceIDs []uint `json:"service_ids" binding:"required,min=1"`
+	VariantIDs []uint `json:"variant_ids"`
+	StartTime  string `json:"start_time" binding:"required"` // HH:MM
+}
+
+// bookingGroupView is a group booking with each of its bookings serialized
+// for the caller's role
+type bookingGroupView struct {
+	*model.BookingGroup
+
+	Bookings []interface{} `json:"bookings"`
+}
+
+// createBookingGroup creates one booking per segment of req under a new
+// BookingGroup. Every segment is validated like a single booking; segments
+// with the same stylist must not overlap each other. The price is the sum of
+// the segments' prices, each with its stylist's price modifier.
+func (h *BookingHandler) createBookingGroup(c *gin.Context, req CreateBookingRequest, user *model.User) {
+	bookingDate, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid date format")
+		return
+	}
+
+	status, err := h.initialStatus()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+		return
+	}
+	taxRate, err := h.settingsRepo.GetFloat(model.SettingsKeyTaxRate, 0)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch tax rate")
+		return
+	}
+
+	customerName, customerPhone, customerEmail := customerInfo(user, req.CustomerName, req.CustomerPhone, req.CustomerEmail)
+	role, _ := middleware.GetUserRole(c)
+
+	group := &model.BookingGroup{UserID: user.ID}
+	bookings := make([]model.Booking, 0, len(req.Segments))
+	for i, segment := range req.Segments {
+		booking, code, apiErr := h.segmentBooking(segment, bookingDate, user.ID)
+		if apiErr == nil {
+			// The duration limits apply to each stylist's part on its own
+			outOfRange, err := h.checkDuration(role, booking.Duration)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+				return
+			}
+			if outOfRange != nil {
+				code, apiErr = http.StatusBadRequest, outOfRange
+			}
+		}
+		if apiErr == nil {
+			for _, other := range bookings {
+				if other.StylistID == booking.StylistID &&
+					booking.StartTime < other.OccupiedEndTime && other.StartTime < booking.OccupiedEndTime {
+					code, apiErr = http.StatusConflict, apierror.New(apierror.CodeBookingSlotTaken, "Segments for the same stylist overlap")
+					break
+				}
+			}
+		}
+		if apiErr != nil {
+			apiErr.Message = fmt.Sprintf("Segment %d: %s", i+1, apiErr.Message)
+			respondAPIError(c, code, apiErr)
+			return
+		}
+
+		booking.Status = status
+		booking.Notes = req.Notes
+		booking.CustomerName = customerName
+		booking.CustomerPhone = customerPhone
+		booking.CustomerEmail = customerEmail
+		booking.CreatedByID = &user.ID
+		booking.ApplyTax(taxRate)
+
+		group.Price += booking.Price
+		group.TaxAmount += booking.TaxAmount
+		group.TotalWithTax += booking.TotalWithTax
+		bookings = append(bookings, *booking)
+	}
+
+	if err := h.bookingRepo.CreateGroup(group, bookings); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create booking")
+		return
+	}
+	for _, booking := range bookings {
+		h.availability.Invalidate(booking.StylistID, booking.BookingDate)
+	}
+
+	// The group is already saved, so if reloading it with its relations fails
+	// answer with what was written and skip the notifications
+	saved, err := h.bookingRepo.GetGroup(group.ID)
+	if err != nil || saved == nil {
+		log.Printf("⚠️  Failed to reload booking group %d after creating it: %v", group.ID, err)
+	} else {
+		group = saved
+		for i := range group.Bookings {
+			h.notifier.NotifyNewBooking(c.Request.Context(), &group.Bookings[i])
+			h.notifier.SendBookingConfirmation(c.Request.Context(), &group.Bookings[i])
+		}
+	}
+
+	c.JSON(http.StatusCreated, bookingGroupView{
+		BookingGroup: group,
+		Bookings:     bookingViews(group.Bookings, role),
+	})
+}
+
+// segmentBooking resolves one segment of a group booking into an unsaved
+// booking with its services, times and price, checking the stylist, the
+// booking window and availability. It returns the status and error to
+// respond with when the segment can't be booked.
+func (h *BookingHandler) segmentBooking(segment BookingSegmentRequest, bookingDate time.Time, userID uint) (*model.Booking, int, *apierror.APIError) {
+	services, totalDuration, totalPrice, buffer, err := h.resolveServices(segment.ServiceIDs, segment.VariantIDs)
+	if err != nil {
+		return nil, http.StatusBadRequest, apierror.New(apierror.CodeInvalidService, err.Error())
+	}
+
+	stylist, err := h.stylistRepo.GetByID(segment.StylistID)
+	if err != nil || stylist == nil {
+		return nil, http.StatusBadRequest, apierror.New(apierror.CodeInvalidStylist, "Invalid stylist")
+	}
+	unqualified, err := h.checkQualified(stylist, services)
+	if err != nil {
+		return nil, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to check stylist services")
+	}
+	if unqualified != nil {
+		return nil, http.StatusBadRequest, unqualified
+	}
+
+	if status, apiErr := h.bookingWindowError(bookingDate, segment.StartTime); apiErr != nil {
+		return nil, status, apiErr
+	}
+	blackedOut, err := h.checkBlackout(services, bookingDate)
+	if err != nil {
+		return nil, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to check service blackouts")
+	}
+	if blackedOut != nil {
+		return nil, http.StatusBadRequest, blackedOut
+	}
+
+	endTime, err := addMinutes(segment.StartTime, totalDuration)
+	if err != nil {
+		return nil, http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, err.Error())
+	}
+	occupiedEndTime, err := addMinutes(segment.StartTime, totalDuration+buffer)
+	if err != nil {
+		return nil, http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, err.Error())
+	}
+
+	available, err := h.stylistRepo.IsAvailable(segment.StylistID, bookingDate, segment.StartTime, occupiedEndTime, userID, nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to check availability")
+	}
+	if !available {
+		return nil, http.StatusConflict, apierror.New(apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+	}
+
+	return &model.Booking{
+		UserID:          userID,
+		StylistID:       segment.StylistID,
+		Services:        services,
+		BookingDate:     bookingDate,
+		StartTime:       segment.StartTime,
+		EndTime:         endTime,
+		Duration:        totalDuration,
+		BufferMinutes:   buffer,
+		OccupiedEndTime: occupiedEndTime,
+		Price:           stylist.ApplyPriceModifier(totalPrice),
+	}, 0, nil
+}
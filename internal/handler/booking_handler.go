@@ -1,49 +1,171 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"linda-salon-api/config"
+	"linda-salon-api/internal/apierror"
 	"linda-salon-api/internal/middleware"
 	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/phone"
 	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
 )
 
 type BookingHandler struct {
-	bookingRepo *repository.BookingRepository
-	serviceRepo *repository.ServiceRepository
-	stylistRepo *repository.StylistRepository
-	userRepo    *repository.UserRepository
+	bookingRepo  *repository.BookingRepository
+	serviceRepo  *repository.ServiceRepository
+	stylistRepo  *repository.StylistRepository
+	userRepo     *repository.UserRepository
+	holdRepo     *repository.BookingHoldRepository
+	settingsRepo *repository.SettingsRepository
+	reviewRepo   *repository.ReviewRepository
+	auditRepo    *repository.AuditLogRepository
+	loyaltyRepo  *repository.LoyaltyRepository
+	notifier     *service.NotificationService
+	availability *service.AvailabilityService
+	cfg          *config.BookingConfig
 }
 
 func NewBookingHandler(
 	bookingRepo *repository.BookingRepository,
 	serviceRepo *repository.ServiceRepository,
 	stylistRepo *repository.StylistRepository,
-	userRepo    *repository.UserRepository,
+	userRepo *repository.UserRepository,
+	holdRepo *repository.BookingHoldRepository,
+	settingsRepo *repository.SettingsRepository,
+	reviewRepo *repository.ReviewRepository,
+	auditRepo *repository.AuditLogRepository,
+	loyaltyRepo *repository.LoyaltyRepository,
+	notifier *service.NotificationService,
+	availability *service.AvailabilityService,
+	cfg *config.BookingConfig,
 ) *BookingHandler {
 	return &BookingHandler{
-		bookingRepo: bookingRepo,
-		serviceRepo: serviceRepo,
-		stylistRepo: stylistRepo,
-		userRepo:    userRepo,
+		bookingRepo:  bookingRepo,
+		serviceRepo:  serviceRepo,
+		stylistRepo:  stylistRepo,
+		userRepo:     userRepo,
+		holdRepo:     holdRepo,
+		settingsRepo: settingsRepo,
+		reviewRepo:   reviewRepo,
+		auditRepo:    auditRepo,
+		loyaltyRepo:  loyaltyRepo,
+		notifier:     notifier,
+		availability: availability,
+		cfg:          cfg,
 	}
 }
 
+// CreateBookingRequest books services with one stylist (service_ids,
+// stylist_id, start_time), or several stylists at once or back-to-back
+// (segments); the two shapes can't be mixed
 type CreateBookingRequest struct {
-	ServiceIDs    []uint `json:"service_ids" binding:"required,min=1"` // 支援多個服務
-	StylistID     uint   `json:"stylist_id" binding:"required"`
+	ServiceIDs    []uint `json:"service_ids"` // 支援多個服務
+	VariantIDs    []uint `json:"variant_ids"`                          // 可選：各服務的價位選項，每個服務最多一個
+	StylistID     uint   `json:"stylist_id"`
 	Date          string `json:"date" binding:"required"`     // YYYY-MM-DD
-	StartTime     string `json:"start_time" binding:"required"` // HH:MM
+	StartTime     string `json:"start_time"` // HH:MM
+	// 團體預約：每個時段各自指定設計師、服務與開始時間，日期共用 Date
+	Segments      []BookingSegmentRequest `json:"segments" binding:"omitempty,max=10,dive"`
 	Notes         string `json:"notes"`
 	CustomerName  string `json:"customer_name"`  // 可選：覆蓋用戶姓名
 	CustomerPhone string `json:"customer_phone"` // 可選：覆蓋用戶電話
 	CustomerEmail string `json:"customer_email"` // 可選：覆蓋用戶信箱
 }
 
+// validate checks that the request uses exactly one of the single-stylist
+// and segments shapes, with the fields that shape requires
+func (r *CreateBookingRequest) validate() error {
+	if len(r.Segments) > 0 {
+		if len(r.ServiceIDs) > 0 || len(r.VariantIDs) > 0 || r.StylistID != 0 || r.StartTime != "" {
+			return errors.New("segments can't be combined with service_ids, variant_ids, stylist_id or start_time")
+		}
+		return nil
+	}
+	if len(r.ServiceIDs) == 0 {
+		return errors.New("service_ids is required")
+	}
+	if r.StylistID == 0 {
+		return errors.New("stylist_id is required")
+	}
+	if r.StartTime == "" {
+		return errors.New("start_time is required")
+	}
+	return nil
+}
+
+type QuoteRequest struct {
+	ServiceIDs []uint `json:"service_ids" binding:"required,min=1"`
+	VariantIDs []uint `json:"variant_ids"`
+	StylistID  uint   `json:"stylist_id" binding:"required"`
+}
+
+// BookingQuote is the computed price of a set of services with a stylist
+type BookingQuote struct {
+	StylistID          uint                       `json:"stylist_id"`
+	Services           []model.BookingServiceItem `json:"services"`
+	Duration           int                        `json:"duration"`
+	BasePrice          int                        `json:"base_price"`
+	PriceModifierType  string                     `json:"price_modifier_type,omitempty"`
+	PriceModifierValue int                        `json:"price_modifier_value"`
+	Price              int                        `json:"price"` // pre-tax subtotal
+	TaxRate            float64                    `json:"tax_rate"`
+	TaxAmount          int                        `json:"tax_amount"`
+	TotalWithTax       int                        `json:"total_with_tax"`
+}
+
+type CancelBookingRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=500"` // 可選：取消原因
+}
+
+// RescheduleBookingRequest is the body of a reschedule, and the query
+// string of a reschedule preview
+type RescheduleBookingRequest struct {
+	StylistID  *uint  `json:"stylist_id" form:"stylist_id"`                    // 可選：同時更換設計師
+	ServiceIDs []uint `json:"service_ids" form:"service_ids"`                  // 可選：更換服務，會重新計算時長與價格
+	VariantIDs []uint `json:"variant_ids" form:"variant_ids"`                  // 可選：搭配 service_ids 的價位選項
+	Date       string `json:"date" form:"date" binding:"required"`             // YYYY-MM-DD
+	StartTime  string `json:"start_time" form:"start_time" binding:"required"` // HH:MM
+}
+
+// ReschedulePreview is what a reschedule would change, without saving it
+type ReschedulePreview struct {
+	Available bool `json:"available"`
+	// Reason explains why the new slot can't be booked; nil when available
+	Reason *apierror.APIError `json:"reason,omitempty"`
+
+	StylistID    uint   `json:"stylist_id"`
+	Date         string `json:"date"`
+	StartTime    string `json:"start_time"`
+	EndTime      string `json:"end_time"`
+	Duration     int    `json:"duration"`
+	Price        int    `json:"price"`
+	TaxAmount    int    `json:"tax_amount"`
+	TotalWithTax int    `json:"total_with_tax"`
+
+	// Differences from the current booking
+	DurationChange int `json:"duration_change"` // minutes
+	PriceChange    int `json:"price_change"`    // total_with_tax
+}
+
+// reschedulePreviewReasons are the failures a preview reports as an
+// unavailable slot rather than as an error response
+var reschedulePreviewReasons = map[string]bool{
+	apierror.CodeBookingSlotTaken:    true,
+	apierror.CodeBookingTooSoon:      true,
+	apierror.CodeBookingTooFarAhead:  true,
+	apierror.CodeStylistNotQualified: true,
+}
+
 type UpdateBookingRequest struct {
 	ServiceID *uint   `json:"service_id"`
 	StylistID *uint   `json:"stylist_id"`
@@ -59,8 +181,12 @@ type UpdateBookingRequest struct {
 // @Security BearerAuth
 // @Produce json
 // @Param status query string false "Filter by status"
+// @Param tag query string false "Filter by tag (admin only)"
+// @Param stylist_id query int false "Filter by stylist"
 // @Param start_date query string false "Start date (YYYY-MM-DD)"
 // @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param start_datetime query string false "Bookings starting at or after (YYYY-MM-DDTHH:MM)"
+// @Param end_datetime query string false "Bookings starting at or before (YYYY-MM-DDTHH:MM)"
 // @Param limit query int false "Limit" default(20)
 // @Param offset query int false "Offset" default(0)
 // @Success 200 {array} model.Booking
@@ -69,40 +195,123 @@ func (h *BookingHandler) ListBookings(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
 	role, _ := middleware.GetUserRole(c)
 
-	status := c.Query("status")
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-
-	var startDate, endDate *time.Time
-	if sd := c.Query("start_date"); sd != "" {
-		t, _ := time.Parse("2006-01-02", sd)
-		startDate = &t
+	limit, offset, err := queryPagination(c, 20, 0)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
 	}
-	if ed := c.Query("end_date"); ed != "" {
-		t, _ := time.Parse("2006-01-02", ed)
-		endDate = &t
+
+	filter, err := parseBookingListFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
 	}
 
-	var userIDPtr *uint
-	// Non-admin users can only see their own bookings
+	// Non-admin users can only see their own bookings, and tags are staff-only
 	if role != "admin" {
-		userIDPtr = &userID
+		filter.UserID = &userID
+		filter.Tag = ""
+	}
+
+	bookings, total, err := h.bookingRepo.List(filter, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch bookings")
+		return
 	}
 
-	bookings, total, err := h.bookingRepo.List(userIDPtr, status, startDate, endDate, limit, offset)
+	views, err := h.ownerBookingViews(bookings, role, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check reviews")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"bookings": bookings,
+		"bookings": views,
 		"total":    total,
 		"limit":    limit,
 		"offset":   offset,
 	})
 }
 
+// GetBookingStatusCounts godoc
+// @Summary Count bookings per status for the booking list's filters (admin only)
+// @Description Takes the same filters as the booking list (status itself is
+// @Description ignored) and returns every status, including those with 0 bookings.
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param start query string false "Start date (YYYY-MM-DD), alias of start_date"
+// @Param end query string false "End date (YYYY-MM-DD), alias of end_date"
+// @Param stylist_id query int false "Filter by stylist"
+// @Param tag query string false "Filter by tag"
+// @Success 200 {object} map[string]int64
+// @Router /admin/bookings/status-counts [get]
+func (h *BookingHandler) GetBookingStatusCounts(c *gin.Context) {
+	filter, err := parseBookingListFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	counts, err := h.bookingRepo.CountByStatusFiltered(filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to count bookings")
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
+
+// parseBookingListFilter reads the booking list filters from the query
+// string. start/end are accepted as aliases of start_date/end_date.
+func parseBookingListFilter(c *gin.Context) (repository.BookingListFilter, error) {
+	filter := repository.BookingListFilter{
+		Status: c.Query("status"),
+		Tag:    c.Query("tag"),
+	}
+
+	if v := c.Query("stylist_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("stylist_id must be a positive integer, got %q", v)
+		}
+		stylistID := uint(id)
+		filter.StylistID = &stylistID
+	}
+
+	if sd := c.DefaultQuery("start_date", c.Query("start")); sd != "" {
+		t, err := time.Parse("2006-01-02", sd)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid start date format, use YYYY-MM-DD")
+		}
+		filter.StartDate = &t
+	}
+	if ed := c.DefaultQuery("end_date", c.Query("end")); ed != "" {
+		t, err := time.Parse("2006-01-02", ed)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid end date format, use YYYY-MM-DD")
+		}
+		filter.EndDate = &t
+	}
+
+	if sdt := c.Query("start_datetime"); sdt != "" {
+		t, err := parseDateTime(sdt)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid start_datetime format, use YYYY-MM-DDTHH:MM")
+		}
+		filter.StartDateTime = &t
+	}
+	if edt := c.Query("end_datetime"); edt != "" {
+		t, err := parseDateTime(edt)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid end_datetime format, use YYYY-MM-DDTHH:MM")
+		}
+		filter.EndDateTime = &t
+	}
+
+	return filter, nil
+}
+
 // GetBooking godoc
 // @Summary Get booking by ID
 // @Tags bookings
@@ -114,17 +323,17 @@ func (h *BookingHandler) ListBookings(c *gin.Context) {
 func (h *BookingHandler) GetBooking(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
 		return
 	}
 
 	booking, err := h.bookingRepo.GetByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
 		return
 	}
 	if booking == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
 		return
 	}
 
@@ -132,15 +341,62 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
 	role, _ := middleware.GetUserRole(c)
 	if role != "admin" && booking.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Access denied")
 		return
 	}
 
-	c.JSON(http.StatusOK, booking)
+	views, err := h.ownerBookingViews([]model.Booking{*booking}, role, userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check reviews")
+		return
+	}
+	c.JSON(http.StatusOK, views[0])
+}
+
+// GetBookingICS godoc
+// @Summary Download a booking as an iCalendar file
+// @Tags bookings
+// @Security BearerAuth
+// @Produce text/calendar
+// @Param id path int true "Booking ID"
+// @Success 200 {string} string "iCalendar file"
+// @Router /bookings/{id}/ics [get]
+func (h *BookingHandler) GetBookingICS(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
+		return
+	}
+
+	// Bookings owned by someone else are reported as missing
+	userID, _ := middleware.GetUserID(c)
+	role, _ := middleware.GetUserRole(c)
+	if booking == nil || (role != "admin" && booking.UserID != userID) {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+
+	contact, err := service.LoadSalonContact(h.settingsRepo)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch salon contact")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, service.ICSFilename(booking)))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(service.BuildCalendar([]model.Booking{*booking}, contact)))
 }
 
 // CreateBooking godoc
 // @Summary Create a new booking
+// @Description Either books services with one stylist, or, when segments is
+// @Description given, creates a group booking with one booking per segment
+// @Description and responds with the group.
 // @Tags bookings
 // @Security BearerAuth
 // @Accept json
@@ -150,90 +406,122 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 // @Router /bookings [post]
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	var req CreateBookingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	if err := req.validate(); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
 		return
 	}
+	customerPhone, err := normalizeCustomerPhone(req.CustomerPhone)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidPhone, "Invalid customer phone number")
+		return
+	}
+	req.CustomerPhone = customerPhone
 
 	userID, _ := middleware.GetUserID(c)
 
 	// Get user info
 	user, err := h.userRepo.GetByID(userID)
 	if err != nil || user == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+		return
+	}
+	if user.IsBlocked {
+		respondError(c, http.StatusForbidden, apierror.CodeAccountBlocked, "Your account is blocked from making bookings")
 		return
 	}
 
-	// Get all services info and calculate total duration and price
-	var services []model.BookingServiceItem
-	var totalDuration int
-	var totalPrice int
-
-	for _, serviceID := range req.ServiceIDs {
-		service, err := h.serviceRepo.GetByID(serviceID)
-		if err != nil || service == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid service ID: %d", serviceID)})
-			return
-		}
-
-		services = append(services, model.BookingServiceItem{
-			ID:       service.ID,
-			Name:     service.Name,
-			Price:    service.Price,
-			Duration: service.Duration,
-		})
+	if len(req.Segments) > 0 {
+		h.createBookingGroup(c, req, user)
+		return
+	}
 
-		totalDuration += service.Duration
-		totalPrice += service.Price
+	// Get all services info and calculate total duration and price
+	services, totalDuration, totalPrice, buffer, err := h.resolveServices(req.ServiceIDs, req.VariantIDs)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidService, err.Error())
+		return
+	}
+	role, _ := middleware.GetUserRole(c)
+	outOfRange, err := h.checkDuration(role, totalDuration)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+		return
+	}
+	if outOfRange != nil {
+		respondAPIError(c, http.StatusBadRequest, outOfRange)
+		return
 	}
 
 	// Get stylist info
 	stylist, err := h.stylistRepo.GetByID(req.StylistID)
 	if err != nil || stylist == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidStylist, "Invalid stylist")
+		return
+	}
+	unqualified, err := h.checkQualified(stylist, services)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check stylist services")
+		return
+	}
+	if unqualified != nil {
+		respondAPIError(c, http.StatusBadRequest, unqualified)
 		return
 	}
 
 	// Parse booking date
 	bookingDate, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid date format")
+		return
+	}
+	if !h.checkBookingWindow(c, bookingDate, req.StartTime) {
+		return
+	}
+	blackedOut, err := h.checkBlackout(services, bookingDate)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check service blackouts")
+		return
+	}
+	if blackedOut != nil {
+		respondAPIError(c, http.StatusBadRequest, blackedOut)
 		return
 	}
 
-	// Calculate end time based on total duration
-	startHour, _ := strconv.Atoi(req.StartTime[:2])
-	startMin, _ := strconv.Atoi(req.StartTime[3:5])
-	endMin := startMin + totalDuration
-	endHour := startHour + (endMin / 60)
-	endMin = endMin % 60
-	endTime := time.Date(0, 0, 0, endHour, endMin, 0, 0, time.UTC).Format("15:04")
+	// Calculate end time based on total duration; the stylist stays busy
+	// through the last service's cleanup buffer
+	endTime, err := addMinutes(req.StartTime, totalDuration)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+	occupiedEndTime, err := addMinutes(req.StartTime, totalDuration+buffer)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
 
-	// Check stylist availability
-	available, err := h.stylistRepo.IsAvailable(req.StylistID, bookingDate, req.StartTime, endTime)
+	// Check stylist availability (the caller's own holds don't block them)
+	available, err := h.stylistRepo.IsAvailable(req.StylistID, bookingDate, req.StartTime, occupiedEndTime, userID, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check availability")
 		return
 	}
 	if !available {
-		c.JSON(http.StatusConflict, gin.H{"error": "Stylist is not available at this time"})
+		respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
 		return
 	}
 
 	// 準備客戶資訊（優先使用前端傳來的，否則用資料庫的）
-	customerName := req.CustomerName
-	if customerName == "" {
-		customerName = user.Name
-	}
-
-	customerPhone := req.CustomerPhone
-	if customerPhone == "" && user.Phone != nil {
-		customerPhone = *user.Phone
-	}
+	customerName, customerPhone, customerEmail := customerInfo(user, req.CustomerName, req.CustomerPhone, req.CustomerEmail)
 
-	customerEmail := req.CustomerEmail
-	if customerEmail == "" {
-		customerEmail = user.Email
+	status, err := h.initialStatus()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+		return
 	}
 
 	// Create booking
@@ -245,23 +533,151 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		StartTime:     req.StartTime,
 		EndTime:       endTime,
 		Duration:      totalDuration,
-		Price:         totalPrice,
-		Status:        model.BookingStatusPending,
-		Notes:         req.Notes,
-		CustomerName:  customerName,
-		CustomerPhone: customerPhone,
-		CustomerEmail: customerEmail,
+		BufferMinutes:   buffer,
+		OccupiedEndTime: occupiedEndTime,
+		Price:           stylist.ApplyPriceModifier(totalPrice),
+		Status:          status,
+		Notes:           req.Notes,
+		CustomerName:    customerName,
+		CustomerPhone:   customerPhone,
+		CustomerEmail:   customerEmail,
+		CreatedByID:     &userID,
 	}
 
-	if err := h.bookingRepo.Create(booking); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+	taxRate, err := h.settingsRepo.GetFloat(model.SettingsKeyTaxRate, 0)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch tax rate")
 		return
 	}
+	booking.ApplyTax(taxRate)
 
-	// Fetch complete booking with relations
-	booking, _ = h.bookingRepo.GetByID(booking.ID)
+	// 在交易中再次檢查並寫入，避免兩個請求同時搶到同一時段
+	if err := h.bookingRepo.CreateWithAvailabilityCheck(booking, req.StartTime, occupiedEndTime); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create booking")
+		return
+	}
+	h.availability.Invalidate(booking.StylistID, booking.BookingDate)
+
+	// Fetch complete booking with relations. The booking is already saved, so
+	// if that fails answer with what was written and skip the notifications.
+	if created, err := h.bookingRepo.GetByID(booking.ID); err == nil {
+		booking = created
+		h.notifier.NotifyNewBooking(c.Request.Context(), booking)
+		h.notifier.SendBookingConfirmation(c.Request.Context(), booking)
+	} else {
+		log.Printf("⚠️  Failed to reload booking %d after creating it: %v", booking.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, bookingView(booking, role))
+}
+
+// QuoteBooking godoc
+// @Summary Compute duration and price for services with a stylist
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body QuoteRequest true "Services and stylist"
+// @Success 200 {object} BookingQuote
+// @Router /bookings/quote [post]
+func (h *BookingHandler) QuoteBooking(c *gin.Context) {
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	services, totalDuration, totalPrice, _, err := h.resolveServices(req.ServiceIDs, req.VariantIDs)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidService, err.Error())
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(req.StylistID)
+	if err != nil || stylist == nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidStylist, "Invalid stylist")
+		return
+	}
+
+	taxRate, err := h.settingsRepo.GetFloat(model.SettingsKeyTaxRate, 0)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch tax rate")
+		return
+	}
+
+	priced := model.Booking{Price: stylist.ApplyPriceModifier(totalPrice)}
+	priced.ApplyTax(taxRate)
+
+	c.JSON(http.StatusOK, BookingQuote{
+		StylistID:          stylist.ID,
+		Services:           services,
+		Duration:           totalDuration,
+		BasePrice:          totalPrice,
+		PriceModifierType:  stylist.PriceModifierType,
+		PriceModifierValue: stylist.PriceModifierValue,
+		Price:              priced.Price,
+		TaxRate:            taxRate,
+		TaxAmount:          priced.TaxAmount,
+		TotalWithTax:       priced.TotalWithTax,
+	})
+}
+
+// BookingEndTime is when a booking of the given services starting at
+// StartTime would end
+type BookingEndTime struct {
+	StartTime     string `json:"start_time"`
+	EndTime       string `json:"end_time"`
+	TotalDuration int    `json:"total_duration"` // minutes
+}
+
+// GetEndTime godoc
+// @Summary Compute the end time of a booking from its services and start time
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param service_ids query []int true "Service IDs (repeated or comma-separated)" collectionFormat(multi)
+// @Param variant_ids query []int false "Variant IDs for the services" collectionFormat(multi)
+// @Param start_time query string true "Start time (HH:MM)"
+// @Success 200 {object} BookingEndTime
+// @Router /bookings/end-time [get]
+func (h *BookingHandler) GetEndTime(c *gin.Context) {
+	serviceIDs, err := queryUintList(c, "service_ids")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+	if len(serviceIDs) == 0 {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "service_ids is required")
+		return
+	}
+	variantIDs, err := queryUintList(c, "variant_ids")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+	startTime := c.Query("start_time")
+
+	_, totalDuration, _, _, err := h.resolveServices(serviceIDs, variantIDs)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidService, err.Error())
+		return
+	}
+
+	endTime, err := addMinutes(startTime, totalDuration)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
 
-	c.JSON(http.StatusCreated, booking)
+	c.JSON(http.StatusOK, BookingEndTime{
+		StartTime:     startTime,
+		EndTime:       endTime,
+		TotalDuration: totalDuration,
+	})
 }
 
 // UpdateBookingStatus godoc
@@ -277,15 +693,15 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 func (h *BookingHandler) UpdateBookingStatus(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
 		return
 	}
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
 		return
 	}
 
@@ -295,38 +711,81 @@ func (h *BookingHandler) UpdateBookingStatus(c *gin.Context) {
 		model.BookingStatusConfirmed: true,
 		model.BookingStatusCompleted: true,
 		model.BookingStatusCancelled: true,
+		model.BookingStatusNoShow:    true,
 	}
 	if !validStatuses[req.Status] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidStatus, "Invalid status")
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
+		return
+	}
+	if booking == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
 		return
 	}
+	previousStatus := booking.Status
 
-	if err := h.bookingRepo.UpdateStatus(uint(id), req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+	actorID, _ := middleware.GetUserID(c)
+	if err := h.bookingRepo.UpdateStatus(uint(id), req.Status, actorID); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update status")
 		return
 	}
+	h.availability.Invalidate(booking.StylistID, booking.BookingDate)
+
+	if req.Status == model.BookingStatusCompleted && previousStatus != model.BookingStatusCompleted {
+		h.awardLoyaltyPoints(booking)
+	}
 
-	booking, _ := h.bookingRepo.GetByID(uint(id))
+	if req.Status != previousStatus {
+		recordAudit(c, h.auditRepo, model.AuditActionStatusChange, model.AuditEntityBooking, uint(id), map[string]interface{}{
+			"status": gin.H{"from": previousStatus, "to": req.Status},
+		})
+	}
+
+	booking, _ = h.bookingRepo.GetByID(uint(id))
+	if req.Status == model.BookingStatusCancelled && previousStatus != model.BookingStatusCancelled {
+		h.notifier.NotifyCancellation(c.Request.Context(), booking)
+	}
+	if req.Status == model.BookingStatusConfirmed && previousStatus != model.BookingStatusConfirmed {
+		h.notifier.SendBookingConfirmation(c.Request.Context(), booking)
+	}
 	c.JSON(http.StatusOK, booking)
 }
 
 // CancelBooking godoc
 // @Summary Cancel a booking
+// @Description Cancelling any booking of a group booking cancels every active booking in the group.
+// @Description Customers can't cancel within booking.cancellation_notice_hours
+// @Description of the start time (400 CANCELLATION_TOO_LATE); admins can.
 // @Tags bookings
 // @Security BearerAuth
+// @Accept json
 // @Param id path int true "Booking ID"
+// @Param request body CancelBookingRequest false "Optional cancellation reason"
 // @Success 200 {object} model.Booking
 // @Router /bookings/{id}/cancel [post]
 func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
 		return
 	}
 
+	var req CancelBookingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+			return
+		}
+	}
+
 	booking, err := h.bookingRepo.GetByID(uint(id))
 	if err != nil || booking == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
 		return
 	}
 
@@ -334,21 +793,842 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
 	role, _ := middleware.GetUserRole(c)
 	if role != "admin" && booking.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Access denied")
 		return
 	}
 
 	// Check if cancellable
-	if !booking.IsCancellable() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking cannot be cancelled"})
+	if !booking.IsActive() {
+		respondError(c, http.StatusBadRequest, apierror.CodeBookingNotModifiable, "Booking cannot be cancelled")
 		return
 	}
 
-	if err := h.bookingRepo.UpdateStatus(uint(id), model.BookingStatusCancelled); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+	// 團體預約的任一筆取消時，整組仍有效的預約一併取消
+	cancelled := []model.Booking{*booking}
+	if booking.GroupID != nil {
+		group, err := h.bookingRepo.GetGroup(*booking.GroupID)
+		if err != nil || group == nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking group")
+			return
+		}
+		cancelled = cancelled[:0]
+		for _, member := range group.Bookings {
+			if member.IsActive() {
+				cancelled = append(cancelled, member)
+			}
+		}
+	}
+
+	// 顧客需在開始前 booking.cancellation_notice_hours 小時取消，管理員不受限制
+	if role != "admin" {
+		hours, err := h.settingsRepo.GetFloat(model.SettingsKeyCancellationNoticeHours, model.DefaultCancellationNoticeHours)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+			return
+		}
+		notice := time.Duration(hours * float64(time.Hour))
+		for i := range cancelled {
+			if !cancelled[i].IsCancellable(time.Now(), notice, h.cfg.Location) {
+				respondAPIError(c, http.StatusBadRequest, apierror.New(apierror.CodeCancellationTooLate,
+					fmt.Sprintf("Bookings can't be cancelled within %g hours of the start time", hours)).
+					WithDetails(gin.H{"cancellation_notice_hours": hours}))
+				return
+			}
+		}
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if booking.GroupID != nil {
+		err = h.bookingRepo.CancelGroup(*booking.GroupID, reason, userID)
+	} else {
+		err = h.bookingRepo.Cancel(uint(id), reason, userID)
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to cancel booking")
 		return
 	}
 
+	for _, member := range cancelled {
+		h.availability.Invalidate(member.StylistID, member.BookingDate)
+		if role == "admin" {
+			changes := map[string]interface{}{
+				"status": gin.H{"from": member.Status, "to": model.BookingStatusCancelled},
+			}
+			if reason != "" {
+				changes["cancellation_reason"] = gin.H{"from": member.CancellationReason, "to": reason}
+			}
+			recordAudit(c, h.auditRepo, model.AuditActionStatusChange, model.AuditEntityBooking, member.ID, changes)
+		}
+
+		if updated, _ := h.bookingRepo.GetByID(member.ID); updated != nil {
+			h.notifier.NotifyCancellation(c.Request.Context(), updated)
+		}
+	}
+
 	booking, _ = h.bookingRepo.GetByID(uint(id))
-	c.JSON(http.StatusOK, booking)
+	c.JSON(http.StatusOK, bookingView(booking, role))
+}
+
+// RescheduleBooking godoc
+// @Summary Move a booking to a new date/time, optionally changing stylist or services (owner or admin)
+// @Description The service list and price are kept unless service_ids is given.
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body RescheduleBookingRequest true "New date and time"
+// @Success 200 {object} model.Booking
+// @Router /bookings/{id}/reschedule [patch]
+func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var req RescheduleBookingRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil || booking == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+
+	// Check authorization
+	userID, _ := middleware.GetUserID(c)
+	role, _ := middleware.GetUserRole(c)
+	if role != "admin" && booking.UserID != userID {
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Access denied")
+		return
+	}
+
+	// Completed, cancelled and no-show bookings can't be moved
+	if !booking.IsActive() {
+		respondError(c, http.StatusBadRequest, apierror.CodeBookingNotModifiable, "Booking cannot be rescheduled")
+		return
+	}
+
+	previousStylistID, previousDate := booking.StylistID, booking.BookingDate
+	if status, apiErr := h.applyReschedule(booking, req); apiErr != nil {
+		respondAPIError(c, status, apiErr)
+		return
+	}
+
+	if err := h.bookingRepo.Reschedule(booking, userID); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to reschedule booking")
+		return
+	}
+	h.availability.Invalidate(previousStylistID, previousDate)
+	h.availability.Invalidate(booking.StylistID, booking.BookingDate)
+
+	booking, _ = h.bookingRepo.GetByID(booking.ID)
+	c.JSON(http.StatusOK, bookingView(booking, role))
+}
+
+// applyReschedule moves booking, in memory only, to the stylist, date, time
+// and services in req, then checks that the new slot can be booked. It
+// returns the status and error to respond with, or nil when the move is
+// possible. The booking's own time range doesn't count as a conflict.
+func (h *BookingHandler) applyReschedule(booking *model.Booking, req RescheduleBookingRequest) (int, *apierror.APIError) {
+	stylistID := booking.StylistID
+	if req.StylistID != nil {
+		stylistID = *req.StylistID
+	}
+	stylist, err := h.stylistRepo.GetByID(stylistID)
+	if err != nil || stylist == nil {
+		return http.StatusBadRequest, apierror.New(apierror.CodeInvalidStylist, "Invalid stylist")
+	}
+
+	bookingDate, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, "Invalid date format")
+	}
+
+	// 未更換服務時保留原本的服務與價格
+	servicesChanged := len(req.ServiceIDs) > 0
+	if servicesChanged {
+		services, totalDuration, totalPrice, buffer, err := h.resolveServices(req.ServiceIDs, req.VariantIDs)
+		if err != nil {
+			return http.StatusBadRequest, apierror.New(apierror.CodeInvalidService, err.Error())
+		}
+		taxRate, err := h.settingsRepo.GetFloat(model.SettingsKeyTaxRate, 0)
+		if err != nil {
+			return http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to fetch tax rate")
+		}
+		booking.Services = services
+		booking.Duration = totalDuration
+		booking.BufferMinutes = buffer
+		booking.Price = stylist.ApplyPriceModifier(totalPrice)
+		booking.ApplyTax(taxRate)
+	}
+
+	endTime, err := addMinutes(req.StartTime, booking.Duration)
+	if err != nil {
+		return http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, err.Error())
+	}
+	occupiedEndTime, err := addMinutes(req.StartTime, booking.Duration+booking.BufferMinutes)
+	if err != nil {
+		return http.StatusBadRequest, apierror.New(apierror.CodeBadRequest, err.Error())
+	}
+
+	stylistChanged := stylistID != booking.StylistID
+	booking.StylistID = stylistID
+	booking.BookingDate = bookingDate
+	booking.StartTime = req.StartTime
+	booking.EndTime = endTime
+	booking.OccupiedEndTime = occupiedEndTime
+
+	if servicesChanged || stylistChanged {
+		unqualified, err := h.checkQualified(stylist, booking.Services)
+		if err != nil {
+			return http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to check stylist services")
+		}
+		if unqualified != nil {
+			return http.StatusBadRequest, unqualified
+		}
+	}
+
+	if status, apiErr := h.bookingWindowError(bookingDate, req.StartTime); apiErr != nil {
+		return status, apiErr
+	}
+	blackedOut, err := h.checkBlackout(booking.Services, bookingDate)
+	if err != nil {
+		return http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to check service blackouts")
+	}
+	if blackedOut != nil {
+		return http.StatusBadRequest, blackedOut
+	}
+
+	// The booking itself must not count as a conflict when it's nudged
+	// within its own time range
+	available, err := h.stylistRepo.IsAvailable(stylistID, bookingDate, req.StartTime, occupiedEndTime, booking.UserID, &booking.ID)
+	if err != nil {
+		return http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to check availability")
+	}
+	if !available {
+		return http.StatusConflict, apierror.New(apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+	}
+	return 0, nil
+}
+
+// PreviewReschedule godoc
+// @Summary Check whether a booking can be moved, without saving (owner or admin)
+// @Description Runs the same checks as a reschedule and returns the new end time,
+// @Description duration and price. When the slot can't be booked (taken, too soon,
+// @Description too far ahead, stylist not qualified) available is false and reason
+// @Description holds the error the reschedule would return.
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param date query string true "New date (YYYY-MM-DD)"
+// @Param start_time query string true "New start time (HH:MM)"
+// @Param stylist_id query int false "New stylist"
+// @Param service_ids query []int false "New services" collectionFormat(multi)
+// @Param variant_ids query []int false "Variants for the new services" collectionFormat(multi)
+// @Success 200 {object} ReschedulePreview
+// @Router /bookings/{id}/reschedule/preview [get]
+func (h *BookingHandler) PreviewReschedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var req RescheduleBookingRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil || booking == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	role, _ := middleware.GetUserRole(c)
+	if role != "admin" && booking.UserID != userID {
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Access denied")
+		return
+	}
+
+	if !booking.IsActive() {
+		respondError(c, http.StatusBadRequest, apierror.CodeBookingNotModifiable, "Booking cannot be rescheduled")
+		return
+	}
+
+	current := *booking
+	status, apiErr := h.applyReschedule(booking, req)
+	if apiErr != nil && !reschedulePreviewReasons[apiErr.Code] {
+		respondAPIError(c, status, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, ReschedulePreview{
+		Available:      apiErr == nil,
+		Reason:         apiErr,
+		StylistID:      booking.StylistID,
+		Date:           booking.BookingDate.Format("2006-01-02"),
+		StartTime:      booking.StartTime,
+		EndTime:        booking.EndTime,
+		Duration:       booking.Duration,
+		Price:          booking.Price,
+		TaxAmount:      booking.TaxAmount,
+		TotalWithTax:   booking.TotalWithTax,
+		DurationChange: booking.Duration - current.Duration,
+		PriceChange:    booking.TotalWithTax - current.TotalWithTax,
+	})
+}
+
+// BookingConflict is a pair of overlapping bookings for the same stylist
+type BookingConflict struct {
+	StylistID   uint                 `json:"stylist_id"`
+	StylistName string               `json:"stylist_name"`
+	Bookings    [2]ConflictedBooking `json:"bookings"`
+}
+
+// ConflictedBooking summarizes one side of a booking conflict
+type ConflictedBooking struct {
+	ID           uint   `json:"id"`
+	CustomerName string `json:"customer_name"`
+	StartTime    string `json:"start_time"`
+	EndTime      string `json:"end_time"`
+	Status       string `json:"status"`
+}
+
+// GetBookingConflicts godoc
+// @Summary Report overlapping bookings for a date (admin only)
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param date query string true "Date (YYYY-MM-DD)"
+// @Success 200 {array} BookingConflict
+// @Router /admin/bookings/conflicts [get]
+func (h *BookingHandler) GetBookingConflicts(c *gin.Context) {
+	date, err := time.Parse("2006-01-02", c.Query("date"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid date format, use YYYY-MM-DD")
+		return
+	}
+
+	pairs, err := h.bookingRepo.FindConflicts(date)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to scan booking conflicts")
+		return
+	}
+
+	conflicts := make([]BookingConflict, 0, len(pairs))
+	if len(pairs) == 0 {
+		c.JSON(http.StatusOK, conflicts)
+		return
+	}
+
+	bookings, err := h.bookingRepo.GetByDate(date)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch bookings")
+		return
+	}
+	byID := make(map[uint]*model.Booking, len(bookings))
+	for i := range bookings {
+		byID[bookings[i].ID] = &bookings[i]
+	}
+
+	for _, pair := range pairs {
+		first, second := byID[pair.FirstID], byID[pair.SecondID]
+		if first == nil || second == nil {
+			continue
+		}
+		conflicts = append(conflicts, BookingConflict{
+			StylistID:   first.StylistID,
+			StylistName: first.Stylist.Name,
+			Bookings:    [2]ConflictedBooking{conflictedBooking(first), conflictedBooking(second)},
+		})
+	}
+
+	c.JSON(http.StatusOK, conflicts)
+}
+
+// GetDaySheet godoc
+// @Summary Printable schedule of a day's bookings grouped by stylist (admin only)
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Produce application/pdf
+// @Param date query string false "Date (YYYY-MM-DD), defaults to today"
+// @Param format query string false "json (default) or pdf"
+// @Success 200 {object} service.DaySheet
+// @Router /admin/day-sheet [get]
+func (h *BookingHandler) GetDaySheet(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "pdf" {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "format must be json or pdf")
+		return
+	}
+
+	now := time.Now().In(h.cfg.Location)
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if dateStr := c.Query("date"); dateStr != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid date format, use YYYY-MM-DD")
+			return
+		}
+	}
+
+	bookings, err := h.bookingRepo.GetByDate(date)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch bookings")
+		return
+	}
+
+	sheet := service.BuildDaySheet(date, bookings)
+	if format == "json" {
+		c.JSON(http.StatusOK, sheet)
+		return
+	}
+
+	pdf, err := service.RenderDaySheetPDF(sheet)
+	if err != nil {
+		log.Printf("❌ Failed to render day sheet for %s: %v", sheet.Date, err)
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to render day sheet")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, service.DaySheetFilename(sheet)))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+func conflictedBooking(booking *model.Booking) ConflictedBooking {
+	return ConflictedBooking{
+		ID:           booking.ID,
+		CustomerName: booking.CustomerName,
+		StartTime:    booking.StartTime,
+		EndTime:      booking.EndTime,
+		Status:       booking.Status,
+	}
+}
+
+// UpdateAdminNotes godoc
+// @Summary Update internal admin notes on a booking (admin only)
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body map[string]string true "Admin notes"
+// @Success 200 {object} model.Booking
+// @Router /admin/bookings/{id}/admin-notes [patch]
+func (h *BookingHandler) UpdateAdminNotes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var req struct {
+		AdminNotes string `json:"admin_notes"`
+	}
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
+		return
+	}
+	if booking == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+
+	actorID, _ := middleware.GetUserID(c)
+	if err := h.bookingRepo.UpdateAdminNotes(uint(id), req.AdminNotes, actorID); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update admin notes")
+		return
+	}
+
+	booking, _ = h.bookingRepo.GetByID(uint(id))
+	c.JSON(http.StatusOK, bookingView(booking, "admin"))
+}
+
+// SetBookingTags godoc
+// @Summary Replace the tags on a booking (admin only)
+// @Description Tags are trimmed and de-duplicated. When the booking.tag_allowlist setting is non-empty, every tag must be on it.
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body map[string][]string true "Tags"
+// @Success 200 {object} model.Booking
+// @Router /admin/bookings/{id}/tags [put]
+func (h *BookingHandler) SetBookingTags(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var req struct {
+		Tags []string `json:"tags" binding:"required"`
+	}
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	tags := model.NormalizeBookingTags(req.Tags)
+	if len(tags) > model.MaxBookingTags {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, fmt.Sprintf("At most %d tags are allowed", model.MaxBookingTags))
+		return
+	}
+	var allowlist []string
+	if _, err := h.settingsRepo.GetValue(model.SettingsKeyBookingTagAllowlist, &allowlist); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch tag allowlist")
+		return
+	}
+	allowed := make(map[string]bool, len(allowlist))
+	for _, tag := range allowlist {
+		allowed[tag] = true
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > model.MaxBookingTagLength {
+			respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, fmt.Sprintf("Tag %q is longer than %d characters", tag, model.MaxBookingTagLength))
+			return
+		}
+		if len(allowed) > 0 && !allowed[tag] {
+			apiErr := apierror.New(apierror.CodeValidationFailed, fmt.Sprintf("Tag %q is not allowed", tag)).
+				WithDetails(gin.H{"allowed_tags": allowlist})
+			respondAPIError(c, http.StatusBadRequest, apiErr)
+			return
+		}
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
+		return
+	}
+	if booking == nil {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+
+	actorID, _ := middleware.GetUserID(c)
+	if err := h.bookingRepo.SetTags(booking.ID, tags, actorID); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update tags")
+		return
+	}
+
+	booking, _ = h.bookingRepo.GetByID(booking.ID)
+	c.JSON(http.StatusOK, bookingView(booking, "admin"))
+}
+
+// initialStatus is the status of a newly created booking: confirmed when the
+// salon auto-confirms bookings, otherwise pending until an admin approves it.
+// Both statuses block the slot, so the setting doesn't change availability.
+func (h *BookingHandler) initialStatus() (string, error) {
+	autoConfirm, err := h.settingsRepo.GetBool(model.SettingsKeyAutoConfirm, false)
+	if err != nil {
+		return "", err
+	}
+	if autoConfirm {
+		return model.BookingStatusConfirmed, nil
+	}
+	return model.BookingStatusPending, nil
+}
+
+// awardLoyaltyPoints credits the customer with the completed booking's
+// loyalty points, at the loyalty.points_rate setting per NT$ of the pre-tax
+// price. The repository credits a booking only once, so completing it again
+// after a status change awards nothing. Failures are logged rather than
+// returned since the status change is already saved.
+func (h *BookingHandler) awardLoyaltyPoints(booking *model.Booking) {
+	rate, err := h.settingsRepo.GetFloat(model.SettingsKeyLoyaltyPointsRate, model.DefaultLoyaltyPointsRate)
+	if err != nil {
+		log.Printf("⚠️  Failed to fetch loyalty points rate for booking %d: %v", booking.ID, err)
+		return
+	}
+	points := model.LoyaltyPointsFor(booking.Price, rate)
+	if points <= 0 {
+		return
+	}
+	if _, err := h.loyaltyRepo.AwardForBooking(booking, points); err != nil {
+		log.Printf("⚠️  Failed to award loyalty points for booking %d: %v", booking.ID, err)
+	}
+}
+
+// resolveServices loads the requested services and sums their duration and
+// price. Each variant ID picks the pricing tier of one of the services. The
+// returned buffer is the cleanup time of the last service.
+func (h *BookingHandler) resolveServices(serviceIDs []uint, variantIDs []uint) ([]model.BookingServiceItem, int, int, int, error) {
+	variants := make(map[uint]*model.ServiceVariant, len(variantIDs))
+	for _, variantID := range variantIDs {
+		variant, err := h.serviceRepo.GetVariant(variantID)
+		if err != nil || variant == nil {
+			return nil, 0, 0, 0, fmt.Errorf("Invalid variant ID: %d", variantID)
+		}
+		if variants[variant.ServiceID] != nil {
+			return nil, 0, 0, 0, fmt.Errorf("Only one variant per service is allowed (service ID: %d)", variant.ServiceID)
+		}
+		variants[variant.ServiceID] = variant
+	}
+
+	var services []model.BookingServiceItem
+	var totalDuration int
+	var totalPrice int
+	var buffer int
+
+	for _, serviceID := range serviceIDs {
+		service, err := h.serviceRepo.GetByID(serviceID)
+		if err != nil || service == nil {
+			return nil, 0, 0, 0, fmt.Errorf("Invalid service ID: %d", serviceID)
+		}
+
+		item := model.BookingServiceItem{
+			ID:       service.ID,
+			Name:     service.Name,
+			Price:    service.Price,
+			Duration: service.Duration,
+		}
+		if variant := variants[service.ID]; variant != nil {
+			item.Price = variant.Price(service)
+			item.Duration = variant.Duration(service)
+			item.VariantLabel = variant.Label
+			delete(variants, service.ID)
+		}
+		services = append(services, item)
+
+		totalDuration += item.Duration
+		totalPrice += item.Price
+		buffer = service.BufferMinutes
+	}
+
+	for serviceID := range variants {
+		return nil, 0, 0, 0, fmt.Errorf("Variant does not belong to a requested service (service ID: %d)", serviceID)
+	}
+
+	return services, totalDuration, totalPrice, buffer, nil
+}
+
+// durationLimits reads the self-service booking duration limits in minutes;
+// 0 means no limit
+func (h *BookingHandler) durationLimits() (int, int, error) {
+	minDuration, err := h.settingsRepo.GetFloat(model.SettingsKeyMinDurationMinutes, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxDuration, err := h.settingsRepo.GetFloat(model.SettingsKeyMaxDurationMinutes, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(minDuration), int(maxDuration), nil
+}
+
+// checkDuration rejects a customer booking whose summed service duration is
+// outside the booking.min/max_duration_minutes settings. Admins aren't limited.
+func (h *BookingHandler) checkDuration(role string, duration int) (*apierror.APIError, error) {
+	if role == "admin" {
+		return nil, nil
+	}
+	minDuration, maxDuration, err := h.durationLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	var message string
+	switch {
+	case minDuration > 0 && duration < minDuration:
+		message = fmt.Sprintf("Bookings must be at least %d minutes; the selected services take %d", minDuration, duration)
+	case maxDuration > 0 && duration > maxDuration:
+		message = fmt.Sprintf("Bookings can be at most %d minutes; the selected services take %d, please contact the salon", maxDuration, duration)
+	default:
+		return nil, nil
+	}
+	return apierror.New(apierror.CodeDurationOutOfRange, message).WithDetails(gin.H{
+		"duration":             duration,
+		"min_duration_minutes": minDuration,
+		"max_duration_minutes": maxDuration,
+	}), nil
+}
+
+// checkBlackout rejects services that are blacked out on date, with the
+// first blackout of each as details so the UI can show the reason
+func (h *BookingHandler) checkBlackout(services []model.BookingServiceItem, date time.Time) (*apierror.APIError, error) {
+	ids := make([]uint, 0, len(services))
+	for _, svc := range services {
+		ids = append(ids, svc.ID)
+	}
+	blackouts, err := h.serviceRepo.GetBlackouts(ids, date, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(blackouts) == 0 {
+		return nil, nil
+	}
+
+	var names []string
+	var details []model.ServiceBlackout
+	seen := make(map[uint]bool)
+	for _, svc := range services {
+		for _, blackout := range blackouts {
+			if blackout.ServiceID == svc.ID && !seen[svc.ID] {
+				seen[svc.ID] = true
+				names = append(names, svc.Name)
+				details = append(details, blackout)
+			}
+		}
+	}
+	message := fmt.Sprintf("Not available on %s: %s", date.Format("2006-01-02"), strings.Join(names, ", "))
+	return apierror.New(apierror.CodeServiceBlackedOut, message).
+		WithDetails(gin.H{"blackouts": details}), nil
+}
+
+// checkQualified rejects services the stylist isn't mapped to. Until the
+// salon configures any mappings, every stylist qualifies for every service
+// (see AvailabilityService.QualifiedForAll).
+func (h *BookingHandler) checkQualified(stylist *model.Stylist, services []model.BookingServiceItem) (*apierror.APIError, error) {
+	if len(stylist.Capabilities) == 0 {
+		qualifiedForAll, err := h.availability.QualifiedForAll()
+		if err != nil {
+			return nil, err
+		}
+		if qualifiedForAll {
+			return nil, nil
+		}
+	}
+
+	var ids []uint
+	var names []string
+	for _, svc := range services {
+		if !stylist.CanPerform(svc.ID) {
+			ids = append(ids, svc.ID)
+			names = append(names, svc.Name)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	message := fmt.Sprintf("%s is not qualified for: %s", stylist.Name, strings.Join(names, ", "))
+	return apierror.New(apierror.CodeStylistNotQualified, message).
+		WithDetails(gin.H{"service_ids": ids}), nil
+}
+
+// customerInfo picks the contact details for a booking, preferring the
+// values sent by the client and falling back to the user's profile
+func customerInfo(user *model.User, name, phone, email string) (string, string, string) {
+	if name == "" {
+		name = user.Name
+	}
+	if phone == "" && user.Phone != nil {
+		phone = *user.Phone
+	}
+	if email == "" {
+		email = user.Email
+	}
+	return name, phone, email
+}
+
+// normalizeCustomerPhone converts a phone number sent with a booking to
+// E.164. An empty value stays empty so customerInfo falls back to the profile.
+func normalizeCustomerPhone(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	return phone.Normalize(raw)
+}
+
+// checkBookingWindow responds with 400 and returns false when a booking
+// would start too soon (see checkLeadTime) or further ahead than the
+// booking.max_advance_days setting allows
+func (h *BookingHandler) checkBookingWindow(c *gin.Context, bookingDate time.Time, startTime string) bool {
+	if status, apiErr := h.bookingWindowError(bookingDate, startTime); apiErr != nil {
+		respondAPIError(c, status, apiErr)
+		return false
+	}
+	return true
+}
+
+// bookingWindowError is checkBookingWindow without the response: it returns
+// the status and error to respond with, or nil when the start is bookable
+func (h *BookingHandler) bookingWindowError(bookingDate time.Time, startTime string) (int, *apierror.APIError) {
+	if apiErr := h.checkLeadTime(bookingDate, startTime); apiErr != nil {
+		return http.StatusBadRequest, apiErr
+	}
+
+	now := time.Now().In(h.cfg.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	lastDate, days, err := service.LastBookableDate(h.settingsRepo, today)
+	if err != nil {
+		return http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Failed to fetch booking settings")
+	}
+	if bookingDate.Format("2006-01-02") > lastDate.Format("2006-01-02") {
+		return http.StatusBadRequest, apierror.New(apierror.CodeBookingTooFarAhead,
+			fmt.Sprintf("Bookings can be made at most %d days in advance", days)).
+			WithDetails(gin.H{"last_bookable_date": lastDate.Format("2006-01-02")})
+	}
+	return 0, nil
+}
+
+// checkLeadTime rejects bookings that start in the past or sooner than the
+// configured minimum lead time, judged in the salon's timezone. A start time
+// that doesn't parse is a validation error, not a timing one.
+func (h *BookingHandler) checkLeadTime(bookingDate time.Time, startTime string) *apierror.APIError {
+	now := time.Now().In(h.cfg.Location)
+	day := bookingDate.Format("2006-01-02")
+	if day < now.Format("2006-01-02") {
+		return apierror.New(apierror.CodeBookingTooSoon, "Booking date is in the past")
+	}
+
+	start, err := time.ParseInLocation("2006-01-02 15:04", day+" "+startTime, h.cfg.Location)
+	if err != nil {
+		return apierror.New(apierror.CodeValidationFailed, "Invalid start time format, use HH:MM")
+	}
+	if !start.After(now) {
+		return apierror.New(apierror.CodeBookingTooSoon, "Start time has already passed")
+	}
+	if start.Before(now.Add(time.Duration(h.cfg.MinLeadMinutes) * time.Minute)) {
+		return apierror.New(apierror.CodeBookingTooSoon,
+			fmt.Sprintf("Too soon to book, bookings must be made at least %d minutes in advance", h.cfg.MinLeadMinutes))
+	}
+	return nil
+}
+
+// parseDateTime parses a salon-local date and time given as
+// YYYY-MM-DDTHH:MM (a space separator is also accepted)
+func parseDateTime(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02T15:04", s)
+	if err != nil {
+		t, err = time.Parse("2006-01-02 15:04", s)
+	}
+	return t, err
+}
+
+// addMinutes returns the HH:MM time that is `minutes` after start.
+// Bookings may not run past midnight.
+func addMinutes(start string, minutes int) (string, error) {
+	t, err := time.Parse("15:04", start)
+	if err != nil || t.Format("15:04") != start {
+		return "", fmt.Errorf("Invalid start time format, use HH:MM")
+	}
+
+	end := t.Add(time.Duration(minutes) * time.Minute)
+	if end.Day() != t.Day() {
+		return "", fmt.Errorf("Booking cannot extend past midnight")
+	}
+
+	return end.Format("15:04"), nil
 }
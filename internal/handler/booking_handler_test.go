@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"linda-salon-api/config"
+	"linda-salon-api/internal/apierror"
+)
+
+func TestCheckLeadTime(t *testing.T) {
+	loc := time.UTC
+	h := &BookingHandler{cfg: &config.BookingConfig{MinLeadMinutes: 60, Location: loc}}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name      string
+		date      time.Time
+		startTime string
+		wantCode  string // empty when the start is bookable
+	}{
+		{"tomorrow", tomorrow, "10:00", ""},
+		{"date in the past", today.AddDate(0, 0, -1), "10:00", apierror.CodeBookingTooSoon},
+		{"within the lead time", today, now.Add(30 * time.Minute).Format("15:04"), apierror.CodeBookingTooSoon},
+		{"malformed start time", tomorrow, "10am", apierror.CodeValidationFailed},
+		{"out of range start time", tomorrow, "25:00", apierror.CodeValidationFailed},
+		{"empty start time", tomorrow, "", apierror.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := h.checkLeadTime(tt.date, tt.startTime)
+			if tt.wantCode == "" {
+				if apiErr != nil {
+					t.Fatalf("checkLeadTime() = %v, want nil", apiErr.Message)
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatalf("checkLeadTime() = nil, want %s", tt.wantCode)
+			}
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("checkLeadTime() code = %s, want %s", apiErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
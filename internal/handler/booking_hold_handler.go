@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+type CreateHoldRequest struct {
+	ServiceIDs []uint `json:"service_ids" binding:"required,min=1"`
+	VariantIDs []uint `json:"variant_ids"`
+	StylistID  uint   `json:"stylist_id" binding:"required"`
+	Date       string `json:"date" binding:"required"`       // YYYY-MM-DD
+	StartTime  string `json:"start_time" binding:"required"` // HH:MM
+}
+
+type ConfirmHoldRequest struct {
+	Notes         string `json:"notes"`
+	CustomerName  string `json:"customer_name"`  // 可選：覆蓋用戶姓名
+	CustomerPhone string `json:"customer_phone"` // 可選：覆蓋用戶電話
+	CustomerEmail string `json:"customer_email"` // 可選：覆蓋用戶信箱
+}
+
+// CreateHold godoc
+// @Summary Temporarily hold a slot while the customer fills in the booking form
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateHoldRequest true "Slot to hold"
+// @Success 201 {object} model.BookingHold
+// @Router /bookings/hold [post]
+func (h *BookingHandler) CreateHold(c *gin.Context) {
+	var req CreateHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil || user == nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+		return
+	}
+	if user.IsBlocked {
+		respondError(c, http.StatusForbidden, apierror.CodeAccountBlocked, "Your account is blocked from making bookings")
+		return
+	}
+
+	services, totalDuration, totalPrice, buffer, err := h.resolveServices(req.ServiceIDs, req.VariantIDs)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidService, err.Error())
+		return
+	}
+	role, _ := middleware.GetUserRole(c)
+	outOfRange, err := h.checkDuration(role, totalDuration)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+		return
+	}
+	if outOfRange != nil {
+		respondAPIError(c, http.StatusBadRequest, outOfRange)
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(req.StylistID)
+	if err != nil || stylist == nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidStylist, "Invalid stylist")
+		return
+	}
+	unqualified, err := h.checkQualified(stylist, services)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check stylist services")
+		return
+	}
+	if unqualified != nil {
+		respondAPIError(c, http.StatusBadRequest, unqualified)
+		return
+	}
+
+	bookingDate, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid date format")
+		return
+	}
+	if !h.checkBookingWindow(c, bookingDate, req.StartTime) {
+		return
+	}
+	blackedOut, err := h.checkBlackout(services, bookingDate)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check service blackouts")
+		return
+	}
+	if blackedOut != nil {
+		respondAPIError(c, http.StatusBadRequest, blackedOut)
+		return
+	}
+
+	endTime, err := addMinutes(req.StartTime, totalDuration)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+	occupiedEndTime, err := addMinutes(req.StartTime, totalDuration+buffer)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	// 每位顧客同時只能保留一個時段，先釋放舊的保留
+	released, err := h.holdRepo.DeleteByUser(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to release previous hold")
+		return
+	}
+	for _, previous := range released {
+		h.availability.Invalidate(previous.StylistID, previous.BookingDate)
+	}
+
+	available, err := h.stylistRepo.IsAvailable(req.StylistID, bookingDate, req.StartTime, occupiedEndTime, userID, nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check availability")
+		return
+	}
+	if !available {
+		respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+		return
+	}
+
+	hold := &model.BookingHold{
+		UserID:          userID,
+		StylistID:       req.StylistID,
+		Services:        services,
+		BookingDate:     bookingDate,
+		StartTime:       req.StartTime,
+		EndTime:         endTime,
+		Duration:        totalDuration,
+		BufferMinutes:   buffer,
+		OccupiedEndTime: occupiedEndTime,
+		Price:           stylist.ApplyPriceModifier(totalPrice),
+		ExpiresAt:       time.Now().Add(time.Duration(h.cfg.HoldMinutes) * time.Minute),
+	}
+
+	if err := h.holdRepo.Create(hold); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create hold")
+		return
+	}
+	h.availability.Invalidate(hold.StylistID, hold.BookingDate)
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+// ConfirmHold godoc
+// @Summary Convert a hold into a booking
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Hold ID"
+// @Param request body ConfirmHoldRequest false "Booking details"
+// @Success 201 {object} model.Booking
+// @Router /bookings/{id}/confirm [post]
+func (h *BookingHandler) ConfirmHold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid hold ID")
+		return
+	}
+
+	var req ConfirmHoldRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+			return
+		}
+	}
+	customerPhone, err := normalizeCustomerPhone(req.CustomerPhone)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidPhone, "Invalid customer phone number")
+		return
+	}
+	req.CustomerPhone = customerPhone
+
+	userID, _ := middleware.GetUserID(c)
+
+	hold, err := h.holdRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch hold")
+		return
+	}
+	if hold == nil || hold.UserID != userID {
+		respondError(c, http.StatusNotFound, apierror.CodeHoldNotFound, "Hold not found")
+		return
+	}
+	if hold.IsExpired() {
+		h.holdRepo.Delete(hold.ID)
+		respondError(c, http.StatusGone, apierror.CodeHoldExpired, "Hold has expired")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil || user == nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+		return
+	}
+	if user.IsBlocked {
+		respondError(c, http.StatusForbidden, apierror.CodeAccountBlocked, "Your account is blocked from making bookings")
+		return
+	}
+
+	available, err := h.stylistRepo.IsAvailable(hold.StylistID, hold.BookingDate, hold.StartTime, hold.OccupiedEndTime, userID, nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check availability")
+		return
+	}
+	if !available {
+		respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+		return
+	}
+
+	customerName, customerPhone, customerEmail := customerInfo(user, req.CustomerName, req.CustomerPhone, req.CustomerEmail)
+
+	status, err := h.initialStatus()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking settings")
+		return
+	}
+
+	booking := &model.Booking{
+		UserID:          userID,
+		StylistID:       hold.StylistID,
+		Services:        hold.Services,
+		BookingDate:     hold.BookingDate,
+		StartTime:       hold.StartTime,
+		EndTime:         hold.EndTime,
+		Duration:        hold.Duration,
+		BufferMinutes:   hold.BufferMinutes,
+		OccupiedEndTime: hold.OccupiedEndTime,
+		Price:           hold.Price,
+		Status:          status,
+		Notes:           req.Notes,
+		CustomerName:    customerName,
+		CustomerPhone:   customerPhone,
+		CustomerEmail:   customerEmail,
+		CreatedByID:     &userID,
+	}
+
+	taxRate, err := h.settingsRepo.GetFloat(model.SettingsKeyTaxRate, 0)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch tax rate")
+		return
+	}
+	booking.ApplyTax(taxRate)
+
+	if err := h.bookingRepo.CreateWithAvailabilityCheck(booking, hold.StartTime, hold.OccupiedEndTime); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			respondError(c, http.StatusConflict, apierror.CodeBookingSlotTaken, "Stylist is not available at this time")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create booking")
+		return
+	}
+
+	// 預約成立後釋放保留
+	h.holdRepo.Delete(hold.ID)
+	h.availability.Invalidate(hold.StylistID, hold.BookingDate)
+
+	if created, err := h.bookingRepo.GetByID(booking.ID); err == nil {
+		booking = created
+		h.notifier.NotifyNewBooking(c.Request.Context(), booking)
+		h.notifier.SendBookingConfirmation(c.Request.Context(), booking)
+	} else {
+		log.Printf("⚠️  Failed to reload booking %d after confirming hold %d: %v", booking.ID, hold.ID, err)
+	}
+
+	role, _ := middleware.GetUserRole(c)
+	c.JSON(http.StatusCreated, bookingView(booking, role))
+}
+
+// ReleaseHold godoc
+// @Summary Release a held slot without booking it
+// @Tags bookings
+// @Security BearerAuth
+// @Param id path int true "Hold ID"
+// @Success 204
+// @Router /bookings/{id}/release [post]
+func (h *BookingHandler) ReleaseHold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid hold ID")
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	hold, err := h.holdRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch hold")
+		return
+	}
+	if hold == nil || hold.UserID != userID {
+		respondError(c, http.StatusNotFound, apierror.CodeHoldNotFound, "Hold not found")
+		return
+	}
+
+	if err := h.holdRepo.Delete(hold.ID); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to release hold")
+		return
+	}
+	h.availability.Invalidate(hold.StylistID, hold.BookingDate)
+
+	c.Status(http.StatusNoContent)
+}
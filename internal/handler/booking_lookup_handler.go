@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/phone"
+)
+
+// bookingLookupView is what a guest sees when looking up a booking by
+// reference; it deliberately leaves out contact details and prices
+type bookingLookupView struct {
+	Reference string `json:"reference"`
+	Date      string `json:"date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Stylist   string `json:"stylist"`
+	Status    string `json:"status"`
+}
+
+// LookupBooking godoc
+// @Summary Look up a booking by reference and customer phone (no login)
+// @Description Both values must match the booking. Mismatches and unknown references return the same 404.
+// @Tags bookings
+// @Produce json
+// @Param reference query string true "Booking reference"
+// @Param phone query string true "Phone number the booking was made with"
+// @Success 200 {object} bookingLookupView
+// @Router /bookings/lookup [get]
+func (h *BookingHandler) LookupBooking(c *gin.Context) {
+	reference := strings.ToUpper(strings.TrimSpace(c.Query("reference")))
+	rawPhone := c.Query("phone")
+	if reference == "" || strings.TrimSpace(rawPhone) == "" {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "reference and phone are required")
+		return
+	}
+	customerPhone, err := phone.Normalize(rawPhone)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidPhone, "Invalid phone number")
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByReference(reference)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
+		return
+	}
+	// 編號不存在與電話不符回傳相同錯誤，避免被用來猜測編號
+	if booking == nil || !samePhone(booking.CustomerPhone, customerPhone) {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, bookingLookupView{
+		Reference: booking.Reference,
+		Date:      booking.BookingDate.Format("2006-01-02"),
+		StartTime: booking.StartTime,
+		EndTime:   booking.EndTime,
+		Stylist:   booking.Stylist.Name,
+		Status:    booking.Status,
+	})
+}
+
+// samePhone compares a stored phone with an E.164 one. Stored values that
+// predate normalization are normalized before comparing.
+func samePhone(stored, normalized string) bool {
+	if stored == normalized {
+		return true
+	}
+	n, err := phone.Normalize(stored)
+	return err == nil && n == normalized
+}
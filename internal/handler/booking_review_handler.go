@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
+)
+
+type CreateReviewRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+// CreateReview godoc
+// @Summary Review a completed booking (owner only, once per booking)
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body CreateReviewRequest true "Rating and comment"
+// @Success 201 {object} model.Review
+// @Router /bookings/{id}/review [post]
+func (h *BookingHandler) CreateReview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var req CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	booking, err := h.bookingRepo.GetByID(uint(id))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch booking")
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	if booking == nil || booking.UserID != userID {
+		respondError(c, http.StatusNotFound, apierror.CodeBookingNotFound, "Booking not found")
+		return
+	}
+	if booking.Status != model.BookingStatusCompleted {
+		respondError(c, http.StatusBadRequest, apierror.CodeBookingNotModifiable, "Only completed bookings can be reviewed")
+		return
+	}
+
+	reviewed, err := h.reviewRepo.ReviewedBookingIDs([]uint{booking.ID})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check reviews")
+		return
+	}
+	if reviewed[booking.ID] {
+		respondError(c, http.StatusConflict, apierror.CodeConflict, "Booking has already been reviewed")
+		return
+	}
+
+	review := &model.Review{
+		BookingID: booking.ID,
+		UserID:    userID,
+		StylistID: booking.StylistID,
+		Rating:    req.Rating,
+		Comment:   strings.TrimSpace(req.Comment),
+	}
+	if err := h.reviewRepo.Create(review); err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create review")
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"linda-salon-api/internal/model"
+)
+
+// customerBookingView is the booking as returned to customers. Staff-only
+// fields are shadowed by empty omitempty fields with the same JSON name, so
+// they are dropped from the output without copying every booking field.
+type customerBookingView struct {
+	*model.Booking
+
+	AdminNotes  string   `json:"admin_notes,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	CreatedByID *uint    `json:"created_by_id,omitempty"`
+	UpdatedByID *uint    `json:"updated_by_id,omitempty"`
+
+	StatusChangedByID *uint `json:"status_changed_by_id,omitempty"`
+
+	// User replaces the preloaded customer with a copy that has the
+	// staff-only ProfileNote cleared
+	User *model.User `json:"user,omitempty"`
+
+	// CanReview is true when the booking is the caller's, completed and not
+	// yet reviewed. Only set by ownerBookingViews.
+	CanReview bool `json:"can_review"`
+}
+
+// adminBookingView is the full booking as returned to staff, including the
+// customer's ProfileNote on the preloaded user.
+type adminBookingView struct {
+	*model.Booking
+}
+
+// bookingView serializes a booking for the given caller role.
+func bookingView(booking *model.Booking, role string) interface{} {
+	if booking == nil {
+		return nil
+	}
+	if role == "admin" {
+		return adminBookingView{Booking: booking}
+	}
+	view := customerBookingView{Booking: booking}
+	if booking.User.ID != 0 {
+		user := booking.User
+		user.ProfileNote = ""
+		view.User = &user
+	}
+	return view
+}
+
+// bookingViews serializes a list of bookings for the given caller role.
+func bookingViews(bookings []model.Booking, role string) []interface{} {
+	views := make([]interface{}, 0, len(bookings))
+	for i := range bookings {
+		views = append(views, bookingView(&bookings[i], role))
+	}
+	return views
+}
+
+// ownerBookingViews serializes bookings like bookingViews and, for
+// customers, fills in CanReview from the reviews table
+func (h *BookingHandler) ownerBookingViews(bookings []model.Booking, role string, userID uint) ([]interface{}, error) {
+	views := bookingViews(bookings, role)
+	if role == "admin" {
+		return views, nil
+	}
+
+	ids := make([]uint, 0, len(bookings))
+	for _, booking := range bookings {
+		if booking.UserID == userID && booking.Status == model.BookingStatusCompleted {
+			ids = append(ids, booking.ID)
+		}
+	}
+	reviewed, err := h.reviewRepo.ReviewedBookingIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, view := range views {
+		if v, ok := view.(customerBookingView); ok {
+			v.CanReview = bookings[i].UserID == userID &&
+				bookings[i].Status == model.BookingStatusCompleted && !reviewed[bookings[i].ID]
+			views[i] = v
+		}
+	}
+	return views, nil
+}
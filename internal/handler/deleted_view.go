@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"linda-salon-api/internal/model"
+)
+
+// Models hide DeletedAt from JSON. When admins list soft-deleted records,
+// these views expose it so deleted rows can be told apart and restored.
+
+type deletedServiceView struct {
+	*model.Service
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type deletedStylistView struct {
+	*model.Stylist
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// deletedAt returns the soft-delete time, or nil if the record isn't deleted
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Time
+}
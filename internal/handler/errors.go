@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+)
+
+// respondError writes an error response with a machine-readable code
+func respondError(c *gin.Context, status int, code, msg string) {
+	apierror.Abort(c, status, apierror.New(code, msg))
+}
+
+// respondAPIError writes a prepared APIError, e.g. one carrying details
+func respondAPIError(c *gin.Context, status int, err *apierror.APIError) {
+	apierror.Abort(c, status, err)
+}
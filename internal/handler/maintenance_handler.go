@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// minPurgeRetentionDays 軟刪除資料至少保留的天數
+const minPurgeRetentionDays = 30
+
+type MaintenanceHandler struct {
+	userRepo    *repository.UserRepository
+	bookingRepo *repository.BookingRepository
+	serviceRepo *repository.ServiceRepository
+	auditRepo   *repository.AuditLogRepository
+}
+
+func NewMaintenanceHandler(
+	userRepo *repository.UserRepository,
+	bookingRepo *repository.BookingRepository,
+	serviceRepo *repository.ServiceRepository,
+	auditRepo *repository.AuditLogRepository,
+) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		userRepo:    userRepo,
+		bookingRepo: bookingRepo,
+		serviceRepo: serviceRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+// PurgeDeleted godoc
+// @Summary Permanently delete old soft-deleted records (admin only)
+// @Tags maintenance
+// @Security BearerAuth
+// @Produce json
+// @Param older_than_days query int true "Only purge rows deleted at least this many days ago (min 30)"
+// @Param confirm query bool true "Must be true to run the purge"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/maintenance/purge [post]
+func (h *MaintenanceHandler) PurgeDeleted(c *gin.Context) {
+	days, err := strconv.Atoi(c.Query("older_than_days"))
+	if err != nil || days < minPurgeRetentionDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "older_than_days must be an integer of at least 30"})
+		return
+	}
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This permanently deletes data, pass confirm=true to proceed"})
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	// Bookings first so users they referenced can be purged in the same run
+	bookings, err := h.bookingRepo.PurgeDeleted(cutoff)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge bookings"})
+		return
+	}
+	users, err := h.userRepo.PurgeDeleted(cutoff)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge users"})
+		return
+	}
+	services, err := h.serviceRepo.PurgeDeleted(cutoff)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge services"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, model.AuditActionPurge, model.AuditEntityMaintenance, 0, map[string]interface{}{
+		"older_than_days": days,
+		"cutoff":          cutoff.Format(time.RFC3339),
+		"bookings":        bookings,
+		"users":           users,
+		"services":        services,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"cutoff": cutoff.Format(time.RFC3339),
+		"purged": gin.H{
+			"bookings": bookings,
+			"users":    users,
+			"services": services,
+		},
+	})
+}
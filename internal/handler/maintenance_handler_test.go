@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/testutil"
+)
+
+func TestPurgeDeletedRecordsAudit(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	h := NewMaintenanceHandler(repository.NewUserRepository(db), repository.NewBookingRepository(db),
+		repository.NewServiceRepository(db), repository.NewAuditLogRepository(db))
+
+	for _, table := range []string{"bookings", "users", "services"} {
+		mock.ExpectQuery(`SELECT "id" FROM "` + table + `"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+	expectAuditInsert(mock, 1, model.AuditActionPurge, model.AuditEntityMaintenance, 0)
+
+	c, w := newAdminContext(http.MethodPost, "/admin/maintenance/purge?older_than_days=30&confirm=true", "", 1)
+	h.PurgeDeleted(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
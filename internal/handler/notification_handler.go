@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"net/mail"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/service"
+)
+
+type NotificationHandler struct {
+	notifier *service.NotificationService
+}
+
+func NewNotificationHandler(notifier *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notifier: notifier,
+	}
+}
+
+type TestNotificationRequest struct {
+	Channel   string `json:"channel" binding:"required"`   // email, sms
+	Recipient string `json:"recipient" binding:"required"` // email address or phone number
+}
+
+// SendTestNotification godoc
+// @Summary Send a test notification to verify delivery settings (admin only)
+// @Tags notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body TestNotificationRequest true "Channel and recipient"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/notifications/test [post]
+func (h *NotificationHandler) SendTestNotification(c *gin.Context) {
+	var req TestNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch req.Channel {
+	case service.ChannelEmail:
+		if _, err := mail.ParseAddress(req.Recipient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+			return
+		}
+	case service.ChannelSMS:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel, use email or sms"})
+		return
+	}
+
+	// 回傳發送結果與錯誤細節，方便排查設定問題
+	if err := h.notifier.SendTest(req.Channel, req.Recipient); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"channel": req.Channel,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"channel": req.Channel,
+	})
+}
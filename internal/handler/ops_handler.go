@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/database"
+	"linda-salon-api/internal/middleware"
+)
+
+// readinessTimeout bounds the database checks behind /health/ready so a hung
+// connection fails the probe instead of stalling it
+const readinessTimeout = 2 * time.Second
+
+type OpsHandler struct {
+	db        *database.Database
+	sqlDB     *sql.DB
+	stats     *middleware.RequestStats
+	startedAt time.Time
+}
+
+func NewOpsHandler(db *database.Database, sqlDB *sql.DB, stats *middleware.RequestStats) *OpsHandler {
+	return &OpsHandler{
+		db:        db,
+		sqlDB:     sqlDB,
+		stats:     stats,
+		startedAt: time.Now(),
+	}
+}
+
+// GetStats godoc
+// @Summary Get in-memory process, database pool and request stats (admin only)
+// @Tags ops
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/ops/stats [get]
+func (h *OpsHandler) GetStats(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	dbStats := h.sqlDB.Stats()
+
+	c.JSON(http.StatusOK, gin.H{
+		"process": gin.H{
+			"uptime_seconds": int64(time.Since(h.startedAt).Seconds()),
+			"started_at":     h.startedAt.Format(time.RFC3339),
+			"goroutines":     runtime.NumGoroutine(),
+			"go_version":     runtime.Version(),
+		},
+		"memory": gin.H{
+			"alloc_bytes":       mem.Alloc,
+			"total_alloc_bytes": mem.TotalAlloc,
+			"sys_bytes":         mem.Sys,
+			"heap_objects":      mem.HeapObjects,
+			"num_gc":            mem.NumGC,
+		},
+		"database": gin.H{
+			"max_open_connections": dbStats.MaxOpenConnections,
+			"open_connections":     dbStats.OpenConnections,
+			"in_use":               dbStats.InUse,
+			"idle":                 dbStats.Idle,
+			"wait_count":           dbStats.WaitCount,
+			"wait_duration_ms":     dbStats.WaitDuration.Milliseconds(),
+		},
+		"requests": h.stats.Snapshot(),
+	})
+}
+
+// Ready godoc
+// @Summary Readiness probe: checks the database connection and pending migrations
+// @Description Unlike /health (liveness), returns 503 when the database can't be
+// @Description reached or custom migrations are still pending, so load balancers
+// @Description stop routing to the instance.
+// @Tags ops
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /health/ready [get]
+func (h *OpsHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	ready := true
+	dbStatus := gin.H{"status": "ok"}
+	migrationStatus := gin.H{"status": "ok"}
+
+	start := time.Now()
+	if err := h.sqlDB.PingContext(ctx); err != nil {
+		ready = false
+		dbStatus["status"] = "error"
+		dbStatus["error"] = err.Error()
+		migrationStatus["status"] = "unknown"
+	} else {
+		dbStatus["latency_ms"] = time.Since(start).Milliseconds()
+
+		pending, err := h.db.PendingMigrations(ctx)
+		switch {
+		case err != nil:
+			ready = false
+			migrationStatus["status"] = "error"
+			migrationStatus["error"] = err.Error()
+		case len(pending) > 0:
+			ready = false
+			migrationStatus["status"] = "pending"
+			migrationStatus["pending"] = pending
+		}
+	}
+
+	status := http.StatusOK
+	overall := "ok"
+	if !ready {
+		status = http.StatusServiceUnavailable
+		overall = "unavailable"
+	}
+	c.JSON(status, gin.H{
+		"status":     overall,
+		"time":       time.Now().Format(time.RFC3339),
+		"database":   dbStatus,
+		"migrations": migrationStatus,
+	})
+}
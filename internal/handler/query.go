@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryInt reads an integer query parameter. def applies only when the
+// parameter is absent or empty; anything else that isn't a number is an
+// error rather than silently becoming 0.
+func queryInt(c *gin.Context, name string, def int) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", name, raw)
+	}
+	return n, nil
+}
+
+// queryLimit reads the limit parameter, which must be positive. Values
+// above maxLimit are capped; maxLimit <= 0 means no cap.
+func queryLimit(c *gin.Context, defaultLimit, maxLimit int) (int, error) {
+	limit, err := queryInt(c, "limit", defaultLimit)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, nil
+}
+
+// queryPagination reads limit (see queryLimit) and offset, which must not
+// be negative
+func queryPagination(c *gin.Context, defaultLimit, maxLimit int) (int, int, error) {
+	limit, err := queryLimit(c, defaultLimit, maxLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset must not be negative")
+	}
+	return limit, offset, nil
+}
+
+// queryUintList reads a list of IDs given as repeated parameters
+// (?ids=1&ids=2), comma-separated (?ids=1,2) or both
+func queryUintList(c *gin.Context, name string) ([]uint, error) {
+	var ids []uint
+	for _, raw := range c.QueryArray(name) {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a list of IDs, got %q", name, part)
+			}
+			ids = append(ids, uint(n))
+		}
+	}
+	return ids, nil
+}
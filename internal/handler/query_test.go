@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newQueryContext returns a gin context for a GET request with the given
+// raw query string
+func newQueryContext(rawQuery string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+	return c, w
+}
+
+func TestQueryPagination(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"", 20, 0, false},
+		{"limit=&offset=", 20, 0, false},
+		{"limit=50&offset=100", 50, 100, false},
+		{"limit=500", 100, 0, false}, // capped at maxLimit
+		{"limit=abc", 0, 0, true},
+		{"limit=10.5", 0, 0, true},
+		{"limit=0", 0, 0, true},
+		{"limit=-5", 0, 0, true},
+		{"limit=1e3", 0, 0, true},
+		{"offset=abc", 0, 0, true},
+		{"offset=-1", 0, 0, true},
+		{"limit=%20", 0, 0, true},
+		{"limit=99999999999999999999", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := newQueryContext(tt.query)
+			limit, offset, err := queryPagination(c, 20, 100)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("queryPagination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if limit != tt.wantLimit || offset != tt.wantOffset {
+				t.Errorf("queryPagination() = %d, %d, want %d, %d", limit, offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestQueryUintList(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []uint
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"ids=1&ids=2", []uint{1, 2}, false},
+		{"ids=1,2,,3", []uint{1, 2, 3}, false},
+		{"ids=1&ids=2,3", []uint{1, 2, 3}, false},
+		{"ids=abc", nil, true},
+		{"ids=1,-2", nil, true},
+		{"ids=1.5", nil, true},
+		{"ids=99999999999", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := newQueryContext(tt.query)
+			got, err := queryUintList(c, "ids")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("queryUintList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryUintList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Malformed pagination is rejected before the database is queried
+func TestListUsersRejectsMalformedPagination(t *testing.T) {
+	for _, query := range []string{"limit=abc", "offset=xyz", "limit=0", "offset=-10"} {
+		t.Run(query, func(t *testing.T) {
+			c, w := newQueryContext(query)
+			(&UserHandler{}).ListUsers(c)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
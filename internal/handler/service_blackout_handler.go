@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/model"
+)
+
+// maxBlackoutDays caps how long one blackout can run
+const maxBlackoutDays = 366
+
+type ServiceBlackoutRequest struct {
+	StartDate string `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate   string `json:"end_date" binding:"required"`   // YYYY-MM-DD, inclusive
+	Reason    string `json:"reason" binding:"max=200"`
+}
+
+// ListActiveBlackouts godoc
+// @Summary List current and upcoming service blackouts
+// @Description Blackouts of active services that haven't ended yet, so the booking UI can explain why a service can't be booked on those dates.
+// @Tags services
+// @Produce json
+// @Success 200 {array} model.ServiceBlackout
+// @Router /services/blackouts [get]
+func (h *ServiceHandler) ListActiveBlackouts(c *gin.Context) {
+	blackouts, err := h.serviceRepo.GetBlackoutsFrom(h.availability.Today())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blackouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, blackouts)
+}
+
+// ListBlackouts godoc
+// @Summary List a service's blackout dates (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param include_past query bool false "Include blackouts that have already ended"
+// @Success 200 {array} model.ServiceBlackout
+// @Router /admin/services/{id}/blackouts [get]
+func (h *ServiceHandler) ListBlackouts(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	from := h.availability.Today()
+	if c.Query("include_past") == "true" {
+		from = time.Time{}
+	}
+	blackouts, err := h.serviceRepo.GetBlackouts([]uint{service.ID}, from, time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blackouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, blackouts)
+}
+
+// CreateBlackout godoc
+// @Summary Stop a service from being booked on a range of dates (admin only)
+// @Description Existing bookings in the range are kept; only new bookings, holds and reschedules are rejected.
+// @Tags services
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param request body ServiceBlackoutRequest true "Blackout details"
+// @Success 201 {object} model.ServiceBlackout
+// @Router /admin/services/{id}/blackouts [post]
+func (h *ServiceHandler) CreateBlackout(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	var req ServiceBlackoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	blackout := &model.ServiceBlackout{ServiceID: service.ID}
+	if !applyBlackoutRequest(c, blackout, &req) {
+		return
+	}
+
+	if err := h.serviceRepo.CreateBlackout(blackout); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create blackout"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, blackout)
+}
+
+// UpdateBlackout godoc
+// @Summary Update a service blackout (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param blackoutId path int true "Blackout ID"
+// @Param request body ServiceBlackoutRequest true "Blackout details"
+// @Success 200 {object} model.ServiceBlackout
+// @Router /admin/services/{id}/blackouts/{blackoutId} [put]
+func (h *ServiceHandler) UpdateBlackout(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	blackout, ok := h.loadBlackout(c, service)
+	if !ok {
+		return
+	}
+
+	var req ServiceBlackoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !applyBlackoutRequest(c, blackout, &req) {
+		return
+	}
+
+	if err := h.serviceRepo.UpdateBlackout(blackout); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update blackout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, blackout)
+}
+
+// DeleteBlackout godoc
+// @Summary Delete a service blackout (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Param id path int true "Service ID"
+// @Param blackoutId path int true "Blackout ID"
+// @Success 204
+// @Router /admin/services/{id}/blackouts/{blackoutId} [delete]
+func (h *ServiceHandler) DeleteBlackout(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	blackout, ok := h.loadBlackout(c, service)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceRepo.DeleteBlackout(blackout.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blackout"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadBlackout fetches the :blackoutId blackout, which must belong to service
+func (h *ServiceHandler) loadBlackout(c *gin.Context, service *model.Service) (*model.ServiceBlackout, bool) {
+	id, err := strconv.ParseUint(c.Param("blackoutId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blackout ID"})
+		return nil, false
+	}
+
+	blackout, err := h.serviceRepo.GetBlackout(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blackout"})
+		return nil, false
+	}
+	if blackout == nil || blackout.ServiceID != service.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blackout not found"})
+		return nil, false
+	}
+	return blackout, true
+}
+
+// applyBlackoutRequest copies req onto blackout, rejecting malformed dates,
+// reversed ranges and ranges longer than maxBlackoutDays
+func applyBlackoutRequest(c *gin.Context, blackout *model.ServiceBlackout, req *ServiceBlackoutRequest) bool {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use YYYY-MM-DD"})
+		return false
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format, use YYYY-MM-DD"})
+		return false
+	}
+
+	blackout.StartDate = startDate
+	blackout.EndDate = endDate
+	blackout.Reason = strings.TrimSpace(req.Reason)
+
+	if err := blackout.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if int(endDate.Sub(startDate).Hours()/24)+1 > maxBlackoutDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A blackout can't be longer than 366 days"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+type CreateCategoryRequest struct {
+	Name         string `json:"name" binding:"required,max=50"`
+	Slug         string `json:"slug" binding:"required,max=50"`
+	DisplayOrder int    `json:"display_order"`
+	IsActive     *bool  `json:"is_active"`
+}
+
+type UpdateCategoryRequest struct {
+	Name         string `json:"name" binding:"max=50"`
+	Slug         string `json:"slug" binding:"max=50"`
+	DisplayOrder *int   `json:"display_order"`
+	IsActive     *bool  `json:"is_active"`
+}
+
+// ListCategories godoc
+// @Summary List service categories
+// @Description The public list only has active categories; the admin list has all of them.
+// @Tags categories
+// @Produce json
+// @Success 200 {array} model.Category
+// @Router /categories [get]
+func (h *ServiceHandler) ListCategories(c *gin.Context) {
+	// 只有管理員路由 (/admin/categories) 會帶有角色
+	role, _ := middleware.GetUserRole(c)
+
+	categories, err := h.serviceRepo.ListCategories(role != "admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory godoc
+// @Summary Create a service category (admin only)
+// @Tags categories
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateCategoryRequest true "Category details"
+// @Success 201 {object} model.Category
+// @Router /admin/categories [post]
+func (h *ServiceHandler) CreateCategory(c *gin.Context) {
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := &model.Category{
+		Name:         strings.TrimSpace(req.Name),
+		Slug:         strings.TrimSpace(req.Slug),
+		DisplayOrder: req.DisplayOrder,
+		IsActive:     req.IsActive == nil || *req.IsActive,
+	}
+	if !h.checkCategory(c, category) {
+		return
+	}
+
+	if err := h.serviceRepo.CreateCategory(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+	recordAudit(c, h.auditRepo, model.AuditActionCreate, model.AuditEntityCategory, category.ID, auditSnapshot(category))
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a service category (admin only)
+// @Description Changing the slug also updates the category string on its services.
+// @Tags categories
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param request body UpdateCategoryRequest true "Category details"
+// @Success 200 {object} model.Category
+// @Router /admin/categories/{id} [put]
+func (h *ServiceHandler) UpdateCategory(c *gin.Context) {
+	category, ok := h.loadCategory(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := *category
+	if name := strings.TrimSpace(req.Name); name != "" {
+		category.Name = name
+	}
+	if slug := strings.TrimSpace(req.Slug); slug != "" {
+		category.Slug = slug
+	}
+	if req.DisplayOrder != nil {
+		category.DisplayOrder = *req.DisplayOrder
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+	if !h.checkCategory(c, category) {
+		return
+	}
+
+	if err := h.serviceRepo.UpdateCategory(category, before.Slug); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+	if changes := auditDiff(before, category); len(changes) > 0 {
+		recordAudit(c, h.auditRepo, model.AuditActionUpdate, model.AuditEntityCategory, category.ID, changes)
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a service category (admin only)
+// @Description Categories still used by a service (deleted ones included) can't be deleted; deactivate them instead.
+// @Tags categories
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Success 204
+// @Failure 409 {object} map[string]string
+// @Router /admin/categories/{id} [delete]
+func (h *ServiceHandler) DeleteCategory(c *gin.Context) {
+	category, ok := h.loadCategory(c)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceRepo.DeleteCategory(category.ID); err != nil {
+		if errors.Is(err, repository.ErrCategoryInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Category is used by services, deactivate it instead"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	recordAudit(c, h.auditRepo, model.AuditActionDelete, model.AuditEntityCategory, category.ID, nil)
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadCategory fetches the category named by the :id parameter, writing the
+// error response when it can't
+func (h *ServiceHandler) loadCategory(c *gin.Context) (*model.Category, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return nil, false
+	}
+
+	category, err := h.serviceRepo.GetCategory(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return nil, false
+	}
+	if category == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return nil, false
+	}
+	return category, true
+}
+
+// checkCategory rejects a malformed slug or one another category already uses
+func (h *ServiceHandler) checkCategory(c *gin.Context, category *model.Category) bool {
+	if category.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return false
+	}
+	if !model.ValidCategorySlug(category.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be lowercase letters and digits, separated by hyphens"})
+		return false
+	}
+
+	existing, err := h.serviceRepo.GetCategoryBySlug(category.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return false
+	}
+	if existing != nil && existing.ID != category.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this slug already exists"})
+		return false
+	}
+	return true
+}
+
+// resolveServiceCategory finds the category a service is assigned to, by
+// category_id or, for older clients, by the category slug. It writes a 400
+// when neither names an existing category.
+func (h *ServiceHandler) resolveServiceCategory(c *gin.Context, categoryID uint, slug string) (*model.Category, bool) {
+	var category *model.Category
+	var err error
+	switch {
+	case categoryID != 0:
+		category, err = h.serviceRepo.GetCategory(categoryID)
+	case slug != "":
+		category, err = h.serviceRepo.GetCategoryBySlug(slug)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category_id is required"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return nil, false
+	}
+	if category == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+		return nil, false
+	}
+	return category, true
+}
@@ -3,62 +3,130 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/middleware"
 	"linda-salon-api/internal/model"
 	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
 )
 
+// maxAvailabilityDays 批次查詢可用日期的最大天數
+const maxAvailabilityDays = 31
+
+// maxServicePageSize 服務列表每頁上限
+const maxServicePageSize = 100
+
 type ServiceHandler struct {
-	serviceRepo *repository.ServiceRepository
+	serviceRepo  *repository.ServiceRepository
+	availability *service.AvailabilityService
+	auditRepo    *repository.AuditLogRepository
 }
 
-func NewServiceHandler(serviceRepo *repository.ServiceRepository) *ServiceHandler {
-	return &ServiceHandler{serviceRepo: serviceRepo}
+func NewServiceHandler(serviceRepo *repository.ServiceRepository, availability *service.AvailabilityService, auditRepo *repository.AuditLogRepository) *ServiceHandler {
+	return &ServiceHandler{
+		serviceRepo:  serviceRepo,
+		availability: availability,
+		auditRepo:    auditRepo,
+	}
 }
 
 type CreateServiceRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Category    string `json:"category" binding:"required"`
-	Price       int    `json:"price" binding:"required,min=0"`
-	Duration    int    `json:"duration" binding:"required,min=1"`
-	ImageURL    string `json:"image_url"`
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	CategoryID    uint   `json:"category_id"`
+	Category      string `json:"category"` // category slug, for clients that don't send category_id
+	Price         int    `json:"price" binding:"required,min=0"`
+	Duration      int    `json:"duration" binding:"required,min=1"`
+	BufferMinutes int    `json:"buffer_minutes" binding:"min=0"`
+	ImageURL      string `json:"image_url"`
 }
 
 type UpdateServiceRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Category    string `json:"category"`
-	Price       int    `json:"price" binding:"omitempty,min=0"`
-	Duration    int    `json:"duration" binding:"omitempty,min=1"`
-	ImageURL    string `json:"image_url"`
-	IsActive    *bool  `json:"is_active"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	CategoryID    uint   `json:"category_id"`
+	Category      string `json:"category"` // category slug, for clients that don't send category_id
+	Price         int    `json:"price" binding:"omitempty,min=0"`
+	Duration      int    `json:"duration" binding:"omitempty,min=1"`
+	BufferMinutes *int   `json:"buffer_minutes" binding:"omitempty,min=0"`
+	ImageURL      string `json:"image_url"`
+	IsActive      *bool  `json:"is_active"`
 }
 
 // ListServices godoc
 // @Summary List all services
 // @Tags services
 // @Produce json
-// @Param category query string false "Filter by category"
+// @Param category query string false "Filter by category ID or slug"
 // @Param active_only query bool false "Show only active services"
-// @Success 200 {array} model.Service
+// @Param search query string false "Case-insensitive match on name or description"
+// @Param sort query string false "price_asc, price_desc, name or duration"
+// @Param limit query int false "Limit" default(50)
+// @Param offset query int false "Offset" default(0)
+// @Param include_deleted query bool false "Also list soft-deleted services (admin only)"
+// @Success 200 {object} map[string]interface{}
 // @Router /services [get]
 func (h *ServiceHandler) ListServices(c *gin.Context) {
-	category := c.Query("category")
-	activeOnly := c.DefaultQuery("active_only", "true") == "true"
+	limit, offset, err := queryPagination(c, 50, maxServicePageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sort := c.Query("sort")
+	if !repository.ValidServiceSort(sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use price_asc, price_desc, name or duration"})
+		return
+	}
+
+	// 只有管理員路由 (/admin/services) 會帶有角色，公開列表忽略 include_deleted
+	role, _ := middleware.GetUserRole(c)
+	includeDeleted := role == "admin" && c.Query("include_deleted") == "true"
+
+	opts := repository.ServiceListOptions{
+		ActiveOnly:     c.DefaultQuery("active_only", "true") == "true",
+		Search:         strings.TrimSpace(c.Query("search")),
+		Sort:           sort,
+		Limit:          limit,
+		Offset:         offset,
+		IncludeDeleted: includeDeleted,
+	}
+	if category := c.Query("category"); category != "" {
+		if id, err := strconv.ParseUint(category, 10, 32); err == nil {
+			opts.CategoryID = uint(id)
+		} else {
+			opts.Category = category
+		}
+	}
 
-	services, err := h.serviceRepo.List(category, activeOnly)
+	services, total, err := h.serviceRepo.List(opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
 		return
 	}
 
-	c.JSON(http.StatusOK, services)
+	var items interface{} = services
+	if includeDeleted {
+		views := make([]deletedServiceView, 0, len(services))
+		for i := range services {
+			views = append(views, deletedServiceView{Service: &services[i], DeletedAt: deletedAt(services[i].DeletedAt)})
+		}
+		items = views
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"services": items,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
 }
 
 // GetService godoc
-// @Summary Get service by ID
+// @Summary Get service by ID, including its variants / pricing tiers
 // @Tags services
 // @Produce json
 // @Param id path int true "Service ID"
@@ -81,9 +149,164 @@ func (h *ServiceHandler) GetService(c *gin.Context) {
 		return
 	}
 
+	service.Variants, err = h.serviceRepo.GetVariants(service.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch variants"})
+		return
+	}
+
 	c.JSON(http.StatusOK, service)
 }
 
+// GetAvailability godoc
+// @Summary Get per-date availability for a service across qualified stylists
+// @Tags services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param start query string false "Start date (YYYY-MM-DD), defaults to today"
+// @Param days query int false "Number of days (max 31, cut off after booking.max_advance_days)" default(14)
+// @Success 200 {array} service.DateAvailability
+// @Router /services/{id}/availability [get]
+func (h *ServiceHandler) GetAvailability(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	start := h.availability.Today()
+	if startStr := c.Query("start"); startStr != "" {
+		start, err = time.Parse("2006-01-02", startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	days, err := queryInt(c, "days", 14)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+		return
+	}
+	if days > maxAvailabilityDays {
+		days = maxAvailabilityDays
+	}
+
+	// 行事曆只顯示到可預約範圍的最後一天
+	lastDate, ok := checkBookableDate(c, h.availability, start)
+	if !ok {
+		return
+	}
+	if remaining := int(lastDate.Sub(start).Hours()/24) + 1; days > remaining {
+		days = remaining
+	}
+
+	svc, err := h.serviceRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+		return
+	}
+	if svc == nil || !svc.IsActive {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	stylistIDs, err := h.availability.QualifiedStylistIDs(svc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylists"})
+		return
+	}
+
+	dates, err := h.availability.ServiceAvailability(stylistIDs, svc.Duration+svc.BufferMinutes, start, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute availability"})
+		return
+	}
+
+	// 服務暫停的日期即使設計師有空也不能預約
+	blackouts, err := h.serviceRepo.GetBlackouts([]uint{svc.ID}, start, start.AddDate(0, 0, days-1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blackouts"})
+		return
+	}
+	for i := range dates {
+		date, _ := time.Parse("2006-01-02", dates[i].Date)
+		for _, blackout := range blackouts {
+			if blackout.Covers(date) {
+				dates[i] = service.DateAvailability{Date: dates[i].Date, BlackedOut: true}
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id":         svc.ID,
+		"duration":           svc.Duration,
+		"dates":              dates,
+		"last_bookable_date": lastDate.Format("2006-01-02"),
+	})
+}
+
+// GetServicesAvailability godoc
+// @Summary Get which active services can still be booked on a date (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Produce json
+// @Param date query string false "Date (YYYY-MM-DD), defaults to today"
+// @Success 200 {array} service.ServiceDayAvailability
+// @Router /admin/services/availability [get]
+func (h *ServiceHandler) GetServicesAvailability(c *gin.Context) {
+	date := h.availability.Today()
+	if dateStr := c.Query("date"); dateStr != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	services, _, err := h.serviceRepo.List(repository.ServiceListOptions{ActiveOnly: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+
+	availability, err := h.availability.ServicesAvailability(services, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute availability"})
+		return
+	}
+
+	blackouts, err := h.serviceRepo.GetBlackouts(nil, date, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blackouts"})
+		return
+	}
+	blackedOut := make(map[uint]bool, len(blackouts))
+	for _, blackout := range blackouts {
+		blackedOut[blackout.ServiceID] = true
+	}
+	for i := range availability {
+		if blackedOut[availability[i].ServiceID] {
+			availability[i].Available = false
+			availability[i].EarliestTime = ""
+			availability[i].BlackedOut = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":     date.Format("2006-01-02"),
+		"services": availability,
+	})
+}
+
 // CreateService godoc
 // @Summary Create a new service (admin only)
 // @Tags services
@@ -95,25 +318,33 @@ func (h *ServiceHandler) GetService(c *gin.Context) {
 // @Router /services [post]
 func (h *ServiceHandler) CreateService(c *gin.Context) {
 	var req CreateServiceRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	category, ok := h.resolveServiceCategory(c, req.CategoryID, req.Category)
+	if !ok {
+		return
+	}
+
 	service := &model.Service{
-		Name:        req.Name,
-		Description: req.Description,
-		Category:    req.Category,
-		Price:       req.Price,
-		Duration:    req.Duration,
-		ImageURL:    req.ImageURL,
-		IsActive:    true,
+		Name:          req.Name,
+		Description:   req.Description,
+		Category:      category.Slug,
+		CategoryID:    &category.ID,
+		Price:         req.Price,
+		Duration:      req.Duration,
+		BufferMinutes: req.BufferMinutes,
+		ImageURL:      req.ImageURL,
+		IsActive:      true,
 	}
 
 	if err := h.serviceRepo.Create(service); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service"})
 		return
 	}
+	recordAudit(c, h.auditRepo, model.AuditActionCreate, model.AuditEntityService, service.ID, auditSnapshot(service))
 
 	c.JSON(http.StatusCreated, service)
 }
@@ -146,11 +377,13 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	}
 
 	var req UpdateServiceRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	before := *service
+
 	// Update fields
 	if req.Name != "" {
 		service.Name = req.Name
@@ -158,8 +391,13 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	if req.Description != "" {
 		service.Description = req.Description
 	}
-	if req.Category != "" {
-		service.Category = req.Category
+	if req.CategoryID != 0 || req.Category != "" {
+		category, ok := h.resolveServiceCategory(c, req.CategoryID, req.Category)
+		if !ok {
+			return
+		}
+		service.Category = category.Slug
+		service.CategoryID = &category.ID
 	}
 	if req.Price > 0 {
 		service.Price = req.Price
@@ -167,6 +405,9 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	if req.Duration > 0 {
 		service.Duration = req.Duration
 	}
+	if req.BufferMinutes != nil {
+		service.BufferMinutes = *req.BufferMinutes
+	}
 	if req.ImageURL != "" {
 		service.ImageURL = req.ImageURL
 	}
@@ -178,6 +419,44 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service"})
 		return
 	}
+	if changes := auditDiff(before, service); len(changes) > 0 {
+		recordAudit(c, h.auditRepo, model.AuditActionUpdate, model.AuditEntityService, service.ID, changes)
+	}
+
+	c.JSON(http.StatusOK, service)
+}
+
+// RestoreService godoc
+// @Summary Restore a soft-deleted service (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} model.Service
+// @Router /admin/services/{id}/restore [post]
+func (h *ServiceHandler) RestoreService(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	restored, err := h.serviceRepo.Restore(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore service"})
+		return
+	}
+	if !restored {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted service not found"})
+		return
+	}
+	recordAudit(c, h.auditRepo, model.AuditActionRestore, model.AuditEntityService, uint(id), nil)
+
+	service, err := h.serviceRepo.GetByID(uint(id))
+	if err != nil || service == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+		return
+	}
 
 	c.JSON(http.StatusOK, service)
 }
@@ -200,6 +479,51 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service"})
 		return
 	}
+	recordAudit(c, h.auditRepo, model.AuditActionDelete, model.AuditEntityService, uint(id), nil)
 
 	c.Status(http.StatusNoContent)
 }
+
+// SetCategoryActive godoc
+// @Summary Activate or deactivate all services in a category (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param category path string true "Service category"
+// @Param request body map[string]bool true "is_active"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/services/category/{category}/active [patch]
+func (h *ServiceHandler) SetCategoryActive(c *gin.Context) {
+	category := c.Param("category")
+
+	var req struct {
+		IsActive *bool `json:"is_active" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exists, err := h.serviceRepo.CategoryExists(category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	updated, err := h.serviceRepo.SetActiveByCategory(category, *req.IsActive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update services"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category":  category,
+		"is_active": *req.IsActive,
+		"updated":   updated,
+	})
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/model"
+)
+
+type ServiceVariantRequest struct {
+	Label         string `json:"label" binding:"required,max=50"`
+	PriceDelta    int    `json:"price_delta"`
+	DurationDelta int    `json:"duration_delta"`
+}
+
+// ListVariants godoc
+// @Summary List a service's variants / pricing tiers (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {array} model.ServiceVariant
+// @Router /admin/services/{id}/variants [get]
+func (h *ServiceHandler) ListVariants(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	variants, err := h.serviceRepo.GetVariants(service.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch variants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// CreateVariant godoc
+// @Summary Add a variant / pricing tier to a service (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param request body ServiceVariantRequest true "Variant details"
+// @Success 201 {object} model.ServiceVariant
+// @Router /admin/services/{id}/variants [post]
+func (h *ServiceHandler) CreateVariant(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	var req ServiceVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant := &model.ServiceVariant{ServiceID: service.ID}
+	if !applyVariantRequest(c, service, variant, &req) {
+		return
+	}
+
+	if err := h.serviceRepo.CreateVariant(variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create variant"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// UpdateVariant godoc
+// @Summary Update a service variant (admin only)
+// @Description Existing bookings keep the label, price and duration captured when they were made.
+// @Tags services
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param variantId path int true "Variant ID"
+// @Param request body ServiceVariantRequest true "Variant details"
+// @Success 200 {object} model.ServiceVariant
+// @Router /admin/services/{id}/variants/{variantId} [put]
+func (h *ServiceHandler) UpdateVariant(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	variant, ok := h.loadVariant(c, service)
+	if !ok {
+		return
+	}
+
+	var req ServiceVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !applyVariantRequest(c, service, variant, &req) {
+		return
+	}
+
+	if err := h.serviceRepo.UpdateVariant(variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update variant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteVariant godoc
+// @Summary Delete a service variant (admin only)
+// @Tags services
+// @Security BearerAuth
+// @Param id path int true "Service ID"
+// @Param variantId path int true "Variant ID"
+// @Success 204
+// @Router /admin/services/{id}/variants/{variantId} [delete]
+func (h *ServiceHandler) DeleteVariant(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	variant, ok := h.loadVariant(c, service)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceRepo.DeleteVariant(variant.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete variant"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadService fetches the service named by the :id parameter, writing the
+// error response when it can't
+func (h *ServiceHandler) loadService(c *gin.Context) (*model.Service, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+		return nil, false
+	}
+	if service == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return nil, false
+	}
+	return service, true
+}
+
+// loadVariant fetches the :variantId variant, which must belong to service
+func (h *ServiceHandler) loadVariant(c *gin.Context, service *model.Service) (*model.ServiceVariant, bool) {
+	id, err := strconv.ParseUint(c.Param("variantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return nil, false
+	}
+
+	variant, err := h.serviceRepo.GetVariant(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch variant"})
+		return nil, false
+	}
+	if variant == nil || variant.ServiceID != service.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return nil, false
+	}
+	return variant, true
+}
+
+// applyVariantRequest copies req onto variant, rejecting deltas that would
+// push the price below zero or the duration under a minute
+func applyVariantRequest(c *gin.Context, service *model.Service, variant *model.ServiceVariant, req *ServiceVariantRequest) bool {
+	variant.Label = strings.TrimSpace(req.Label)
+	variant.PriceDelta = req.PriceDelta
+	variant.DurationDelta = req.DurationDelta
+
+	if variant.Label == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "label is required"})
+		return false
+	}
+	if variant.Price(service) < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price_delta would make the price negative"})
+		return false
+	}
+	if variant.Duration(service) < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_delta would make the duration shorter than a minute"})
+		return false
+	}
+	return true
+}
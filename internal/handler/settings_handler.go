@@ -2,12 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"linda-salon-api/internal/model"
 	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
 )
 
 type SettingsHandler struct {
@@ -70,6 +76,12 @@ func (h *SettingsHandler) UpdatePWAIcons(c *gin.Context) {
 		return
 	}
 
+	// 圖標變更後讓 service worker 重新快取
+	if err := h.bumpPWACacheVersion(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update PWA cache version"})
+		return
+	}
+
 	c.JSON(http.StatusOK, config)
 }
 
@@ -84,13 +96,7 @@ func (h *SettingsHandler) GetBranding(c *gin.Context) {
 
 	if err == gorm.ErrRecordNotFound {
 		// 返回預設值
-		c.JSON(http.StatusOK, model.BrandingConfig{
-			Name:            "Linda 髮廊",
-			ShortName:       "Linda",
-			Description:     "專業美髮服務，打造您的完美造型",
-			ThemeColor:      "#8B5CF6",
-			BackgroundColor: "#FFFFFF",
-		})
+		c.JSON(http.StatusOK, model.DefaultBrandingConfig())
 		return
 	}
 
@@ -129,9 +135,77 @@ func (h *SettingsHandler) UpdateBranding(c *gin.Context) {
 		return
 	}
 
+	// 品牌變更會改變 manifest，讓 service worker 重新快取
+	if err := h.bumpPWACacheVersion(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update PWA cache version"})
+		return
+	}
+
 	c.JSON(http.StatusOK, config)
 }
 
+// GetSalonContact 取得店家聯絡資訊
+// GET /api/v1/settings/contact
+func (h *SettingsHandler) GetSalonContact(c *gin.Context) {
+	contact, err := service.LoadSalonContact(h.settingsRepo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get salon contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+// UpdateSalonContact 更新店家聯絡資訊 (Admin only)
+// PUT /api/v1/admin/settings/contact
+func (h *SettingsHandler) UpdateSalonContact(c *gin.Context) {
+	var config model.SalonContactConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, err := json.Marshal(config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize config"})
+		return
+	}
+
+	settings := &model.Settings{
+		Key:      model.SettingsKeySalonContact,
+		Value:    string(value),
+		Category: "general",
+	}
+
+	if err := h.settingsRepo.Upsert(settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save salon contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// GetPublicSettings 取得前端所需的公開設定
+// GET /api/v1/settings
+func (h *SettingsHandler) GetPublicSettings(c *gin.Context) {
+	branding := model.DefaultBrandingConfig()
+	if _, err := h.settingsRepo.GetValue(model.SettingsKeyBranding, &branding); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding"})
+		return
+	}
+
+	contact, err := service.LoadSalonContact(h.settingsRepo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get salon contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"branding": branding,
+		"contact":  contact,
+	})
+}
+
 // GetManifest 取得 PWA manifest.json
 // GET /api/v1/manifest.json
 func (h *SettingsHandler) GetManifest(c *gin.Context) {
@@ -142,13 +216,7 @@ func (h *SettingsHandler) GetManifest(c *gin.Context) {
 		json.Unmarshal([]byte(branding.Value), &brandingConfig)
 	} else {
 		// 使用預設值
-		brandingConfig = model.BrandingConfig{
-			Name:            "Linda 髮廊",
-			ShortName:       "Linda",
-			Description:     "專業美髮服務，打造您的完美造型",
-			ThemeColor:      "#8B5CF6",
-			BackgroundColor: "#FFFFFF",
-		}
+		brandingConfig = model.DefaultBrandingConfig()
 	}
 
 	// 取得圖標設定
@@ -247,3 +315,393 @@ func (h *SettingsHandler) GetManifest(c *gin.Context) {
 	c.Header("Content-Type", "application/manifest+json")
 	c.JSON(http.StatusOK, manifest)
 }
+
+// GetServiceWorkerConfig 取得 PWA service worker 設定
+// GET /api/v1/settings/pwa/sw-config
+func (h *SettingsHandler) GetServiceWorkerConfig(c *gin.Context) {
+	config := model.DefaultPWAServiceWorkerConfig()
+	if _, err := h.settingsRepo.GetValue(model.SettingsKeyPWAServiceWorker, &config); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get service worker config"})
+		return
+	}
+
+	// 每次都要重新驗證，避免 service worker 拿到舊版本
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, config)
+}
+
+// UpdateServiceWorkerConfig 更新 PWA service worker 設定 (Admin only)
+// PUT /api/v1/admin/settings/pwa/sw-config
+func (h *SettingsHandler) UpdateServiceWorkerConfig(c *gin.Context) {
+	var config model.PWAServiceWorkerConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, pattern := range config.AssetPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid asset pattern: %s", pattern)})
+			return
+		}
+	}
+
+	// 快取版本由系統管理，在資料庫中沿用目前版本再遞增
+	version, err := h.settingsRepo.ReplaceJSONIncrementing(model.SettingsKeyPWAServiceWorker, config, "cache_version", 1)
+	if err == gorm.ErrRecordNotFound {
+		config.CacheVersion = model.DefaultPWAServiceWorkerConfig().CacheVersion + 1
+		err = h.saveServiceWorkerConfig(config)
+	} else {
+		config.CacheVersion = version
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save service worker config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// bumpPWACacheVersion 遞增 service worker 快取版本，讓用戶端重新下載資源
+func (h *SettingsHandler) bumpPWACacheVersion() error {
+	_, err := h.settingsRepo.IncrementJSONInt(model.SettingsKeyPWAServiceWorker, "cache_version", 1)
+	if err == gorm.ErrRecordNotFound {
+		config := model.DefaultPWAServiceWorkerConfig()
+		config.CacheVersion++
+		return h.saveServiceWorkerConfig(config)
+	}
+	return err
+}
+
+func (h *SettingsHandler) saveServiceWorkerConfig(config model.PWAServiceWorkerConfig) error {
+	value, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return h.settingsRepo.Upsert(&model.Settings{
+		Key:      model.SettingsKeyPWAServiceWorker,
+		Value:    string(value),
+		Category: "pwa",
+	})
+}
+
+// ruleSetting 描述一個可透過 /admin/settings/rules 管理的單值設定
+type ruleSetting struct {
+	category     string
+	defaultValue interface{}
+	// validate 解析並驗證 JSON 值，回傳要儲存的值
+	validate func(raw json.RawMessage) (interface{}, error)
+}
+
+// ruleSettings 所有可由管理員調整的規則設定
+var ruleSettings = map[string]ruleSetting{
+	model.SettingsKeyStylistServicesFallback:  {category: "booking", defaultValue: model.DefaultStylistServicesFallback, validate: validateBoolRule},
+	model.SettingsKeyTaxRate:                  {category: "booking", defaultValue: 0.0, validate: validateRateRule},
+	model.SettingsKeyAutoConfirm:              {category: "booking", defaultValue: false, validate: validateBoolRule},
+	model.SettingsKeyNextAvailableHorizonDays: {category: "booking", defaultValue: model.DefaultNextAvailableHorizonDays, validate: validateHorizonDaysRule},
+	model.SettingsKeyBookingTagAllowlist:      {category: "booking", defaultValue: []string{}, validate: validateTagListRule},
+	model.SettingsKeyMaxAdvanceDays:           {category: "booking", defaultValue: model.DefaultMaxAdvanceDays, validate: validateMaxAdvanceDaysRule},
+	model.SettingsKeyCancellationNoticeHours:  {category: "booking", defaultValue: model.DefaultCancellationNoticeHours, validate: validateCancellationNoticeRule},
+	model.SettingsKeyMinDurationMinutes:       {category: "booking", defaultValue: 0, validate: validateDurationLimitRule},
+	model.SettingsKeyMaxDurationMinutes:       {category: "booking", defaultValue: 0, validate: validateDurationLimitRule},
+	model.SettingsKeyReminderHoursBefore:      {category: "notifications", defaultValue: model.DefaultReminderHoursBefore, validate: validateReminderHoursRule},
+	model.SettingsKeyLoyaltyPointsRate:        {category: "loyalty", defaultValue: model.DefaultLoyaltyPointsRate, validate: validateRateRule},
+}
+
+func validateBoolRule(raw json.RawMessage) (interface{}, error) {
+	var v bool
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a boolean")
+	}
+	return v, nil
+}
+
+func validateRateRule(raw json.RawMessage) (interface{}, error) {
+	var v float64
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a number")
+	}
+	if v < 0 || v > 1 {
+		return nil, fmt.Errorf("must be between 0 and 1")
+	}
+	return v, nil
+}
+
+func validateReminderHoursRule(raw json.RawMessage) (interface{}, error) {
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a whole number of hours")
+	}
+	if v < model.MinReminderHoursBefore || v > model.MaxReminderHoursBefore {
+		return nil, fmt.Errorf("must be between %d and %d", model.MinReminderHoursBefore, model.MaxReminderHoursBefore)
+	}
+	return v, nil
+}
+
+func validateHorizonDaysRule(raw json.RawMessage) (interface{}, error) {
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a whole number of days")
+	}
+	if v < 1 || v > model.MaxNextAvailableHorizonDays {
+		return nil, fmt.Errorf("must be between 1 and %d", model.MaxNextAvailableHorizonDays)
+	}
+	return v, nil
+}
+
+func validateMaxAdvanceDaysRule(raw json.RawMessage) (interface{}, error) {
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a whole number of days")
+	}
+	if v < 1 || v > model.MaxMaxAdvanceDays {
+		return nil, fmt.Errorf("must be between 1 and %d", model.MaxMaxAdvanceDays)
+	}
+	return v, nil
+}
+
+func validateCancellationNoticeRule(raw json.RawMessage) (interface{}, error) {
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a whole number of hours")
+	}
+	if v < 0 || v > model.MaxCancellationNoticeHours {
+		return nil, fmt.Errorf("must be between 0 and %d", model.MaxCancellationNoticeHours)
+	}
+	return v, nil
+}
+
+func validateDurationLimitRule(raw json.RawMessage) (interface{}, error) {
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be a whole number of minutes")
+	}
+	if v < 0 || v > model.MaxBookingDurationLimitMinutes {
+		return nil, fmt.Errorf("must be between 0 and %d", model.MaxBookingDurationLimitMinutes)
+	}
+	return v, nil
+}
+
+func validateTagListRule(raw json.RawMessage) (interface{}, error) {
+	var v []string
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	v = model.NormalizeBookingTags(v)
+	for _, tag := range v {
+		if utf8.RuneCountInString(tag) > model.MaxBookingTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, model.MaxBookingTagLength)
+		}
+	}
+	return v, nil
+}
+
+// GetRules 取得所有規則設定 (Admin only)
+// GET /api/v1/admin/settings/rules
+func (h *SettingsHandler) GetRules(c *gin.Context) {
+	rules := make(map[string]interface{}, len(ruleSettings))
+	for key, rule := range ruleSettings {
+		var value interface{}
+		found, err := h.settingsRepo.GetValue(key, &value)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rules"})
+			return
+		}
+		if !found {
+			value = rule.defaultValue
+		}
+		rules[key] = value
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateRules 更新規則設定 (Admin only)
+// PUT /api/v1/admin/settings/rules
+func (h *SettingsHandler) UpdateRules(c *gin.Context) {
+	var req map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 先驗證全部欄位，避免部分寫入
+	values := make(map[string]interface{}, len(req))
+	for key, raw := range req {
+		rule, ok := ruleSettings[key]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown setting: %s", key)})
+			return
+		}
+		value, err := rule.validate(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid value for %s: %v", key, err)})
+			return
+		}
+		values[key] = value
+	}
+	minDuration, maxDuration, err := h.durationLimitsAfter(values)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rules"})
+		return
+	}
+	if minDuration > 0 && maxDuration > 0 && minDuration > maxDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s (%d) must not be greater than %s (%d)",
+			model.SettingsKeyMinDurationMinutes, minDuration, model.SettingsKeyMaxDurationMinutes, maxDuration)})
+		return
+	}
+
+	for key, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize config"})
+			return
+		}
+		settings := &model.Settings{
+			Key:      key,
+			Value:    string(encoded),
+			Category: ruleSettings[key].category,
+		}
+		if err := h.settingsRepo.Upsert(settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rules"})
+			return
+		}
+	}
+
+	h.GetRules(c)
+}
+
+// durationLimitsAfter returns the booking duration limits as they'd be
+// after saving values, falling back to the stored settings
+func (h *SettingsHandler) durationLimitsAfter(values map[string]interface{}) (int, int, error) {
+	limit := func(key string) (int, error) {
+		if v, ok := values[key]; ok {
+			return v.(int), nil
+		}
+		stored, err := h.settingsRepo.GetFloat(key, 0)
+		return int(stored), err
+	}
+	minDuration, err := limit(model.SettingsKeyMinDurationMinutes)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxDuration, err := limit(model.SettingsKeyMaxDurationMinutes)
+	if err != nil {
+		return 0, 0, err
+	}
+	return minDuration, maxDuration, nil
+}
+
+// GetNotificationRecipients 取得管理員通知收件人 (Admin only)
+// GET /api/v1/admin/settings/notifications/recipients
+func (h *SettingsHandler) GetNotificationRecipients(c *gin.Context) {
+	recipients := []string{}
+	if _, err := h.settingsRepo.GetValue(model.SettingsKeyAdminNotificationRecipients, &recipients); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification recipients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recipients": recipients})
+}
+
+// UpdateNotificationRecipients 更新管理員通知收件人 (Admin only)
+// PUT /api/v1/admin/settings/notifications/recipients
+func (h *SettingsHandler) UpdateNotificationRecipients(c *gin.Context) {
+	var req struct {
+		Recipients []string `json:"recipients"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 驗證並去除重複的信箱
+	recipients := []string{}
+	seen := make(map[string]bool)
+	for _, raw := range req.Recipients {
+		email := strings.TrimSpace(raw)
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid email: %s", raw)})
+			return
+		}
+		key := strings.ToLower(email)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		recipients = append(recipients, email)
+	}
+
+	value, err := json.Marshal(recipients)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize config"})
+		return
+	}
+
+	settings := &model.Settings{
+		Key:      model.SettingsKeyAdminNotificationRecipients,
+		Value:    string(value),
+		Category: "notifications",
+	}
+
+	if err := h.settingsRepo.Upsert(settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notification recipients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recipients": recipients})
+}
+
+// GetScheduleTemplates 取得設計師班表範本 (Admin only)
+// GET /api/v1/admin/settings/schedule-templates
+func (h *SettingsHandler) GetScheduleTemplates(c *gin.Context) {
+	templates := model.DefaultScheduleTemplates()
+	if _, err := h.settingsRepo.GetValue(model.SettingsKeyScheduleTemplates, &templates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// UpdateScheduleTemplates 更新設計師班表範本，整組取代 (Admin only)
+// PUT /api/v1/admin/settings/schedule-templates
+func (h *SettingsHandler) UpdateScheduleTemplates(c *gin.Context) {
+	var templates model.ScheduleTemplates
+	if err := c.ShouldBindJSON(&templates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for name, slots := range templates {
+		if strings.TrimSpace(name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Template name is required"})
+			return
+		}
+		if err := model.ValidateScheduleSlots(slots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Template %q: %v", name, err)})
+			return
+		}
+	}
+
+	value, err := json.Marshal(templates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize config"})
+		return
+	}
+
+	settings := &model.Settings{
+		Key:      model.SettingsKeyScheduleTemplates,
+		Value:    string(value),
+		Category: "stylist",
+	}
+
+	if err := h.settingsRepo.Upsert(settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save schedule templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
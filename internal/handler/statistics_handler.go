@@ -1,25 +1,50 @@
 package handler
 
 import (
+	"encoding/csv"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/model"
 	"linda-salon-api/internal/repository"
 )
 
 type StatisticsHandler struct {
 	bookingRepo *repository.BookingRepository
 	stylistRepo *repository.StylistRepository
+	location    *time.Location // 店家時區，決定「今天」的日界線
 }
 
-func NewStatisticsHandler(bookingRepo *repository.BookingRepository, stylistRepo *repository.StylistRepository) *StatisticsHandler {
+func NewStatisticsHandler(bookingRepo *repository.BookingRepository, stylistRepo *repository.StylistRepository, location *time.Location) *StatisticsHandler {
 	return &StatisticsHandler{
 		bookingRepo: bookingRepo,
 		stylistRepo: stylistRepo,
+		location:    location,
 	}
 }
 
+// maxSummaryDays 自訂期間統計的最大天數
+const maxSummaryDays = 366
+
+// SummaryStats is the aggregate KPI payload for a custom period
+type SummaryStats struct {
+	StartDate           string  `json:"start_date"`
+	EndDate             string  `json:"end_date"`
+	TotalBookings       int64   `json:"total_bookings"`
+	CompletedBookings   int64   `json:"completed_bookings"`
+	CancelledBookings   int64   `json:"cancelled_bookings"`
+	NoShowBookings      int64   `json:"no_show_bookings"`
+	CancellationRate    float64 `json:"cancellation_rate"`
+	TotalRevenue        int     `json:"total_revenue"`
+	AverageBookingValue int     `json:"average_booking_value"`
+	UniqueCustomers     int64   `json:"unique_customers"`
+	NewCustomers        int64   `json:"new_customers"`
+}
+
 type DashboardStats struct {
 	TodayBookings  int64                    `json:"today_bookings"`
 	WeekBookings   int64                    `json:"week_bookings"`
@@ -39,17 +64,14 @@ type DashboardStats struct {
 // @Success 200 {object} DashboardStats
 // @Router /statistics/dashboard [get]
 func (h *StatisticsHandler) GetDashboardStats(c *gin.Context) {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// booking_date 以 UTC 午夜儲存，所以用店家時區的日期換算成 UTC 日期
+	now := time.Now().In(h.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 
-	// Start of week (Monday)
-	weekStart := today.AddDate(0, 0, -int(today.Weekday())+1)
-	if today.Weekday() == time.Sunday {
-		weekStart = weekStart.AddDate(0, 0, -7)
-	}
+	weekStart := startOfWeek(today)
 
 	// Start of month
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 
 	// Today's bookings count
 	todayBookings, err := h.bookingRepo.CountByDateRange(today, today, "")
@@ -96,8 +118,8 @@ func (h *StatisticsHandler) GetDashboardStats(c *gin.Context) {
 		return
 	}
 
-	// Popular services (this month)
-	popularServices, err := h.bookingRepo.GetPopularServices(5, monthStart, monthEnd)
+	// Popular services (this month, completed bookings only)
+	popularServices, err := h.bookingRepo.GetPopularServices(5, monthStart, monthEnd, []string{model.BookingStatusCompleted})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch popular services"})
 		return
@@ -128,9 +150,14 @@ func (h *StatisticsHandler) GetDashboardStats(c *gin.Context) {
 // @Summary Get revenue report (admin only)
 // @Tags statistics
 // @Security BearerAuth
+// @Description With format=csv the daily revenue is returned as a CSV download
+// @Description (date, bookings, revenue and a totals row) instead of JSON.
 // @Produce json
+// @Produce text/csv
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param split_tax query bool false "Split revenue into net and tax"
+// @Param format query string false "json (default) or csv"
 // @Success 200 {object} map[string]interface{}
 // @Router /statistics/revenue [get]
 func (h *StatisticsHandler) GetRevenueReport(c *gin.Context) {
@@ -154,6 +181,22 @@ func (h *StatisticsHandler) GetRevenueReport(c *gin.Context) {
 		return
 	}
 
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+	if format == "csv" {
+		// Same rows as revenue_by_day in the JSON report
+		revenueByDay, err := h.bookingRepo.GetRevenueByDay(startDate, endDate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue by day"})
+			return
+		}
+		writeRevenueCSV(c, startDateStr, endDateStr, revenueByDay)
+		return
+	}
+
 	// Total revenue
 	totalRevenue, err := h.bookingRepo.GetRevenueByDateRange(startDate, endDate)
 	if err != nil {
@@ -175,11 +218,433 @@ func (h *StatisticsHandler) GetRevenueReport(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	report := gin.H{
 		"start_date":     startDateStr,
 		"end_date":       endDateStr,
 		"total_revenue":  totalRevenue,
 		"booking_count":  bookingCount,
 		"revenue_by_day": revenueByDay,
+	}
+
+	// Revenue is the pre-tax subtotal; optionally add the tax collected on top
+	if c.Query("split_tax") == "true" {
+		totalTax, err := h.bookingRepo.GetTaxByDateRange(startDate, endDate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total tax"})
+			return
+		}
+		report["net_revenue"] = totalRevenue
+		report["total_tax"] = totalTax
+		report["total_with_tax"] = totalRevenue + totalTax
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetStylistStats godoc
+// @Summary Per-stylist bookings and revenue for a date range (admin only)
+// @Description Every active stylist (all stylists with include_inactive=true),
+// @Description including those without bookings. bookings excludes cancelled
+// @Description bookings; revenue is the pre-tax price of completed bookings.
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param sort query string false "revenue (default) or bookings"
+// @Param include_inactive query bool false "Include inactive stylists"
+// @Success 200 {array} repository.StylistStats
+// @Router /admin/statistics/stylists [get]
+func (h *StatisticsHandler) GetStylistStats(c *gin.Context) {
+	startDateStr := c.Query("start_date")
+	endDateStr := c.Query("end_date")
+
+	if startDateStr == "" || endDateStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required"})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", startDateStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format"})
+		return
+	}
+
+	endDate, err := time.Parse("2006-01-02", endDateStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format"})
+		return
+	}
+
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	sort := c.DefaultQuery("sort", repository.StylistStatsSortRevenue)
+	if sort != repository.StylistStatsSortRevenue && sort != repository.StylistStatsSortBookings {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be revenue or bookings"})
+		return
+	}
+
+	stats, err := h.stylistRepo.GetStylistStats(startDate, endDate, c.Query("include_inactive") == "true", sort)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// writeRevenueCSV streams the revenue-by-day rows as a CSV attachment with
+// a totals row. A range without revenue gives just the header.
+func writeRevenueCSV(c *gin.Context, startDate, endDate string, revenueByDay []map[string]interface{}) {
+	filename := fmt.Sprintf("revenue_%s_%s.csv", startDate, endDate)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"date", "bookings", "revenue"})
+
+	var totalBookings, totalRevenue int64
+	for _, row := range revenueByDay {
+		bookings := csvInt(row["bookings"])
+		revenue := csvInt(row["revenue"])
+		totalBookings += bookings
+		totalRevenue += revenue
+
+		date := fmt.Sprint(row["date"])
+		if t, ok := row["date"].(time.Time); ok {
+			date = t.Format("2006-01-02")
+		}
+		w.Write([]string{date, strconv.FormatInt(bookings, 10), strconv.FormatInt(revenue, 10)})
+	}
+	if len(revenueByDay) > 0 {
+		w.Write([]string{"total", strconv.FormatInt(totalBookings, 10), strconv.FormatInt(totalRevenue, 10)})
+	}
+	w.Flush()
+}
+
+// csvInt reads a COUNT/SUM column from a map scan, whose Go type depends on
+// the driver
+func csvInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int32:
+		return int64(n)
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	case []byte:
+		i, _ := strconv.ParseInt(string(n), 10, 64)
+		return i
+	}
+	return 0
+}
+
+// GetSummary godoc
+// @Summary Get aggregate KPIs for a custom period (admin only)
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} SummaryStats
+// @Router /admin/statistics/summary [get]
+func (h *StatisticsHandler) GetSummary(c *gin.Context) {
+	startDate, endDate, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+
+	counts, err := h.bookingRepo.CountByStatus(startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking counts"})
+		return
+	}
+
+	totalRevenue, err := h.bookingRepo.GetRevenueByDateRange(startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total revenue"})
+		return
+	}
+
+	uniqueCustomers, err := h.bookingRepo.CountUniqueCustomers(startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unique customers"})
+		return
+	}
+
+	newCustomers, err := h.bookingRepo.CountNewCustomers(startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch new customers"})
+		return
+	}
+
+	stats := SummaryStats{
+		StartDate:         startDate.Format("2006-01-02"),
+		EndDate:           endDate.Format("2006-01-02"),
+		CompletedBookings: counts[model.BookingStatusCompleted],
+		CancelledBookings: counts[model.BookingStatusCancelled],
+		NoShowBookings:    counts[model.BookingStatusNoShow],
+		TotalRevenue:      totalRevenue,
+		UniqueCustomers:   uniqueCustomers,
+		NewCustomers:      newCustomers,
+	}
+	for _, count := range counts {
+		stats.TotalBookings += count
+	}
+	if stats.TotalBookings > 0 {
+		stats.CancellationRate = float64(stats.CancelledBookings) / float64(stats.TotalBookings)
+	}
+	if stats.CompletedBookings > 0 {
+		stats.AverageBookingValue = totalRevenue / int(stats.CompletedBookings)
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// startOfWeek returns the Monday of day's week
+func startOfWeek(day time.Time) time.Time {
+	start := day.AddDate(0, 0, -int(day.Weekday())+1)
+	if day.Weekday() == time.Sunday {
+		start = start.AddDate(0, 0, -7)
+	}
+	return start
+}
+
+// PeriodStats is the revenue and booking count of one calendar period
+type PeriodStats struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Revenue   int    `json:"revenue"`  // completed bookings
+	Bookings  int64  `json:"bookings"` // all statuses, as on the dashboard
+}
+
+// PeriodComparison compares the current week or month with the previous one.
+// The percentages are nil when the previous period's value is zero.
+type PeriodComparison struct {
+	Period                string      `json:"period"` // week or month
+	Current               PeriodStats `json:"current"`
+	Previous              PeriodStats `json:"previous"`
+	RevenueChange         int         `json:"revenue_change"`
+	RevenueChangePercent  *float64    `json:"revenue_change_percent"`
+	BookingsChange        int64       `json:"bookings_change"`
+	BookingsChangePercent *float64    `json:"bookings_change_percent"`
+}
+
+// GetPeriodComparison godoc
+// @Summary Compare this week or month with the previous one (admin only)
+// @Description Periods are whole calendar weeks (Monday to Sunday) or months
+// @Description in the salon's timezone, so the current period includes its
+// @Description remaining days.
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "week or month" default(month)
+// @Success 200 {object} PeriodComparison
+// @Router /admin/statistics/compare [get]
+func (h *StatisticsHandler) GetPeriodComparison(c *gin.Context) {
+	period := c.DefaultQuery("period", "month")
+
+	now := time.Now().In(h.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var currentStart, currentEnd, previousStart, previousEnd time.Time
+	switch period {
+	case "month":
+		currentStart = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		currentEnd = currentStart.AddDate(0, 1, -1)
+		previousStart = currentStart.AddDate(0, -1, 0)
+		previousEnd = currentStart.AddDate(0, 0, -1)
+	case "week":
+		currentStart = startOfWeek(today)
+		currentEnd = currentStart.AddDate(0, 0, 6)
+		previousStart = currentStart.AddDate(0, 0, -7)
+		previousEnd = currentStart.AddDate(0, 0, -1)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be week or month"})
+		return
+	}
+
+	current, err := h.periodStats(currentStart, currentEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch current period statistics"})
+		return
+	}
+	previous, err := h.periodStats(previousStart, previousEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch previous period statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PeriodComparison{
+		Period:                period,
+		Current:               current,
+		Previous:              previous,
+		RevenueChange:         current.Revenue - previous.Revenue,
+		RevenueChangePercent:  percentChange(int64(current.Revenue), int64(previous.Revenue)),
+		BookingsChange:        current.Bookings - previous.Bookings,
+		BookingsChangePercent: percentChange(current.Bookings, previous.Bookings),
 	})
 }
+
+// periodStats loads the revenue and booking count between two dates (inclusive)
+func (h *StatisticsHandler) periodStats(start, end time.Time) (PeriodStats, error) {
+	revenue, err := h.bookingRepo.GetRevenueByDateRange(start, end)
+	if err != nil {
+		return PeriodStats{}, err
+	}
+	bookings, err := h.bookingRepo.CountByDateRange(start, end, "")
+	if err != nil {
+		return PeriodStats{}, err
+	}
+	return PeriodStats{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Revenue:   revenue,
+		Bookings:  bookings,
+	}, nil
+}
+
+// percentChange returns the change from previous to current in percent,
+// rounded to one decimal, or nil when previous is zero
+func percentChange(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round(float64(current-previous)/float64(previous)*1000) / 10
+	return &change
+}
+
+// WeekdayRevenueStats is the average completed-booking revenue on one day of
+// the week over a period. Averages are per occurrence of that weekday in the
+// period, including days without bookings.
+type WeekdayRevenueStats struct {
+	DayOfWeek       int     `json:"day_of_week"` // 0=Sunday, 1=Monday, ..., 6=Saturday
+	Weekday         string  `json:"weekday"`
+	Days            int     `json:"days"` // 期間內此星期幾出現的次數
+	TotalBookings   int64   `json:"total_bookings"`
+	TotalRevenue    int     `json:"total_revenue"`
+	AverageBookings float64 `json:"average_bookings"`
+	AverageRevenue  float64 `json:"average_revenue"`
+}
+
+// GetWeekdayRevenue godoc
+// @Summary Get average revenue and bookings per day of the week (admin only)
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Success 200 {array} WeekdayRevenueStats
+// @Router /admin/statistics/weekday-revenue [get]
+func (h *StatisticsHandler) GetWeekdayRevenue(c *gin.Context) {
+	startDate, endDate, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+
+	totals, err := h.bookingRepo.GetRevenueByWeekday(startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weekday revenue"})
+		return
+	}
+
+	// 每個星期幾都要回傳，沒有預約的日子為 0
+	stats := make([]WeekdayRevenueStats, 7)
+	for day := range stats {
+		stats[day].DayOfWeek = day
+		stats[day].Weekday = time.Weekday(day).String()
+	}
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		stats[d.Weekday()].Days++
+	}
+	for _, total := range totals {
+		if total.DayOfWeek < 0 || total.DayOfWeek > 6 {
+			continue
+		}
+		stats[total.DayOfWeek].TotalBookings = total.Bookings
+		stats[total.DayOfWeek].TotalRevenue = total.Revenue
+	}
+	for i := range stats {
+		if stats[i].Days > 0 {
+			stats[i].AverageBookings = float64(stats[i].TotalBookings) / float64(stats[i].Days)
+			stats[i].AverageRevenue = float64(stats[i].TotalRevenue) / float64(stats[i].Days)
+		}
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// parsePeriod reads the required start/end (YYYY-MM-DD) query parameters of a
+// custom statistics period, writing a 400 response when they're invalid
+func parsePeriod(c *gin.Context) (time.Time, time.Time, bool) {
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if startStr == "" || endStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start and end are required"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	startDate, err := time.Parse("2006-01-02", startStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start format"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	endDate, err := time.Parse("2006-01-02", endStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end format"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must not be before start"})
+		return time.Time{}, time.Time{}, false
+	}
+	if endDate.Sub(startDate) >= maxSummaryDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Period cannot exceed %d days", maxSummaryDays)})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return startDate, endDate, true
+}
+
+// GetNoShowStats godoc
+// @Summary Rank customers by no-shows in a period (admin only)
+// @Tags statistics
+// @Security BearerAuth
+// @Produce json
+// @Param start query string true "Start date (YYYY-MM-DD)"
+// @Param end query string true "End date (YYYY-MM-DD)"
+// @Param limit query int false "Max customers (max 100)" default(20)
+// @Success 200 {array} repository.CustomerNoShowStats
+// @Router /admin/statistics/no-shows [get]
+func (h *StatisticsHandler) GetNoShowStats(c *gin.Context) {
+	startDate, endDate, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+
+	limit, err := queryLimit(c, 20, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.bookingRepo.GetNoShowStats(startDate, endDate, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch no-show statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stylist exception types. Time off is the only exception the schedule has
+// today; date overrides and salon closures would be added here as new types.
+const (
+	StylistExceptionTimeOff = "time_off"
+)
+
+// StylistException is one deviation from a stylist's weekly schedule
+type StylistException struct {
+	Type      string `json:"type"`
+	ID        uint   `json:"id"`
+	Date      string `json:"date"`                 // YYYY-MM-DD
+	FullDay   bool   `json:"full_day"`             // the stylist is unavailable all day
+	StartTime string `json:"start_time,omitempty"` // HH:MM, partial days only
+	EndTime   string `json:"end_time,omitempty"`   // HH:MM, partial days only
+	Reason    string `json:"reason,omitempty"`
+}
+
+// GetExceptions godoc
+// @Summary List everything that overrides a stylist's weekly schedule in a range (admin only)
+// @Description Time off (full and partial days) merged into one chronological list with a
+// @Description type discriminator, to explain why days show no availability.
+// @Tags stylists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param start query string false "Start date (YYYY-MM-DD), defaults to today"
+// @Param end query string false "End date (YYYY-MM-DD), defaults to 90 days after start"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/stylists/{id}/exceptions [get]
+func (h *StylistHandler) GetExceptions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	start := h.availability.Today()
+	if startStr := c.Query("start"); startStr != "" {
+		start, err = time.Parse("2006-01-02", startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	end := start.AddDate(0, 0, 90)
+	if endStr := c.Query("end"); endStr != "" {
+		end, err = time.Parse("2006-01-02", endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if end.Before(start) || end.Sub(start) > maxTimeOffListDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be on or after start and within a year of it"})
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
+
+	timeOff, err := h.stylistRepo.GetTimeOffByStylistsAndDateRange([]uint{stylist.ID}, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time off"})
+		return
+	}
+
+	exceptions := make([]StylistException, 0, len(timeOff))
+	for _, t := range timeOff {
+		exceptions = append(exceptions, StylistException{
+			Type:      StylistExceptionTimeOff,
+			ID:        t.ID,
+			Date:      t.Date.Format("2006-01-02"),
+			FullDay:   t.IsFullDay(),
+			StartTime: t.StartTime,
+			EndTime:   t.EndTime,
+			Reason:    t.Reason,
+		})
+	}
+	// Full days sort before partial ones on the same date
+	sort.SliceStable(exceptions, func(i, j int) bool {
+		a, b := exceptions[i], exceptions[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.FullDay != b.FullDay {
+			return a.FullDay
+		}
+		return a.StartTime < b.StartTime
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"stylist_id": stylist.ID,
+		"start":      start.Format("2006-01-02"),
+		"end":        end.Format("2006-01-02"),
+		"exceptions": exceptions,
+	})
+}
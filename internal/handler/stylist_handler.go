@@ -1,18 +1,26 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/middleware"
 	"linda-salon-api/internal/model"
 	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
 )
 
 type StylistHandler struct {
-	stylistRepo *repository.StylistRepository
-	bookingRepo *repository.BookingRepository
+	stylistRepo  *repository.StylistRepository
+	bookingRepo  *repository.BookingRepository
+	serviceRepo  *repository.ServiceRepository
+	settingsRepo *repository.SettingsRepository
+	availability *service.AvailabilityService
+	auditRepo    *repository.AuditLogRepository
 }
 
 func NewStylistHandler(stylistRepo *repository.StylistRepository) *StylistHandler {
@@ -21,46 +29,92 @@ func NewStylistHandler(stylistRepo *repository.StylistRepository) *StylistHandle
 	}
 }
 
-func NewStylistHandlerWithBooking(stylistRepo *repository.StylistRepository, bookingRepo *repository.BookingRepository) *StylistHandler {
+func NewStylistHandlerWithBooking(
+	stylistRepo *repository.StylistRepository,
+	bookingRepo *repository.BookingRepository,
+	serviceRepo *repository.ServiceRepository,
+	settingsRepo *repository.SettingsRepository,
+	availability *service.AvailabilityService,
+	auditRepo *repository.AuditLogRepository,
+) *StylistHandler {
 	return &StylistHandler{
-		stylistRepo: stylistRepo,
-		bookingRepo: bookingRepo,
+		stylistRepo:  stylistRepo,
+		bookingRepo:  bookingRepo,
+		serviceRepo:  serviceRepo,
+		settingsRepo: settingsRepo,
+		availability: availability,
+		auditRepo:    auditRepo,
+	}
+}
+
+// invalidateSlots drops the stylist's cached availability after a schedule
+// or time off change
+func (h *StylistHandler) invalidateSlots(stylistID uint) {
+	if h.availability != nil {
+		h.availability.InvalidateStylist(stylistID)
 	}
 }
 
 type CreateStylistRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Specialty   string `json:"specialty"`
-	Experience  int    `json:"experience" binding:"omitempty,min=0"`
-	Avatar      string `json:"avatar"`
+	Name               string `json:"name" binding:"required"`
+	Description        string `json:"description"`
+	Specialty          string `json:"specialty"`
+	Experience         int    `json:"experience" binding:"omitempty,min=0"`
+	Avatar             string `json:"avatar"`
+	PriceModifierType  string `json:"price_modifier_type"` // "", percent, flat
+	PriceModifierValue int    `json:"price_modifier_value"`
 }
 
 type UpdateStylistRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Specialty   string `json:"specialty"`
-	Experience  int    `json:"experience" binding:"omitempty,min=0"`
-	Avatar      string `json:"avatar"`
-	IsActive    *bool  `json:"is_active"`
+	Name               string  `json:"name"`
+	Description        string  `json:"description"`
+	Specialty          string  `json:"specialty"`
+	Experience         int     `json:"experience" binding:"omitempty,min=0"`
+	Avatar             string  `json:"avatar"`
+	IsActive           *bool   `json:"is_active"`
+	PriceModifierType  *string `json:"price_modifier_type"` // "" clears the modifier
+	PriceModifierValue *int    `json:"price_modifier_value"`
 }
 
 type CreateScheduleRequest struct {
-	DayOfWeek int    `json:"day_of_week" binding:"required,min=0,max=6"`
+	DayOfWeek int    `json:"day_of_week" binding:"min=0,max=6"`
 	StartTime string `json:"start_time" binding:"required"`
 	EndTime   string `json:"end_time" binding:"required"`
 }
 
+type BulkScheduleRequest struct {
+	Schedules []model.ScheduleTemplateSlot `json:"schedules"`
+	// AllWeekdays 捷徑：同一時段套用到週一至週五
+	AllWeekdays *WorkingTimeRange `json:"all_weekdays"`
+}
+
 // ListStylists godoc
 // @Summary List all stylists
 // @Tags stylists
 // @Produce json
 // @Param active_only query bool false "Show only active stylists" default(true)
+// @Param include_deleted query bool false "Also list soft-deleted stylists (admin only)"
 // @Success 200 {array} model.Stylist
 // @Router /stylists [get]
 func (h *StylistHandler) ListStylists(c *gin.Context) {
 	activeOnly := c.DefaultQuery("active_only", "true") == "true"
 
+	// 只有管理員路由 (/admin/stylists) 會帶有角色，公開列表忽略 include_deleted
+	role, _ := middleware.GetUserRole(c)
+	if role == "admin" && c.Query("include_deleted") == "true" {
+		stylists, err := h.stylistRepo.ListIncludingDeleted(activeOnly)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylists"})
+			return
+		}
+		views := make([]deletedStylistView, 0, len(stylists))
+		for i := range stylists {
+			views = append(views, deletedStylistView{Stylist: &stylists[i], DeletedAt: deletedAt(stylists[i].DeletedAt)})
+		}
+		c.JSON(http.StatusOK, views)
+		return
+	}
+
 	stylists, err := h.stylistRepo.List(activeOnly)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylists"})
@@ -70,6 +124,22 @@ func (h *StylistHandler) ListStylists(c *gin.Context) {
 	c.JSON(http.StatusOK, stylists)
 }
 
+// GetSpecialties godoc
+// @Summary List the specialties of active stylists with a count each
+// @Tags stylists
+// @Produce json
+// @Success 200 {array} repository.SpecialtyCount
+// @Router /stylists/specialties [get]
+func (h *StylistHandler) GetSpecialties(c *gin.Context) {
+	specialties, err := h.stylistRepo.GetSpecialties()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch specialties"})
+		return
+	}
+
+	c.JSON(http.StatusOK, specialties)
+}
+
 // GetStylist godoc
 // @Summary Get stylist by ID
 // @Tags stylists
@@ -108,24 +178,32 @@ func (h *StylistHandler) GetStylist(c *gin.Context) {
 // @Router /stylists [post]
 func (h *StylistHandler) CreateStylist(c *gin.Context) {
 	var req CreateStylistRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validatePriceModifier(req.PriceModifierType, req.PriceModifierValue); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	stylist := &model.Stylist{
-		Name:        req.Name,
-		Description: req.Description,
-		Specialty:   req.Specialty,
-		Experience:  req.Experience,
-		Avatar:      req.Avatar,
-		IsActive:    true,
+		Name:               req.Name,
+		Description:        req.Description,
+		Specialty:          req.Specialty,
+		Experience:         req.Experience,
+		Avatar:             req.Avatar,
+		IsActive:           true,
+		PriceModifierType:  req.PriceModifierType,
+		PriceModifierValue: req.PriceModifierValue,
 	}
 
 	if err := h.stylistRepo.Create(stylist); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stylist"})
 		return
 	}
+	recordAudit(c, h.auditRepo, model.AuditActionCreate, model.AuditEntityStylist, stylist.ID, auditSnapshot(stylist))
 
 	c.JSON(http.StatusCreated, stylist)
 }
@@ -154,10 +232,11 @@ func (h *StylistHandler) UpdateStylist(c *gin.Context) {
 	}
 
 	var req UpdateStylistRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	before := *stylist
 
 	if req.Name != "" {
 		stylist.Name = req.Name
@@ -177,11 +256,27 @@ func (h *StylistHandler) UpdateStylist(c *gin.Context) {
 	if req.IsActive != nil {
 		stylist.IsActive = *req.IsActive
 	}
+	if req.PriceModifierType != nil {
+		stylist.PriceModifierType = *req.PriceModifierType
+	}
+	if req.PriceModifierValue != nil {
+		stylist.PriceModifierValue = *req.PriceModifierValue
+	}
+	if stylist.PriceModifierType == "" {
+		stylist.PriceModifierValue = 0
+	}
+	if err := validatePriceModifier(stylist.PriceModifierType, stylist.PriceModifierValue); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := h.stylistRepo.Update(stylist); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update stylist"})
 		return
 	}
+	if changes := auditDiff(before, stylist); len(changes) > 0 {
+		recordAudit(c, h.auditRepo, model.AuditActionUpdate, model.AuditEntityStylist, stylist.ID, changes)
+	}
 
 	c.JSON(http.StatusOK, stylist)
 }
@@ -204,10 +299,46 @@ func (h *StylistHandler) DeleteStylist(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete stylist"})
 		return
 	}
+	recordAudit(c, h.auditRepo, model.AuditActionDelete, model.AuditEntityStylist, uint(id), nil)
 
 	c.Status(http.StatusNoContent)
 }
 
+// RestoreStylist godoc
+// @Summary Restore a soft-deleted stylist (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Success 200 {object} model.Stylist
+// @Router /admin/stylists/{id}/restore [post]
+func (h *StylistHandler) RestoreStylist(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	restored, err := h.stylistRepo.Restore(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore stylist"})
+		return
+	}
+	if !restored {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted stylist not found"})
+		return
+	}
+	recordAudit(c, h.auditRepo, model.AuditActionRestore, model.AuditEntityStylist, uint(id), nil)
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil || stylist == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stylist)
+}
+
 // CreateSchedule godoc
 // @Summary Create stylist schedule (admin only)
 // @Tags stylists
@@ -231,6 +362,11 @@ func (h *StylistHandler) CreateSchedule(c *gin.Context) {
 		return
 	}
 
+	slot := model.ScheduleTemplateSlot{DayOfWeek: req.DayOfWeek, StartTime: req.StartTime, EndTime: req.EndTime}
+	if !h.checkNewScheduleSlots(c, uint(id), []model.ScheduleTemplateSlot{slot}) {
+		return
+	}
+
 	schedule := &model.StylistSchedule{
 		StylistID: uint(id),
 		DayOfWeek: req.DayOfWeek,
@@ -243,10 +379,118 @@ func (h *StylistHandler) CreateSchedule(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
 		return
 	}
+	h.invalidateSlots(schedule.StylistID)
 
 	c.JSON(http.StatusCreated, schedule)
 }
 
+// CreateSchedulesBulk godoc
+// @Summary Create several schedules for a stylist at once (admin only)
+// @Description Entries may not overlap each other or the stylist's existing schedules on the same day. all_weekdays adds the same range for Monday to Friday.
+// @Tags stylists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param request body BulkScheduleRequest true "Schedules"
+// @Success 201 {array} model.StylistSchedule
+// @Router /admin/stylists/{id}/schedules/bulk [post]
+func (h *StylistHandler) CreateSchedulesBulk(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	var req BulkScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slots := append([]model.ScheduleTemplateSlot{}, req.Schedules...)
+	if req.AllWeekdays != nil {
+		for day := 1; day <= 5; day++ { // 週一至週五
+			slots = append(slots, model.ScheduleTemplateSlot{
+				DayOfWeek: day,
+				StartTime: req.AllWeekdays.StartTime,
+				EndTime:   req.AllWeekdays.EndTime,
+			})
+		}
+	}
+	if len(slots) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "schedules or all_weekdays is required"})
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
+
+	if !h.checkNewScheduleSlots(c, stylist.ID, slots) {
+		return
+	}
+
+	schedules := make([]model.StylistSchedule, 0, len(slots))
+	for _, slot := range slots {
+		schedules = append(schedules, model.StylistSchedule{
+			StylistID: stylist.ID,
+			DayOfWeek: slot.DayOfWeek,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			IsActive:  true,
+		})
+	}
+
+	if err := h.stylistRepo.CreateSchedules(schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedules"})
+		return
+	}
+	h.invalidateSlots(stylist.ID)
+
+	c.JSON(http.StatusCreated, schedules)
+}
+
+// checkNewScheduleSlots validates the format of new slots and that they don't
+// overlap each other or the stylist's existing schedules, writing the error
+// response when they do
+func (h *StylistHandler) checkNewScheduleSlots(c *gin.Context, stylistID uint, slots []model.ScheduleTemplateSlot) bool {
+	if err := model.ValidateScheduleSlots(slots); err != nil {
+		var fieldErr *model.ScheduleFieldError
+		if errors.As(err, &fieldErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": fieldErr.Field})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	existing, err := h.stylistRepo.GetSchedulesByStylistID(stylistID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return false
+	}
+	combined := append([]model.ScheduleTemplateSlot{}, slots...)
+	for _, schedule := range existing {
+		combined = append(combined, model.ScheduleTemplateSlot{
+			DayOfWeek: schedule.DayOfWeek,
+			StartTime: schedule.StartTime,
+			EndTime:   schedule.EndTime,
+		})
+	}
+	if err := model.ValidateScheduleSlots(combined); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Conflicts with an existing schedule: " + err.Error()})
+		return false
+	}
+	return true
+}
+
 // GetSchedules godoc
 // @Summary Get stylist schedules
 // @Tags stylists
@@ -288,14 +532,297 @@ func (h *StylistHandler) DeleteSchedule(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
 		return
 	}
+	// 只有排班 ID，不知道是哪位設計師，整個清掉
+	if h.availability != nil {
+		h.availability.InvalidateAll()
+	}
 
 	c.Status(http.StatusNoContent)
 }
 
-// TimeSlot represents an available time slot
-type TimeSlot struct {
-	Time      string `json:"time"`
-	Available bool   `json:"available"`
+type ApplyScheduleTemplateRequest struct {
+	Template string `json:"template" binding:"required"`
+}
+
+// ApplyScheduleTemplate godoc
+// @Summary Replace a stylist's schedules with a weekly template (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param request body ApplyScheduleTemplateRequest true "Template name"
+// @Success 200 {array} model.StylistSchedule
+// @Router /admin/stylists/{id}/schedules/apply-template [post]
+func (h *StylistHandler) ApplyScheduleTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	var req ApplyScheduleTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
+
+	templates := model.DefaultScheduleTemplates()
+	if _, err := h.settingsRepo.GetValue(model.SettingsKeyScheduleTemplates, &templates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule templates"})
+		return
+	}
+	slots, ok := templates[req.Template]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Schedule template %q not found", req.Template)})
+		return
+	}
+	if err := model.ValidateScheduleSlots(slots); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid schedule template: " + err.Error()})
+		return
+	}
+
+	schedules := make([]model.StylistSchedule, 0, len(slots))
+	for _, slot := range slots {
+		schedules = append(schedules, model.StylistSchedule{
+			StylistID: stylist.ID,
+			DayOfWeek: slot.DayOfWeek,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			IsActive:  true,
+		})
+	}
+
+	if err := h.stylistRepo.ReplaceSchedules(stylist.ID, schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply schedule template"})
+		return
+	}
+	h.invalidateSlots(stylist.ID)
+
+	result, err := h.stylistRepo.GetSchedulesByStylistID(stylist.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// WorkingDay lists a weekday a stylist works and its scheduled time ranges
+type WorkingDay struct {
+	DayOfWeek int                `json:"day_of_week"`
+	Ranges    []WorkingTimeRange `json:"ranges"`
+}
+
+type WorkingTimeRange struct {
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// GetWorkingDays godoc
+// @Summary Get the weekdays a stylist works and their time ranges
+// @Tags stylists
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /stylists/{id}/working-days [get]
+func (h *StylistHandler) GetWorkingDays(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	// Schedules come back ordered by day_of_week, start_time
+	schedules, err := h.stylistRepo.GetSchedulesByStylistID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	days, workingDays := groupWorkingDays(schedules)
+
+	c.JSON(http.StatusOK, gin.H{
+		"stylist_id":   uint(id),
+		"days_of_week": days,
+		"working_days": workingDays,
+	})
+}
+
+// groupWorkingDays groups one stylist's schedules, ordered by day_of_week
+// and start_time, into the weekdays worked and each day's time ranges
+func groupWorkingDays(schedules []model.StylistSchedule) ([]int, []WorkingDay) {
+	days := []int{}
+	workingDays := []WorkingDay{}
+	for _, schedule := range schedules {
+		timeRange := WorkingTimeRange{StartTime: schedule.StartTime, EndTime: schedule.EndTime}
+		if n := len(workingDays); n > 0 && workingDays[n-1].DayOfWeek == schedule.DayOfWeek {
+			workingDays[n-1].Ranges = append(workingDays[n-1].Ranges, timeRange)
+			continue
+		}
+		days = append(days, schedule.DayOfWeek)
+		workingDays = append(workingDays, WorkingDay{
+			DayOfWeek: schedule.DayOfWeek,
+			Ranges:    []WorkingTimeRange{timeRange},
+		})
+	}
+	return days, workingDays
+}
+
+// GetServices godoc
+// @Summary List active services a stylist is qualified to perform
+// @Tags stylists
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Success 200 {array} model.Service
+// @Router /stylists/{id}/services [get]
+func (h *StylistHandler) GetServices(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
+
+	// Until the salon configures any mappings, every stylist is treated as
+	// qualified for every active service
+	qualifiedForAll, err := h.availability.QualifiedForAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+	if qualifiedForAll {
+		services, _, err := h.serviceRepo.List(repository.ServiceListOptions{ActiveOnly: true})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+			return
+		}
+		c.JSON(http.StatusOK, services)
+		return
+	}
+
+	services, err := h.stylistRepo.GetServices(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+type AddStylistServicesRequest struct {
+	ServiceIDs []uint `json:"service_ids" binding:"required,min=1"`
+}
+
+// AddServices godoc
+// @Summary Mark a stylist as qualified for services (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param request body AddStylistServicesRequest true "Service IDs"
+// @Success 200 {array} model.Service
+// @Router /admin/stylists/{id}/services [post]
+func (h *StylistHandler) AddServices(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	var req AddStylistServicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
+
+	for _, serviceID := range req.ServiceIDs {
+		svc, err := h.serviceRepo.GetByID(serviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+			return
+		}
+		if svc == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid service ID: %d", serviceID)})
+			return
+		}
+	}
+
+	if err := h.stylistRepo.AddServices(stylist.ID, req.ServiceIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add services"})
+		return
+	}
+
+	services, err := h.stylistRepo.GetServices(stylist.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+// RemoveService godoc
+// @Summary Remove a service from a stylist's qualifications (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Param id path int true "Stylist ID"
+// @Param serviceId path int true "Service ID"
+// @Success 204
+// @Router /admin/stylists/{id}/services/{serviceId} [delete]
+func (h *StylistHandler) RemoveService(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+	serviceID, err := strconv.ParseUint(c.Param("serviceId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	removed, err := h.stylistRepo.RemoveService(uint(id), uint(serviceID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove service"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist is not mapped to this service"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 // GetAvailableSlots godoc
@@ -305,7 +832,8 @@ type TimeSlot struct {
 // @Param id path int true "Stylist ID"
 // @Param date query string true "Date (YYYY-MM-DD)"
 // @Param duration query int true "Service duration in minutes"
-// @Success 200 {array} TimeSlot
+// @Param exclude_booking_id query int false "Booking being rescheduled, treated as free (owner or admin only)"
+// @Success 200 {array} service.TimeSlot
 // @Router /stylists/{id}/available-slots [get]
 func (h *StylistHandler) GetAvailableSlots(c *gin.Context) {
 	stylistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -338,83 +866,214 @@ func (h *StylistHandler) GetAvailableSlots(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
 		return
 	}
+	if _, ok := checkBookableDate(c, h.availability, date); !ok {
+		return
+	}
+
+	var excludeBookingID *uint
+	if excludeStr := c.Query("exclude_booking_id"); excludeStr != "" {
+		id, err := strconv.ParseUint(excludeStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exclude_booking_id"})
+			return
+		}
+
+		// Only the booking's owner or an admin may see its slot as free, so
+		// nobody can make another customer's booking look cancelled
+		userID, ok := middleware.GetUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required for exclude_booking_id"})
+			return
+		}
+		booking, err := h.bookingRepo.GetByID(uint(id))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			return
+		}
+		role, _ := middleware.GetUserRole(c)
+		if booking == nil || (role != "admin" && booking.UserID != userID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		excludeBookingID = &booking.ID
+	}
+
+	slots, err := h.availability.DaySlots(uint(stylistID), date, duration, excludeBookingID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute available slots"})
+		return
+	}
 
-	// Get day of week (0=Sunday, 6=Saturday)
-	dayOfWeek := int(date.Weekday())
+	c.JSON(http.StatusOK, slots)
+}
 
-	// Get stylist's schedule for this day
-	schedules, err := h.stylistRepo.GetSchedulesByStylistID(uint(stylistID))
+// checkBookableDate responds with 400 and returns false when date is later
+// than the booking.max_advance_days window. It also returns the last
+// bookable date.
+func checkBookableDate(c *gin.Context, availability *service.AvailabilityService, date time.Time) (time.Time, bool) {
+	lastDate, days, err := availability.LastBookableDate()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking settings"})
+		return lastDate, false
+	}
+	if date.After(lastDate) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              fmt.Sprintf("Bookings can be made at most %d days in advance", days),
+			"last_bookable_date": lastDate.Format("2006-01-02"),
+		})
+		return lastDate, false
+	}
+	return lastDate, true
+}
+
+// GetSuggestedSlots godoc
+// @Summary Get a stylist's available slots ranked to minimize idle gaps
+// @Description Slots adjacent to existing bookings or the start/end of a shift score highest (100); the score falls as the surrounding idle time grows.
+// @Tags stylists
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param date query string true "Date (YYYY-MM-DD)"
+// @Param duration query int true "Service duration in minutes"
+// @Success 200 {array} service.ScoredSlot
+// @Router /stylists/{id}/suggested-slots [get]
+func (h *StylistHandler) GetSuggestedSlots(c *gin.Context) {
+	stylistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
 		return
 	}
 
-	// Find schedule for this day of week
-	var daySchedule *model.StylistSchedule
-	for i := range schedules {
-		if schedules[i].DayOfWeek == dayOfWeek && schedules[i].IsActive {
-			daySchedule = &schedules[i]
-			break
-		}
+	date, err := time.Parse("2006-01-02", c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	if _, ok := checkBookableDate(c, h.availability, date); !ok {
+		return
 	}
 
-	// If no schedule for this day, return empty slots
-	if daySchedule == nil {
-		c.JSON(http.StatusOK, []TimeSlot{})
+	duration, err := strconv.Atoi(c.Query("duration"))
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
 		return
 	}
 
-	// Get existing bookings for this stylist on this date
-	var existingBookings []model.Booking
-	if h.bookingRepo != nil {
-		existingBookings, err = h.bookingRepo.GetByStylistAndDateString(uint(stylistID), dateStr)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
-			return
-		}
+	slots, err := h.availability.SuggestedSlots(uint(stylistID), date, duration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute suggested slots"})
+		return
 	}
 
-	// Parse schedule times
-	startTime, _ := time.Parse("15:04", daySchedule.StartTime)
-	endTime, _ := time.Parse("15:04", daySchedule.EndTime)
+	c.JSON(http.StatusOK, slots)
+}
 
-	// Generate time slots (30-minute intervals)
-	var slots []TimeSlot
-	currentTime := startTime
+// GetNextAvailable godoc
+// @Summary Get a stylist's soonest bookable slot
+// @Description Scans forward from today up to the booking.next_available_horizon_days setting. Returns 404 when nothing is open within that horizon.
+// @Tags stylists
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param duration query int true "Service duration in minutes"
+// @Success 200 {object} service.NextSlot
+// @Router /stylists/{id}/next-available [get]
+func (h *StylistHandler) GetNextAvailable(c *gin.Context) {
+	stylistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
 
-	for currentTime.Before(endTime) {
-		timeStr := currentTime.Format("15:04")
+	duration, err := strconv.Atoi(c.Query("duration"))
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+		return
+	}
 
-		// Check if this slot has enough time for the service
-		slotEnd := currentTime.Add(time.Duration(duration) * time.Minute)
-		if slotEnd.After(endTime) {
-			break // Not enough time before end of work day
-		}
+	stylist, err := h.stylistRepo.GetByID(uint(stylistID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil || !stylist.IsActive {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
 
-		// Check if this slot conflicts with existing bookings
-		available := true
-		for _, booking := range existingBookings {
-			if booking.Status == "cancelled" {
-				continue
-			}
-
-			bookingTime, _ := time.Parse("15:04", booking.StartTime)
-			bookingEnd := bookingTime.Add(time.Duration(booking.Duration) * time.Minute)
-
-			// Check for overlap
-			if (currentTime.Before(bookingEnd) && slotEnd.After(bookingTime)) {
-				available = false
-				break
-			}
-		}
+	days, err := h.settingsRepo.GetFloat(model.SettingsKeyNextAvailableHorizonDays, model.DefaultNextAvailableHorizonDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking settings"})
+		return
+	}
+	// 不搜尋超過可預約範圍的日期（今天加上 max_advance_days 天）
+	_, maxAdvanceDays, err := h.availability.LastBookableDate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking settings"})
+		return
+	}
+	if int(days) > maxAdvanceDays+1 {
+		days = float64(maxAdvanceDays + 1)
+	}
 
-		slots = append(slots, TimeSlot{
-			Time:      timeStr,
-			Available: available,
-		})
+	next, err := h.availability.NextAvailable(stylist.ID, duration, int(days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute availability"})
+		return
+	}
+	if next == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No availability within %d days", int(days))})
+		return
+	}
+
+	c.JSON(http.StatusOK, next)
+}
+
+// GetWalkInAvailability godoc
+// @Summary Which active stylists can take a walk-in right now (admin only)
+// @Description A stylist is free when the current salon time is within one of their shifts and no booking, hold or time off covers it. free_until is their next booking or the end of the shift.
+// @Tags stylists
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/walkin-availability [get]
+func (h *StylistHandler) GetWalkInAvailability(c *gin.Context) {
+	stylists, err := h.stylistRepo.List(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylists"})
+		return
+	}
 
-		currentTime = currentTime.Add(30 * time.Minute)
+	walkIns, now, err := h.availability.WalkIns(stylists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute availability"})
+		return
 	}
 
-	c.JSON(http.StatusOK, slots)
+	c.JSON(http.StatusOK, gin.H{
+		"date":     now.Format("2006-01-02"),
+		"time":     now.Format("15:04"),
+		"stylists": walkIns,
+	})
+}
+
+// validatePriceModifier checks the modifier type and that its value is in range
+func validatePriceModifier(modifierType string, value int) error {
+	switch modifierType {
+	case "":
+		if value != 0 {
+			return fmt.Errorf("A price_modifier_type is required when price_modifier_value is set")
+		}
+	case model.PriceModifierPercent:
+		if value < model.MinPriceModifierPercent || value > model.MaxPriceModifierPercent {
+			return fmt.Errorf("Percent price modifier must be between %d and %d",
+				model.MinPriceModifierPercent, model.MaxPriceModifierPercent)
+		}
+	case model.PriceModifierFlat:
+		if value < model.MinPriceModifierFlat || value > model.MaxPriceModifierFlat {
+			return fmt.Errorf("Flat price modifier must be between %d and %d",
+				model.MinPriceModifierFlat, model.MaxPriceModifierFlat)
+		}
+	default:
+		return fmt.Errorf("Invalid price_modifier_type, use percent or flat")
+	}
+	return nil
 }
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/service"
+	"linda-salon-api/internal/testutil"
+)
+
+// exclude_booking_id is refused before any slots are computed unless the
+// caller owns the booking or is an admin
+func TestGetAvailableSlotsExcludeBookingAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   uint // 0 for an anonymous caller
+		role     string
+		wantCode int
+	}{
+		{"anonymous", 0, "", http.StatusUnauthorized},
+		{"another customer", 8, "customer", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testutil.MockDB(t)
+			bookingRepo := repository.NewBookingRepository(db)
+			settingsRepo := repository.NewSettingsRepository(db)
+			availability := service.NewAvailabilityService(nil, bookingRepo, nil, settingsRepo, time.UTC)
+			h := NewStylistHandlerWithBooking(nil, bookingRepo, nil, settingsRepo, availability, nil)
+
+			mock.ExpectQuery(`SELECT \* FROM "settings"`).WillReturnRows(sqlmock.NewRows([]string{"key", "value"}))
+			if tt.userID != 0 {
+				mock.ExpectQuery(`SELECT \* FROM "bookings"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "stylist_id"}).AddRow(42, 7, 2))
+				mock.ExpectQuery(`SELECT \* FROM "stylists"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			}
+
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			date := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+			c.Request = httptest.NewRequest(http.MethodGet, "/stylists/2/available-slots?date="+date+"&duration=60&exclude_booking_id=42", nil)
+			c.Params = gin.Params{{Key: "id", Value: "2"}}
+			if tt.userID != 0 {
+				c.Set(middleware.UserIDKey, tt.userID)
+				c.Set(middleware.UserRoleKey, tt.role)
+			}
+
+			h.GetAvailableSlots(c)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/model"
+)
+
+// maxTimeOffListDays 查詢休假清單的最大天數
+const maxTimeOffListDays = 366
+
+type TimeOffRequest struct {
+	Date      string `json:"date" binding:"required"` // YYYY-MM-DD
+	StartTime string `json:"start_time"`              // HH:MM，整天休假時留空
+	EndTime   string `json:"end_time"`                // HH:MM，整天休假時留空
+	Reason    string `json:"reason" binding:"max=200"`
+}
+
+// ListTimeOff godoc
+// @Summary List a stylist's time off (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param from query string false "Start date (YYYY-MM-DD), defaults to today"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to 90 days after from"
+// @Success 200 {array} model.StylistTimeOff
+// @Router /admin/stylists/{id}/time-off [get]
+func (h *StylistHandler) ListTimeOff(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	from := h.availability.Today()
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 90)
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxTimeOffListDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be on or after from and within a year of it"})
+		return
+	}
+
+	timeOff, err := h.stylistRepo.GetTimeOffByStylistsAndDateRange([]uint{uint(id)}, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time off"})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeOff)
+}
+
+// CreateTimeOff godoc
+// @Summary Add a day off or a partial-day block for a stylist (admin only)
+// @Description Leave start_time and end_time empty for a full day. Existing bookings are not touched.
+// @Tags stylists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param request body TimeOffRequest true "Time off"
+// @Success 201 {object} model.StylistTimeOff
+// @Router /admin/stylists/{id}/time-off [post]
+func (h *StylistHandler) CreateTimeOff(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return
+	}
+
+	stylist, err := h.stylistRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stylist"})
+		return
+	}
+	if stylist == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stylist not found"})
+		return
+	}
+
+	var req TimeOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timeOff := &model.StylistTimeOff{StylistID: stylist.ID}
+	if !applyTimeOffRequest(c, timeOff, &req) {
+		return
+	}
+
+	if err := h.stylistRepo.CreateTimeOff(timeOff); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create time off"})
+		return
+	}
+	h.invalidateSlots(timeOff.StylistID)
+
+	c.JSON(http.StatusCreated, timeOff)
+}
+
+// UpdateTimeOff godoc
+// @Summary Update a stylist's time off (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Stylist ID"
+// @Param timeOffId path int true "Time off ID"
+// @Param request body TimeOffRequest true "Time off"
+// @Success 200 {object} model.StylistTimeOff
+// @Router /admin/stylists/{id}/time-off/{timeOffId} [put]
+func (h *StylistHandler) UpdateTimeOff(c *gin.Context) {
+	timeOff, ok := h.loadTimeOff(c)
+	if !ok {
+		return
+	}
+
+	var req TimeOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !applyTimeOffRequest(c, timeOff, &req) {
+		return
+	}
+
+	if err := h.stylistRepo.UpdateTimeOff(timeOff); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update time off"})
+		return
+	}
+	h.invalidateSlots(timeOff.StylistID)
+
+	c.JSON(http.StatusOK, timeOff)
+}
+
+// DeleteTimeOff godoc
+// @Summary Delete a stylist's time off (admin only)
+// @Tags stylists
+// @Security BearerAuth
+// @Param id path int true "Stylist ID"
+// @Param timeOffId path int true "Time off ID"
+// @Success 204
+// @Router /admin/stylists/{id}/time-off/{timeOffId} [delete]
+func (h *StylistHandler) DeleteTimeOff(c *gin.Context) {
+	timeOff, ok := h.loadTimeOff(c)
+	if !ok {
+		return
+	}
+
+	if err := h.stylistRepo.DeleteTimeOff(timeOff.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete time off"})
+		return
+	}
+	h.invalidateSlots(timeOff.StylistID)
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadTimeOff fetches the :timeOffId entry, which must belong to the :id stylist
+func (h *StylistHandler) loadTimeOff(c *gin.Context) (*model.StylistTimeOff, bool) {
+	stylistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stylist ID"})
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("timeOffId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time off ID"})
+		return nil, false
+	}
+
+	timeOff, err := h.stylistRepo.GetTimeOff(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time off"})
+		return nil, false
+	}
+	if timeOff == nil || timeOff.StylistID != uint(stylistID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time off not found"})
+		return nil, false
+	}
+	return timeOff, true
+}
+
+// applyTimeOffRequest copies req onto timeOff and validates it
+func applyTimeOffRequest(c *gin.Context, timeOff *model.StylistTimeOff, req *TimeOffRequest) bool {
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return false
+	}
+
+	timeOff.Date = date
+	timeOff.StartTime = strings.TrimSpace(req.StartTime)
+	timeOff.EndTime = strings.TrimSpace(req.EndTime)
+	timeOff.Reason = strings.TrimSpace(req.Reason)
+
+	if err := timeOff.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
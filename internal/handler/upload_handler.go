@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,22 +18,75 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"linda-salon-api/config"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/service"
 )
 
+// presignExpiry is how long a presigned upload URL stays valid
+const presignExpiry = 15 * time.Minute
+
+// uploadContentTypes maps each allowed image extension to its content type.
+// HEIC/HEIF photos are converted to JPEG before they're stored.
+var uploadContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// presignExtensions is the extension given to a presigned upload of each
+// allowed content type. HEIC isn't offered: direct uploads skip the
+// server, so it couldn't be converted.
+var presignExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// uploadFolders are the folders images can be uploaded to
+var uploadFolders = map[string]bool{
+	"services":    true,
+	"stylists":    true,
+	"avatars":     true,
+	"uploads":     true,
+	"icons":       true,
+	"logos":       true,
+	"screenshots": true,
+}
+
+// PresignUploadRequest describes the image a client is about to upload
+type PresignUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"` // image/jpeg, image/png or image/webp
+	Size        int64  `json:"size" binding:"required,min=1"`   // bytes; the upload must be exactly this size
+	Folder      string `json:"folder"`
+}
+
 type UploadHandler struct {
-	s3Client *s3.Client
-	cfg      *config.AWSConfig
+	s3Client  *s3.Client
+	cfg       *config.AWSConfig
+	uploadCfg *config.UploadConfig
 }
 
-func NewUploadHandler(s3Client *s3.Client, cfg *config.AWSConfig) *UploadHandler {
+func NewUploadHandler(s3Client *s3.Client, cfg *config.AWSConfig, uploadCfg *config.UploadConfig) *UploadHandler {
 	return &UploadHandler{
-		s3Client: s3Client,
-		cfg:      cfg,
+		s3Client:  s3Client,
+		cfg:       cfg,
+		uploadCfg: uploadCfg,
 	}
 }
 
 // UploadImage godoc
 // @Summary Upload an image to S3
+// @Description Also stores a thumbnail (longest edge UPLOAD_THUMBNAIL_SIZE pixels) under
+// @Description the thumb/ prefix. thumbnail_url is the original's URL when the image is
+// @Description already small enough or can't be decoded. Files over the folder's size
+// @Description limit get a 400 with max_bytes; avatars below UPLOAD_AVATAR_MIN_DIMENSION
+// @Description pixels get a 400 with min_dimension.
 // @Tags upload
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -46,56 +104,73 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 
 	// Validate file type
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".webp": true,
-	}
-	if !allowedExts[ext] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only JPG, PNG, and WEBP are allowed"})
+	contentType, ok := uploadContentTypes[ext]
+	if !ok || !h.uploadCfg.ExtensionAllowed(ext) {
+		allowed := h.allowedExtensions()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Invalid file type. Allowed types: " + strings.Join(allowed, ", "),
+			"allowed_extensions": allowed,
+		})
 		return
 	}
-
-	// Validate file size (max 5MB)
-	if file.Size > 5*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size exceeds 5MB limit"})
+	heic := ext == ".heic" || ext == ".heif"
+	if heic && !service.HEICSupported() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "HEIC images are not supported, please upload a JPG or PNG"})
 		return
 	}
 
 	// Get folder from query or default to 'uploads'
-	folder := c.DefaultQuery("folder", "uploads")
-	validFolders := map[string]bool{
-		"services":    true,
-		"stylists":    true,
-		"avatars":     true,
-		"uploads":     true,
-		"icons":       true,
-		"logos":       true,
-		"screenshots": true,
-	}
-	if !validFolders[folder] {
-		folder = "uploads"
-	}
+	folder := uploadFolder(c.Query("folder"))
 
-	// Generate unique filename
-	uniqueID := uuid.New().String()
-	filename := fmt.Sprintf("%s/%s%s", folder, uniqueID, ext)
+	// Validate file size against the folder's limit
+	if !h.checkSize(c, folder, file.Size) {
+		return
+	}
 
-	// Open file
+	// Read the file (within the size limit) so it can be both uploaded and thumbnailed
 	fileContent, err := file.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
 		return
 	}
-	defer fileContent.Close()
+	data, err := io.ReadAll(fileContent)
+	fileContent.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	// Browsers other than Safari can't display HEIC, so store it as JPEG
+	if heic {
+		data, err = service.ConvertHEICToJPEG(data)
+		if err != nil {
+			log.Printf("⚠️  Failed to convert HEIC upload %s: %v", file.Filename, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the HEIC image"})
+			return
+		}
+		ext, contentType = ".jpg", "image/jpeg"
+	}
 
-	// Determine content type
-	contentType := "image/jpeg"
-	if ext == ".png" {
-		contentType = "image/png"
-	} else if ext == ".webp" {
-		contentType = "image/webp"
+	// Reject avatars too small to display, or that aren't valid images at all
+	if minDimension := h.uploadCfg.MinDimensionFor(folder); minDimension > 0 {
+		width, height, err := service.ImageDimensions(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the image, the file may be corrupted"})
+			return
+		}
+		if width < minDimension || height < minDimension {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         fmt.Sprintf("Image must be at least %dx%d pixels", minDimension, minDimension),
+				"min_dimension": minDimension,
+			})
+			return
+		}
+	}
+
+	// Generate unique filename
+	filename, ok := objectKey(c, folder, ext)
+	if !ok {
+		return
 	}
 
 	// Upload to S3
@@ -105,7 +180,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	_, err = h.s3Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(h.cfg.S3Bucket),
 		Key:         aws.String(filename),
-		Body:        fileContent,
+		Body:        bytes.NewReader(data),
 		ContentType: aws.String(contentType),
 		ACL:         "public-read",
 	})
@@ -118,33 +193,268 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	}
 
 	// Generate URL
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
-		h.cfg.S3Bucket,
-		h.cfg.Region,
-		filename)
+	url := h.objectURL(filename)
+
+	thumbnailURL := url
+	if key, ok := h.uploadThumbnail(ctx, filename, data); ok {
+		thumbnailURL = h.objectURL(key)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":           url,
+		"thumbnail_url": thumbnailURL,
+		"filename":      filename,
+		"folder":        folder,
+	})
+}
+
+// PresignUpload godoc
+// @Summary Get a presigned S3 URL to upload an image directly
+// @Description The client PUTs the file to upload_url with the returned headers; the
+// @Description signature covers the content type and exact size, so S3 rejects anything
+// @Description else. No thumbnail is generated, so thumbnail_url is the image's own URL.
+// @Tags upload
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PresignUploadRequest true "Image to upload"
+// @Success 200 {object} map[string]interface{}
+// @Router /upload/presign [post]
+func (h *UploadHandler) PresignUpload(c *gin.Context) {
+	var req PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ext, ok := presignExtensions[req.ContentType]
+	if !ok || !h.contentTypeAllowed(req.ContentType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Invalid content type",
+			"allowed_extensions": h.allowedExtensions(),
+		})
+		return
+	}
+
+	folder := uploadFolder(req.Folder)
+	if !h.checkSize(c, folder, req.Size) {
+		return
+	}
+	filename, ok := objectKey(c, folder, ext)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	presigned, err := s3.NewPresignClient(h.s3Client).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(h.cfg.S3Bucket),
+		Key:           aws.String(filename),
+		ContentType:   aws.String(req.ContentType),
+		ContentLength: req.Size,
+		ACL:           "public-read",
+	}, s3.WithPresignExpires(presignExpiry))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to presign upload"})
+		return
+	}
+
+	// Host and Content-Length are set by the client's HTTP library
+	headers := make(map[string]string, len(presigned.SignedHeader))
+	for name, values := range presigned.SignedHeader {
+		if name == "Host" || name == "Content-Length" || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
 
+	url := h.objectURL(filename)
 	c.JSON(http.StatusOK, gin.H{
-		"url":      url,
-		"filename": filename,
-		"folder":   folder,
+		"upload_url":    presigned.URL,
+		"method":        presigned.Method,
+		"headers":       headers,
+		"expires_at":    time.Now().Add(presignExpiry).UTC(),
+		"url":           url,
+		"thumbnail_url": url,
+		"filename":      filename,
+		"folder":        folder,
+	})
+}
+
+// checkSize rejects a file larger than the folder's size limit, including
+// the limit in the response so the client can show it
+func (h *UploadHandler) checkSize(c *gin.Context, folder string, size int64) bool {
+	limit := h.uploadCfg.MaxBytesFor(folder)
+	if size <= limit {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":     fmt.Sprintf("File size exceeds %s limit", formatBytes(limit)),
+		"max_bytes": limit,
 	})
+	return false
+}
+
+// allowedExtensions lists the extensions that may be uploaded, sorted
+func (h *UploadHandler) allowedExtensions() []string {
+	allowed := make([]string, 0, len(uploadContentTypes))
+	for ext := range uploadContentTypes {
+		if h.uploadCfg.ExtensionAllowed(ext) {
+			allowed = append(allowed, ext)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// contentTypeAllowed reports whether any allowed extension has contentType
+func (h *UploadHandler) contentTypeAllowed(contentType string) bool {
+	for ext, ct := range uploadContentTypes {
+		if ct == contentType && h.uploadCfg.ExtensionAllowed(ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes formats a size limit for error messages, e.g. "5MB" or "512KB"
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024 && n%(1024*1024) == 0:
+		return fmt.Sprintf("%dMB", n/(1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	case n >= 1024 && n%1024 == 0:
+		return fmt.Sprintf("%dKB", n/1024)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// objectKey generates a unique S3 key for an upload to folder. Avatars go
+// under the uploader's own avatars/<userID>/ prefix, so a user can only
+// point their profile at (and later delete) images they uploaded.
+func objectKey(c *gin.Context, folder, ext string) (string, bool) {
+	if folder != "avatars" {
+		return fmt.Sprintf("%s/%s%s", folder, uuid.New().String(), ext), true
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	return service.AvatarKeyPrefix(userID) + uuid.New().String() + ext, true
+}
+
+// uploadFolder returns folder if it's an allowed upload folder, otherwise
+// the default "uploads"
+func uploadFolder(folder string) string {
+	if !uploadFolders[folder] {
+		return "uploads"
+	}
+	return folder
+}
+
+// isUploadKey reports whether key names an image uploaded through this
+// handler, i.e. a file directly in one of the uploadFolders. Avatars may also
+// sit in their user's subfolder. Thumbnails and anything else in the bucket
+// are never deleted directly.
+func isUploadKey(key string) bool {
+	if strings.Contains(key, "..") || strings.HasSuffix(key, "/") {
+		return false
+	}
+	parts := strings.Split(key, "/")
+	if !uploadFolders[parts[0]] {
+		return false
+	}
+	if parts[0] == "avatars" && len(parts) == 3 {
+		return parts[1] != "" && parts[2] != ""
+	}
+	return len(parts) == 2 && parts[1] != ""
+}
+
+// uploadThumbnail stores a scaled-down copy of an uploaded image under the
+// thumb/ prefix and returns its key. A thumbnail is a nice-to-have: when the
+// image is already small, can't be decoded or the upload fails, it returns
+// false and the original is used as its own thumbnail.
+func (h *UploadHandler) uploadThumbnail(ctx context.Context, filename string, data []byte) (string, bool) {
+	thumb, contentType, err := service.MakeThumbnail(data, h.uploadCfg.ThumbnailSize)
+	if err != nil {
+		if !errors.Is(err, service.ErrThumbnailNotNeeded) {
+			log.Printf("⚠️  Skipping thumbnail for %s: %v", filename, err)
+		}
+		return "", false
+	}
+
+	key := service.ThumbnailKey(filename)
+	_, err = h.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(h.cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(thumb),
+		ContentType: aws.String(contentType),
+		ACL:         "public-read",
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to upload thumbnail for %s: %v", filename, err)
+		return "", false
+	}
+	return key, true
+}
+
+// objectURL returns the public URL of an object in the upload bucket
+func (h *UploadHandler) objectURL(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
+		h.cfg.S3Bucket,
+		h.cfg.Region,
+		key)
+}
+
+// DeleteImageRequest names the image to delete by its public URL or its S3
+// key; filename is the older name for key
+type DeleteImageRequest struct {
+	URL      string `json:"url"`
+	Key      string `json:"key"`
+	Filename string `json:"filename"`
 }
 
 // DeleteImage godoc
 // @Summary Delete an image from S3 (admin only)
+// @Description Give either the image's url (as returned by the upload endpoints) or its key.
 // @Tags upload
 // @Security BearerAuth
 // @Accept json
 // @Produce json
-// @Param request body map[string]string true "Image filename"
+// @Param request body DeleteImageRequest true "Image URL or key"
 // @Success 200 {object} map[string]string
 // @Router /upload/image [delete]
 func (h *UploadHandler) DeleteImage(c *gin.Context) {
-	var req struct {
-		Filename string `json:"filename" binding:"required"`
-	}
+	var req DeleteImageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	key := strings.TrimSpace(req.Key)
+	if key == "" {
+		key = strings.TrimSpace(req.Filename)
+	}
+	url := strings.TrimSpace(req.URL)
+	switch {
+	case url != "" && key != "":
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Give either url or key, not both")
+		return
+	case url != "":
+		key = service.S3KeyFromURL(h.cfg.S3Bucket, url)
+		if key == "" {
+			respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, "URL does not belong to the upload bucket")
+			return
+		}
+	case key == "":
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, "url or key is required")
+		return
+	}
+	if !isUploadKey(key) {
+		respondError(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Only images in the upload folders can be deleted")
 		return
 	}
 
@@ -153,17 +463,24 @@ func (h *UploadHandler) DeleteImage(c *gin.Context) {
 
 	_, err := h.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(h.cfg.S3Bucket),
-		Key:    aws.String(req.Filename),
+		Key:    aws.String(key),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to delete from S3",
-			"details": err.Error(),
-		})
+		log.Printf("❌ Failed to delete %s from S3: %v", key, err)
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete from S3")
 		return
 	}
 
+	// Deleting a thumbnail that was never generated is a no-op in S3
+	if _, err := h.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(h.cfg.S3Bucket),
+		Key:    aws.String(service.ThumbnailKey(key)),
+	}); err != nil {
+		log.Printf("⚠️  Failed to delete thumbnail for %s: %v", key, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Image deleted successfully",
+		"key":     key,
 	})
 }
@@ -0,0 +1,29 @@
+package handler
+
+import "testing"
+
+func TestIsUploadKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"services/3f2b.jpg", true},
+		{"avatars/7/3f2b.jpg", true},
+		{"avatars/3f2b.jpg", true},
+		{"thumb/services/3f2b.jpg", false},
+		{"backups/db.sql", false},
+		{"3f2b.jpg", false},
+		{"services/", false},
+		{"services/nested/3f2b.jpg", false},
+		{"avatars//3f2b.jpg", false},
+		{"services/../backups/db.sql", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := isUploadKey(tt.key); got != tt.want {
+				t.Errorf("isUploadKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,25 +1,39 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
 	"linda-salon-api/internal/repository"
 )
 
 type UserHandler struct {
 	userRepo    *repository.UserRepository
 	bookingRepo *repository.BookingRepository
+	loyaltyRepo *repository.LoyaltyRepository
+	auditRepo   *repository.AuditLogRepository
 }
 
-func NewUserHandler(userRepo *repository.UserRepository, bookingRepo *repository.BookingRepository) *UserHandler {
+func NewUserHandler(userRepo *repository.UserRepository, bookingRepo *repository.BookingRepository, loyaltyRepo *repository.LoyaltyRepository, auditRepo *repository.AuditLogRepository) *UserHandler {
 	return &UserHandler{
 		userRepo:    userRepo,
 		bookingRepo: bookingRepo,
+		loyaltyRepo: loyaltyRepo,
+		auditRepo:   auditRepo,
 	}
 }
 
+// AdjustLoyaltyRequest is a manual loyalty point adjustment
+type AdjustLoyaltyRequest struct {
+	Points int    `json:"points" binding:"required"` // 正數為加點，負數為扣點，不可為 0
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
 // ListUsers godoc
 // @Summary List all users (admin only)
 // @Tags users
@@ -30,8 +44,11 @@ func NewUserHandler(userRepo *repository.UserRepository, bookingRepo *repository
 // @Success 200 {object} map[string]interface{}
 // @Router /admin/users [get]
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, offset, err := queryPagination(c, 20, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	users, total, err := h.userRepo.List(limit, offset)
 	if err != nil {
@@ -94,3 +111,219 @@ func (h *UserHandler) GetUserBookings(c *gin.Context) {
 
 	c.JSON(http.StatusOK, bookings)
 }
+
+// GetUserLifetimeValue godoc
+// @Summary Get a customer's lifetime value (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} repository.CustomerLifetimeValue
+// @Router /admin/users/{id}/ltv [get]
+func (h *UserHandler) GetUserLifetimeValue(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	ltv, err := h.bookingRepo.GetCustomerLifetimeValue(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute lifetime value"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ltv)
+}
+
+// ListBlockedUsers godoc
+// @Summary List blocked users (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Limit" default(20)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/blocked [get]
+func (h *UserHandler) ListBlockedUsers(c *gin.Context) {
+	limit, offset, err := queryPagination(c, 20, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, total, err := h.userRepo.ListBlocked(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// BlockUser godoc
+// @Summary Block a user from making bookings (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body map[string]string false "Block reason"
+// @Success 200 {object} model.User
+// @Router /admin/users/{id}/block [post]
+func (h *UserHandler) BlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.IsAdmin() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admin accounts cannot be blocked"})
+		return
+	}
+
+	if err := h.userRepo.SetBlocked(user.ID, true, req.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, model.AuditActionBlock, model.AuditEntityUser, user.ID, map[string]interface{}{
+		"is_blocked":   gin.H{"from": user.IsBlocked, "to": true},
+		"block_reason": gin.H{"from": user.BlockReason, "to": req.Reason},
+	})
+
+	user, _ = h.userRepo.GetByID(user.ID)
+	c.JSON(http.StatusOK, user)
+}
+
+// UnblockUser godoc
+// @Summary Unblock a user (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User
+// @Router /admin/users/{id}/unblock [post]
+func (h *UserHandler) UnblockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.userRepo.SetBlocked(user.ID, false, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, model.AuditActionUnblock, model.AuditEntityUser, user.ID, map[string]interface{}{
+		"is_blocked":   gin.H{"from": user.IsBlocked, "to": false},
+		"block_reason": gin.H{"from": user.BlockReason, "to": ""},
+	})
+
+	user, _ = h.userRepo.GetByID(user.ID)
+	c.JSON(http.StatusOK, user)
+}
+
+// AdjustLoyaltyPoints godoc
+// @Summary Add or deduct a customer's loyalty points by hand (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body AdjustLoyaltyRequest true "Points (negative to deduct) and reason"
+// @Success 201 {object} model.LoyaltyEntry
+// @Router /admin/users/{id}/loyalty [post]
+func (h *UserHandler) AdjustLoyaltyPoints(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req AdjustLoyaltyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	actorID, _ := middleware.GetUserID(c)
+	entry, err := h.loyaltyRepo.Adjust(user.ID, req.Points, reason, actorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientPoints) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Not enough points", "balance": user.LoyaltyPoints})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust loyalty points"})
+		return
+	}
+
+	recordAudit(c, h.auditRepo, model.AuditActionLoyaltyAdjust, model.AuditEntityUser, user.ID, map[string]interface{}{
+		"loyalty_points": gin.H{"from": entry.Balance - entry.Points, "to": entry.Balance},
+		"points":         entry.Points,
+		"reason":         reason,
+	})
+
+	c.JSON(http.StatusCreated, entry)
+}
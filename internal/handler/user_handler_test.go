@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+
+	"linda-salon-api/internal/middleware"
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/testutil"
+)
+
+// newAdminContext returns a gin context for a request made by admin adminID
+func newAdminContext(method, target, body string, adminID uint) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(middleware.UserIDKey, adminID)
+	return c, w
+}
+
+// expectAuditInsert expects one audit log entry by actorID
+func expectAuditInsert(mock sqlmock.Sqlmock, actorID uint, action, entityType string, entityID uint) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(sqlmock.AnyArg(), actorID, action, entityType, entityID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+func TestBlockUnblockUserRecordsAudit(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		action string
+		call   func(h *UserHandler, c *gin.Context)
+	}{
+		{"block", "/admin/users/7/block", `{"reason":"no-shows"}`, model.AuditActionBlock, (*UserHandler).BlockUser},
+		{"unblock", "/admin/users/7/unblock", "", model.AuditActionUnblock, (*UserHandler).UnblockUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testutil.MockDB(t)
+			h := NewUserHandler(repository.NewUserRepository(db), nil, nil, repository.NewAuditLogRepository(db))
+			userRows := func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"id", "role"}).AddRow(7, "customer")
+			}
+
+			mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(userRows())
+			mock.ExpectBegin()
+			mock.ExpectExec(`UPDATE "users" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			expectAuditInsert(mock, 1, tt.action, model.AuditEntityUser, 7)
+			mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(userRows())
+
+			c, w := newAdminContext(http.MethodPost, tt.path, tt.body, 1)
+			c.Params = gin.Params{{Key: "id", Value: "7"}}
+			tt.call(h, c)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestAdjustLoyaltyPointsRecordsAudit(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	h := NewUserHandler(repository.NewUserRepository(db), nil, repository.NewLoyaltyRepository(db), repository.NewAuditLogRepository(db))
+
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role", "loyalty_points"}).AddRow(7, "customer", 100))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE users\s+SET loyalty_points = loyalty_points \+ \$1`).WithArgs(-30, 7, -30).
+		WillReturnRows(sqlmock.NewRows([]string{"loyalty_points"}).AddRow(70))
+	mock.ExpectQuery(`INSERT INTO "loyalty_entries"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	expectAuditInsert(mock, 1, model.AuditActionLoyaltyAdjust, model.AuditEntityUser, 7)
+
+	c, w := newAdminContext(http.MethodPost, "/admin/users/7/loyalty", `{"points":-30,"reason":"redeemed"}`, 1)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+	h.AdjustLoyaltyPoints(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+}
@@ -18,7 +18,7 @@ const (
 
 func AuthRequired(jwtManager *auth.JWTManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := extractToken(c)
+		token := ExtractToken(c)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Authorization token required",
@@ -45,9 +45,24 @@ func AuthRequired(jwtManager *auth.JWTManager) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuth sets the user info in the context like AuthRequired when the
+// request carries a valid token, and otherwise lets it through anonymously
+func OptionalAuth(jwtManager *auth.JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := ExtractToken(c); token != "" {
+			if claims, err := jwtManager.ValidateToken(token); err == nil {
+				c.Set(UserIDKey, claims.UserID)
+				c.Set(UserEmailKey, claims.Email)
+				c.Set(UserRoleKey, claims.Role)
+			}
+		}
+		c.Next()
+	}
+}
+
 func AdminRequired(jwtManager *auth.JWTManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := extractToken(c)
+		token := ExtractToken(c)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Authorization token required",
@@ -82,7 +97,9 @@ func AdminRequired(jwtManager *auth.JWTManager) gin.HandlerFunc {
 	}
 }
 
-func extractToken(c *gin.Context) string {
+// ExtractToken returns the bearer token from the Authorization header or
+// the access_token cookie
+func ExtractToken(c *gin.Context) string {
 	// Try to get token from Authorization header first
 	authHeader := c.GetHeader(AuthorizationHeader)
 	if authHeader != "" && strings.HasPrefix(authHeader, BearerPrefix) {
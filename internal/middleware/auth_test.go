@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/config"
+	"linda-salon-api/internal/auth"
+)
+
+func TestOptionalAuth(t *testing.T) {
+	jwtManager := auth.NewJWTManager(&config.JWTConfig{Secret: "test-secret", Expiration: time.Hour}, auth.NewMemoryBlacklist(time.Hour, 0))
+	pair, err := jwtManager.GenerateTokenPair(7, "customer@example.com", "customer")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantUserID uint
+		wantOK     bool
+	}{
+		{"valid token", BearerPrefix + pair.AccessToken, 7, true},
+		{"no token", "", 0, false},
+		{"invalid token", BearerPrefix + "not-a-token", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			var userID uint
+			var ok bool
+			router.GET("/", OptionalAuth(jwtManager), func(c *gin.Context) {
+				userID, ok = GetUserID(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(AuthorizationHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", w.Code)
+			}
+			if ok != tt.wantOK || userID != tt.wantUserID {
+				t.Errorf("GetUserID() = %d, %v, want %d, %v", userID, ok, tt.wantUserID, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,13 +1,42 @@
 package middleware
 
 import (
+	"encoding/json"
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Logger() gin.HandlerFunc {
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// requestLogEntry is what gets logged for each request, whatever the format
+type requestLogEntry struct {
+	Time      time.Time
+	Status    int
+	Latency   time.Duration
+	ClientIP  string
+	Method    string
+	Path      string
+	RequestID string
+	Bytes     int
+}
+
+// jsonLogger writes bare JSON lines, without the standard logger's date prefix
+var jsonLogger = log.New(os.Stderr, "", 0)
+
+// Logger logs one line per request, in the given LOG_FORMAT (text or json)
+func Logger(format string) gin.HandlerFunc {
+	emit := logText
+	if format == LogFormatJSON {
+		emit = logJSON
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -15,22 +44,62 @@ func Logger() gin.HandlerFunc {
 
 		c.Next()
 
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		method := c.Request.Method
-		clientIP := c.ClientIP()
-
 		if raw != "" {
 			path = path + "?" + raw
 		}
 
-		log.Printf("[%s] %d | %13v | %15s | %-7s %s",
-			time.Now().Format("2006-01-02 15:04:05"),
-			statusCode,
-			latency,
-			clientIP,
-			method,
-			path,
-		)
+		bytes := c.Writer.Size()
+		if bytes < 0 {
+			bytes = 0
+		}
+		emit(requestLogEntry{
+			Time:      time.Now(),
+			Status:    c.Writer.Status(),
+			Latency:   time.Since(start),
+			ClientIP:  c.ClientIP(),
+			Method:    c.Request.Method,
+			Path:      path,
+			RequestID: GetRequestID(c),
+			Bytes:     bytes,
+		})
+	}
+}
+
+func logText(e requestLogEntry) {
+	log.Printf("[%s] %d | %13v | %15s | %-7s %s | %s",
+		e.Time.Format("2006-01-02 15:04:05"),
+		e.Status,
+		e.Latency,
+		e.ClientIP,
+		e.Method,
+		e.Path,
+		e.RequestID,
+	)
+}
+
+func logJSON(e requestLogEntry) {
+	line, err := json.Marshal(struct {
+		TS        string  `json:"ts"`
+		Status    int     `json:"status"`
+		LatencyMS float64 `json:"latency_ms"`
+		ClientIP  string  `json:"client_ip"`
+		Method    string  `json:"method"`
+		Path      string  `json:"path"`
+		RequestID string  `json:"request_id"`
+		Bytes     int     `json:"bytes"`
+	}{
+		TS:        e.Time.UTC().Format(time.RFC3339Nano),
+		Status:    e.Status,
+		LatencyMS: float64(e.Latency.Microseconds()) / 1000,
+		ClientIP:  e.ClientIP,
+		Method:    e.Method,
+		Path:      e.Path,
+		RequestID: e.RequestID,
+		Bytes:     e.Bytes,
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to encode request log: %v", err)
+		return
 	}
+	jsonLogger.Println(string(line))
 }
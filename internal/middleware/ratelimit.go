@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+)
+
+// Limiter decides whether a request identified by key may proceed. When it
+// may not, it returns how long until the key is allowed again.
+type Limiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+// RateLimiter is a fixed-window request counter keyed by the authenticated
+// user, or the client IP for anonymous requests. Expired windows are dropped
+// by a background cleanup once per window.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter allows limit requests per key per window and starts the
+// expired-window cleanup
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	l := &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+	go func() {
+		for range time.Tick(window) {
+			l.cleanup(time.Now())
+		}
+	}()
+	return l
+}
+
+// Allow records a request for key and reports whether it is within the limit.
+// When it isn't, the time until the window resets is returned.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, l.window - now.Sub(w.start)
+	}
+	w.count++
+	return true, 0
+}
+
+// cleanup drops windows that have expired, which are the same as having no
+// window at all
+func (l *RateLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// TokenBucketLimiter gives each key a bucket of perMinute tokens that refills
+// continuously, so short bursts are allowed but the sustained rate is capped.
+// Buckets that have refilled completely carry no state and are dropped by a
+// background cleanup every bucketCleanupInterval.
+type TokenBucketLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+const bucketCleanupInterval = time.Minute
+
+// NewTokenBucketLimiter allows perMinute requests per key per minute, with
+// bursts of up to perMinute, and starts the idle-bucket cleanup
+func NewTokenBucketLimiter(perMinute int) *TokenBucketLimiter {
+	l := &TokenBucketLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		buckets: make(map[string]*tokenBucket),
+	}
+	go func() {
+		for range time.Tick(bucketCleanupInterval) {
+			l.cleanup(time.Now())
+		}
+	}()
+	return l
+}
+
+// Allow takes a token from key's bucket. When it's empty, the time until the
+// next token is returned.
+func (l *TokenBucketLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// cleanup drops buckets that would be full by now, which are the same as
+// having no bucket at all
+func (l *TokenBucketLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit rejects requests over the limiter's budget with 429 RATE_LIMITED
+func RateLimit(limiter Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.ClientIP()
+		if userID, ok := GetUserID(c); ok {
+			key = fmt.Sprintf("user:%d", userID)
+		}
+
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			apierror.Abort(c, http.StatusTooManyRequests,
+				apierror.New(apierror.CodeRateLimited, "Too many requests, please try again later"))
+			return
+		}
+
+		c.Next()
+	}
+}
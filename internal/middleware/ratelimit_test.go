@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+	"linda-salon-api/internal/requestid"
+)
+
+func TestRateLimiterCleanup(t *testing.T) {
+	l := &RateLimiter{limit: 1, window: time.Minute, windows: make(map[string]*rateWindow)}
+	now := time.Now()
+	l.windows["expired"] = &rateWindow{start: now.Add(-2 * time.Minute), count: 1}
+	l.windows["current"] = &rateWindow{start: now.Add(-30 * time.Second), count: 1}
+
+	l.cleanup(now)
+
+	if _, ok := l.windows["expired"]; ok {
+		t.Error("cleanup() kept an expired window")
+	}
+	if _, ok := l.windows["current"]; !ok {
+		t.Error("cleanup() dropped a current window")
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), RateLimit(NewRateLimiter(1, time.Minute)))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestid.Header, "req-123")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(); w.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", w.Code)
+	}
+	w := send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+
+	var body apierror.APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Code != apierror.CodeRateLimited {
+		t.Errorf("code = %s, want %s", body.Code, apierror.CodeRateLimited)
+	}
+	if body.RequestID != "req-123" {
+		t.Errorf("request_id = %q, want req-123", body.RequestID)
+	}
+}
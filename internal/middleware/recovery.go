@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"linda-salon-api/internal/apierror"
+)
+
+// Recovery turns panics into a 500 with the standard error body. gin logs
+// the panic and stack trace.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		apierror.Abort(c, http.StatusInternalServerError, apierror.New(apierror.CodeInternal, "Internal server error"))
+	})
+}
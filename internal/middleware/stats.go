@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestStats holds process-wide request counters. Fields are updated with
+// atomic operations so reading a snapshot never blocks request handling.
+type RequestStats struct {
+	total    int64
+	inFlight int64
+	status2x int64
+	status3x int64
+	status4x int64
+	status5x int64
+}
+
+// RequestStatsSnapshot is a point-in-time copy of RequestStats
+type RequestStatsSnapshot struct {
+	Total    int64 `json:"total"`
+	InFlight int64 `json:"in_flight"`
+	Status2x int64 `json:"status_2xx"`
+	Status3x int64 `json:"status_3xx"`
+	Status4x int64 `json:"status_4xx"`
+	Status5x int64 `json:"status_5xx"`
+}
+
+func NewRequestStats() *RequestStats {
+	return &RequestStats{}
+}
+
+// Snapshot returns the current counter values
+func (s *RequestStats) Snapshot() RequestStatsSnapshot {
+	return RequestStatsSnapshot{
+		Total:    atomic.LoadInt64(&s.total),
+		InFlight: atomic.LoadInt64(&s.inFlight),
+		Status2x: atomic.LoadInt64(&s.status2x),
+		Status3x: atomic.LoadInt64(&s.status3x),
+		Status4x: atomic.LoadInt64(&s.status4x),
+		Status5x: atomic.LoadInt64(&s.status5x),
+	}
+}
+
+// Stats counts requests by response status class
+func Stats(stats *RequestStats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		atomic.AddInt64(&stats.inFlight, 1)
+		defer atomic.AddInt64(&stats.inFlight, -1)
+
+		c.Next()
+
+		atomic.AddInt64(&stats.total, 1)
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			atomic.AddInt64(&stats.status5x, 1)
+		case status >= 400:
+			atomic.AddInt64(&stats.status4x, 1)
+		case status >= 300:
+			atomic.AddInt64(&stats.status3x, 1)
+		default:
+			atomic.AddInt64(&stats.status2x, 1)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Audit actions
+const (
+	AuditActionCreate        = "create"
+	AuditActionUpdate        = "update"
+	AuditActionDelete        = "delete"
+	AuditActionRestore       = "restore"
+	AuditActionStatusChange  = "status_change"
+	AuditActionBlock         = "block"
+	AuditActionUnblock       = "unblock"
+	AuditActionPurge         = "purge"
+	AuditActionLoyaltyAdjust = "loyalty_adjust"
+)
+
+// Audit entity types
+const (
+	AuditEntityService  = "service"
+	AuditEntityStylist  = "stylist"
+	AuditEntityBooking  = "booking"
+	AuditEntityCategory = "category"
+	AuditEntityUser     = "user"
+	// AuditEntityMaintenance entries aren't about one record; their entity ID is 0
+	AuditEntityMaintenance = "maintenance"
+)
+
+// AuditLog 管理員操作紀錄：誰在何時對哪筆資料做了什麼變更
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	ActorUserID uint   `gorm:"not null;index" json:"actor_user_id"`
+	Action      string `gorm:"type:varchar(30);not null" json:"action"`
+	EntityType  string `gorm:"type:varchar(30);not null;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID    uint   `gorm:"not null;index:idx_audit_logs_entity" json:"entity_id"`
+
+	// Changes 依動作而定：新增時為建立的資料，修改時為 {"欄位": {"from": 舊值, "to": 新值}}
+	Changes map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"changes"`
+
+	Actor *User `gorm:"foreignKey:ActorUserID" json:"actor,omitempty"`
+}
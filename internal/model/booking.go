@@ -1,6 +1,9 @@
 package model
 
 import (
+	"crypto/rand"
+	"math"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,6 +15,8 @@ type BookingServiceItem struct {
 	Name     string `json:"name"`
 	Price    int    `json:"price"`
 	Duration int    `json:"duration"`
+	// VariantLabel 預約當下所選的價位選項名稱，沒有選擇時為空
+	VariantLabel string `json:"variant_label,omitempty"`
 }
 
 type Booking struct {
@@ -23,6 +28,8 @@ type Booking struct {
 	// Foreign Keys
 	UserID    uint `gorm:"not null;index" json:"user_id"`
 	StylistID uint `gorm:"not null;index" json:"stylist_id"`
+	// 團體預約的上層紀錄，單一設計師的預約為 nil
+	GroupID *uint `gorm:"index" json:"group_id,omitempty"`
 
 	// Relationships
 	User    User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -31,19 +38,44 @@ type Booking struct {
 	// Multiple Services (JSONB)
 	Services []BookingServiceItem `gorm:"type:jsonb;serializer:json;not null" json:"services"`
 
+	// 預約參考編號，給沒有帳號的顧客查詢預約用
+	Reference string `gorm:"type:varchar(12);uniqueIndex" json:"reference"`
+
 	// Booking Details
-	BookingDate time.Time `gorm:"not null;index" json:"booking_date"`
-	StartTime   string    `gorm:"type:varchar(5);not null" json:"start_time"` // HH:MM
-	EndTime     string    `gorm:"type:varchar(5);not null" json:"end_time"`   // HH:MM
-	Duration    int       `gorm:"not null" json:"duration"` // minutes
-	Price       int       `gorm:"not null" json:"price"`
-	Status      string    `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending, confirmed, completed, cancelled
-	Notes       string    `gorm:"type:text" json:"notes"`
+	BookingDate  time.Time `gorm:"not null;index" json:"booking_date"`
+	StartTime    string    `gorm:"type:varchar(5);not null" json:"start_time"` // HH:MM
+	EndTime      string    `gorm:"type:varchar(5);not null" json:"end_time"`   // HH:MM
+	Duration     int       `gorm:"not null" json:"duration"`                   // minutes
+	// Buffer after the last service; the stylist is busy until OccupiedEndTime
+	// while EndTime stays the customer-facing end
+	BufferMinutes   int    `gorm:"not null;default:0" json:"buffer_minutes"`
+	OccupiedEndTime string `gorm:"type:varchar(5);not null;default:''" json:"occupied_end_time"` // HH:MM
+	Price        int       `gorm:"not null" json:"price"`                      // pre-tax subtotal
+	TaxAmount    int       `gorm:"not null;default:0" json:"tax_amount"`
+	TotalWithTax int       `gorm:"not null;default:0" json:"total_with_tax"`
+	Status       string    `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending, confirmed, completed, cancelled, no_show
+	Notes        string    `gorm:"type:text" json:"notes"`
+	AdminNotes   string    `gorm:"type:text" json:"admin_notes,omitempty"` // 內部備註，僅管理員可見
+	// 取消原因（顧客或管理員取消時選填）
+	CancellationReason string `gorm:"type:varchar(500)" json:"cancellation_reason,omitempty"`
+	// 管理員自訂標籤（例如 VIP、complaint、first-visit），僅管理員可見
+	Tags []string `gorm:"type:jsonb;serializer:json;not null;default:'[]';index:idx_bookings_tags,type:gin" json:"tags"`
 
 	// Customer Info (denormalized for easier queries)
 	CustomerName  string `gorm:"type:varchar(100);not null" json:"customer_name"`
 	CustomerPhone string `gorm:"type:varchar(20);not null" json:"customer_phone"`
 	CustomerEmail string `gorm:"type:varchar(255)" json:"customer_email"`
+
+	// Audit (staff only)
+	CreatedByID *uint `gorm:"index" json:"created_by_id,omitempty"` // 建立者 user ID
+	UpdatedByID *uint `json:"updated_by_id,omitempty"`              // 最後修改者 user ID
+
+	// 狀態最後一次實際改變的時間與操作者；修改備註、標籤或改期不會更動
+	StatusChangedAt   *time.Time `gorm:"index" json:"status_changed_at,omitempty"`
+	StatusChangedByID *uint      `json:"status_changed_by_id,omitempty"`
+
+	// 已寄出預約提醒的時間
+	ReminderSentAt *time.Time `json:"reminder_sent_at,omitempty"`
 }
 
 // BookingStatus constants
@@ -52,13 +84,87 @@ const (
 	BookingStatusConfirmed = "confirmed"
 	BookingStatusCompleted = "completed"
 	BookingStatusCancelled = "cancelled"
+	BookingStatusNoShow    = "no_show"
 )
 
-// IsCancellable checks if booking can be cancelled
-func (b *Booking) IsCancellable() bool {
+// BookingStatuses lists every booking status, in lifecycle order
+var BookingStatuses = []string{
+	BookingStatusPending,
+	BookingStatusConfirmed,
+	BookingStatusCompleted,
+	BookingStatusCancelled,
+	BookingStatusNoShow,
+}
+
+// Booking tag limits
+const (
+	MaxBookingTags      = 20
+	MaxBookingTagLength = 30
+)
+
+// bookingReferenceAlphabet leaves out 0/O and 1/I so references can be read
+// out over the phone
+const bookingReferenceAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// BookingReferenceLength 預約參考編號長度
+const BookingReferenceLength = 8
+
+// NewBookingReference returns a random booking reference such as "K7QM2XWD"
+func NewBookingReference() string {
+	b := make([]byte, BookingReferenceLength)
+	rand.Read(b)
+	for i := range b {
+		b[i] = bookingReferenceAlphabet[int(b[i])%len(bookingReferenceAlphabet)]
+	}
+	return string(b)
+}
+
+// NormalizeBookingTags trims tags and drops empty and duplicate ones,
+// keeping the first occurrence's order
+func NormalizeBookingTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ApplyTax sets the tax fields from the pre-tax Price and a rate (0~1)
+func (b *Booking) ApplyTax(rate float64) {
+	b.TaxAmount = int(math.Round(float64(b.Price) * rate))
+	b.TotalWithTax = b.Price + b.TaxAmount
+}
+
+// IsActive reports whether the booking is still pending or confirmed
+func (b *Booking) IsActive() bool {
 	return b.Status == BookingStatusPending || b.Status == BookingStatusConfirmed
 }
 
+// StartsAt returns the booking's start, reading BookingDate and StartTime as
+// salon-local time in loc
+func (b *Booking) StartsAt(loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02 15:04", b.BookingDate.Format("2006-01-02")+" "+b.StartTime, loc)
+}
+
+// IsCancellable checks if the booking is still active and starts at least
+// minNotice after now
+func (b *Booking) IsCancellable(now time.Time, minNotice time.Duration, loc *time.Location) bool {
+	if !b.IsActive() {
+		return false
+	}
+	start, err := b.StartsAt(loc)
+	if err != nil {
+		return false
+	}
+	return !now.Add(minNotice).After(start)
+}
+
 // IsUpcoming checks if booking is in the future
 func (b *Booking) IsUpcoming() bool {
 	return b.BookingDate.After(time.Now()) &&
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookingGroup 多位設計師同時或接續服務的團體預約（例如新娘秘書）
+// 每個時段是一筆獨立的 Booking，以 GroupID 連結；取消任一筆會一併取消整組
+type BookingGroup struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+
+	// Combined totals of the group's bookings at creation time
+	Price        int `gorm:"not null" json:"price"` // pre-tax subtotal
+	TaxAmount    int `gorm:"not null;default:0" json:"tax_amount"`
+	TotalWithTax int `gorm:"not null;default:0" json:"total_with_tax"`
+
+	Bookings []Booking `gorm:"foreignKey:GroupID" json:"bookings,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// BookingHold 暫時保留的預約時段
+// 顧客填寫預約表單時先保留時段，避免被他人搶走；逾時自動釋放
+type BookingHold struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID    uint `gorm:"not null;index" json:"user_id"`
+	StylistID uint `gorm:"not null;index" json:"stylist_id"`
+
+	Services        []BookingServiceItem `gorm:"type:jsonb;serializer:json;not null" json:"services"`
+	BookingDate     time.Time            `gorm:"not null;index" json:"booking_date"`
+	StartTime       string               `gorm:"type:varchar(5);not null" json:"start_time"` // HH:MM
+	EndTime         string               `gorm:"type:varchar(5);not null" json:"end_time"`   // HH:MM
+	Duration        int                  `gorm:"not null" json:"duration"`                   // minutes
+	BufferMinutes   int                  `gorm:"not null;default:0" json:"buffer_minutes"`
+	OccupiedEndTime string               `gorm:"type:varchar(5);not null;default:''" json:"occupied_end_time"` // EndTime plus buffer
+	Price           int                  `gorm:"not null" json:"price"`
+
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// IsExpired checks if the hold no longer reserves its slot
+func (h *BookingHold) IsExpired() bool {
+	return !time.Now().Before(h.ExpiresAt)
+}
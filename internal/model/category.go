@@ -0,0 +1,27 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// Category 服務分類。Slug 是程式與網址使用的代碼（例如 coloring），
+// Name 是顯示名稱，DisplayOrder 小的排前面。
+type Category struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name         string `gorm:"type:varchar(50);not null" json:"name"`
+	Slug         string `gorm:"type:varchar(50);not null;uniqueIndex" json:"slug"`
+	DisplayOrder int    `gorm:"not null;default:0" json:"display_order"`
+	IsActive     bool   `gorm:"default:true" json:"is_active"`
+}
+
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidCategorySlug reports whether slug is lowercase letters and digits,
+// optionally separated by single hyphens
+func ValidCategorySlug(slug string) bool {
+	return len(slug) <= 50 && categorySlugPattern.MatchString(slug)
+}
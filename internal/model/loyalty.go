@@ -0,0 +1,31 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// LoyaltyEntry 顧客點數異動紀錄：完成預約自動累積，或由管理員手動調整
+type LoyaltyEntry struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	UserID  uint   `gorm:"not null;index" json:"user_id"`
+	Points  int    `gorm:"not null" json:"points"`  // 正數為累積，負數為扣除
+	Balance int    `gorm:"not null" json:"balance"` // 這筆異動後的餘額
+	Reason  string `gorm:"type:varchar(255)" json:"reason"`
+
+	// 完成預約累積的點數；唯一索引確保每筆預約只給一次點數，即使狀態來回切換
+	BookingID *uint `gorm:"uniqueIndex" json:"booking_id,omitempty"`
+	// 手動調整的管理員
+	ActorUserID *uint `json:"actor_user_id,omitempty"`
+}
+
+// MaxLoyaltyReasonLength caps LoyaltyEntry.Reason, in characters
+const MaxLoyaltyReasonLength = 255
+
+// LoyaltyPointsFor returns the points earned for a booking of price at rate
+// points per NT$, rounded down
+func LoyaltyPointsFor(price int, rate float64) int {
+	return int(math.Floor(float64(price) * rate))
+}
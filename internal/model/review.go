@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review 顧客對已完成預約的評價，每筆預約最多一則
+type Review struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	BookingID uint `gorm:"not null;uniqueIndex" json:"booking_id"`
+	UserID    uint `gorm:"not null;index" json:"user_id"`
+	StylistID uint `gorm:"not null;index" json:"stylist_id"`
+
+	Rating  int    `gorm:"not null" json:"rating"` // 1~5
+	Comment string `gorm:"type:text" json:"comment"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,9 +15,69 @@ type Service struct {
 
 	Name        string `gorm:"type:varchar(100);not null" json:"name"`
 	Description string `gorm:"type:text" json:"description"`
-	Category    string `gorm:"type:varchar(50);not null" json:"category"` // haircut, coloring, treatment, styling, perm
-	Price       int    `gorm:"not null" json:"price"`
-	Duration    int    `gorm:"not null" json:"duration"` // in minutes
-	ImageURL    string `gorm:"type:varchar(500)" json:"image_url"`
-	IsActive    bool   `gorm:"default:true" json:"is_active"`
+	// Category 是 CategoryID 對應分類的 slug，保留給尚未改用 category_id 的用戶端
+	Category   string `gorm:"type:varchar(50);not null" json:"category"` // haircut, coloring, treatment, styling, perm
+	CategoryID *uint  `gorm:"index" json:"category_id"`
+	Price      int    `gorm:"not null" json:"price"`
+	Duration   int    `gorm:"not null" json:"duration"` // in minutes
+	// BufferMinutes 服務結束後的整理時間，設計師在這段時間不能接下一位客人。
+	// 目前沒有全店統一的緩衝設定，若日後加入，應與此值相加而非取代。
+	BufferMinutes int    `gorm:"not null;default:0" json:"buffer_minutes"`
+	ImageURL      string `gorm:"type:varchar(500)" json:"image_url"`
+	IsActive      bool   `gorm:"default:true" json:"is_active"`
+
+	Variants []ServiceVariant `gorm:"foreignKey:ServiceID" json:"variants,omitempty"`
+}
+
+// ServiceVariant 同一服務的價位選項（例如短髮／中長髮／長髮），
+// 以差額加在服務的基本價格與時長上
+type ServiceVariant struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ServiceID     uint   `gorm:"not null;index" json:"service_id"`
+	Label         string `gorm:"type:varchar(50);not null" json:"label"`
+	PriceDelta    int    `gorm:"not null;default:0" json:"price_delta"`
+	DurationDelta int    `gorm:"not null;default:0" json:"duration_delta"` // in minutes
+}
+
+// Price returns the variant's price given its service's base price
+func (v *ServiceVariant) Price(service *Service) int {
+	return service.Price + v.PriceDelta
+}
+
+// Duration returns the variant's duration given its service's base duration
+func (v *ServiceVariant) Duration(service *Service) int {
+	return service.Duration + v.DurationDelta
+}
+
+// ServiceBlackout 服務暫停提供的日期區間（例如燙髮藥水缺貨），
+// 區間內的日期不能預約此服務。StartDate/EndDate 皆包含在內。
+type ServiceBlackout struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ServiceID uint      `gorm:"not null;index" json:"service_id"`
+	StartDate time.Time `gorm:"type:date;not null;index" json:"start_date"`
+	EndDate   time.Time `gorm:"type:date;not null;index" json:"end_date"`
+	Reason    string    `gorm:"type:varchar(200)" json:"reason"`
+}
+
+// Validate checks that the range doesn't end before it starts
+func (b *ServiceBlackout) Validate() error {
+	if b.EndDate.Before(b.StartDate) {
+		return fmt.Errorf("end_date %s must not be before start_date %s",
+			b.EndDate.Format("2006-01-02"), b.StartDate.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// Covers reports whether date falls within the blackout
+func (b *ServiceBlackout) Covers(date time.Time) bool {
+	day := date.Format("2006-01-02")
+	return b.StartDate.Format("2006-01-02") <= day && day <= b.EndDate.Format("2006-01-02")
 }
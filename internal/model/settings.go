@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"sort"
 	"time"
 )
 
@@ -38,6 +40,170 @@ type BrandingConfig struct {
 	BackgroundColor string `json:"background_color"`  // 背景顏色
 }
 
+// DefaultBrandingConfig 尚未設定品牌時使用的預設值
+func DefaultBrandingConfig() BrandingConfig {
+	return BrandingConfig{
+		Name:            "Linda 髮廊",
+		ShortName:       "Linda",
+		Description:     "專業美髮服務，打造您的完美造型",
+		ThemeColor:      "#8B5CF6",
+		BackgroundColor: "#FFFFFF",
+	}
+}
+
+// Salon Contact Configuration
+type SalonContactConfig struct {
+	Name    string `json:"name"`    // 店名，未設定時使用品牌名稱
+	Address string `json:"address"` // 地址
+	Phone   string `json:"phone"`   // 電話
+	Website string `json:"website"` // 網站
+}
+
+// Location 組合店名與地址，用於行事曆地點
+func (c SalonContactConfig) Location() string {
+	if c.Address == "" {
+		return c.Name
+	}
+	if c.Name == "" {
+		return c.Address
+	}
+	return c.Name + ", " + c.Address
+}
+
+// PWA Service Worker Configuration
+type PWAServiceWorkerConfig struct {
+	CacheVersion    int      `json:"cache_version"`     // 快取版本，品牌或圖標變更時自動遞增
+	OfflineURL      string   `json:"offline_url"`       // 離線時顯示的頁面
+	PrecacheURLs    []string `json:"precache_urls"`     // 安裝時預先快取的網址
+	AssetPatterns   []string `json:"asset_patterns"`    // 採 cache-first 的資源路徑規則 (regex)
+	NetworkOnlyURLs []string `json:"network_only_urls"` // 永不快取的路徑前綴，例如 API
+}
+
+// DefaultPWAServiceWorkerConfig 尚未設定 service worker 時使用的預設值
+func DefaultPWAServiceWorkerConfig() PWAServiceWorkerConfig {
+	return PWAServiceWorkerConfig{
+		CacheVersion:    1,
+		OfflineURL:      "/offline.html",
+		PrecacheURLs:    []string{"/", "/offline.html", "/manifest.json"},
+		AssetPatterns:   []string{`\.(?:js|css|woff2?)$`, `\.(?:png|jpe?g|svg|webp|ico)$`},
+		NetworkOnlyURLs: []string{"/api/"},
+	}
+}
+
+// ScheduleTemplateSlot 班表範本中的一個時段
+type ScheduleTemplateSlot struct {
+	DayOfWeek int    `json:"day_of_week"` // 0=Sunday, 1=Monday, ..., 6=Saturday
+	StartTime string `json:"start_time"`  // HH:MM format
+	EndTime   string `json:"end_time"`    // HH:MM format
+}
+
+// ScheduleTemplates 依名稱儲存的每週班表範本，例如 "full-time"、"weekends"
+type ScheduleTemplates map[string][]ScheduleTemplateSlot
+
+// DefaultScheduleTemplates 尚未設定班表範本時使用的預設值
+func DefaultScheduleTemplates() ScheduleTemplates {
+	fullTime := []ScheduleTemplateSlot{}
+	for day := 2; day <= 6; day++ { // 週二至週六
+		fullTime = append(fullTime, ScheduleTemplateSlot{DayOfWeek: day, StartTime: "10:00", EndTime: "19:00"})
+	}
+	return ScheduleTemplates{
+		"full-time": fullTime,
+		"weekends": {
+			{DayOfWeek: 6, StartTime: "10:00", EndTime: "19:00"},
+			{DayOfWeek: 0, StartTime: "10:00", EndTime: "18:00"},
+		},
+	}
+}
+
+// ScheduleFieldError 班表時段中某個欄位不合法
+type ScheduleFieldError struct {
+	Field   string // day_of_week, start_time or end_time
+	Message string
+}
+
+func (e *ScheduleFieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidateScheduleSlot 檢查單一時段：星期 0~6、24 小時制 HH:MM，且開始早於結束。
+// 錯誤為 *ScheduleFieldError，指出哪個欄位不合法。
+func ValidateScheduleSlot(slot ScheduleTemplateSlot) error {
+	if slot.DayOfWeek < 0 || slot.DayOfWeek > 6 {
+		return &ScheduleFieldError{Field: "day_of_week", Message: fmt.Sprintf("%d must be between 0 (Sunday) and 6 (Saturday)", slot.DayOfWeek)}
+	}
+	if !isClockTime(slot.StartTime) {
+		return &ScheduleFieldError{Field: "start_time", Message: fmt.Sprintf("%q must be a 24-hour HH:MM time", slot.StartTime)}
+	}
+	if !isClockTime(slot.EndTime) {
+		return &ScheduleFieldError{Field: "end_time", Message: fmt.Sprintf("%q must be a 24-hour HH:MM time", slot.EndTime)}
+	}
+	if slot.StartTime >= slot.EndTime {
+		return &ScheduleFieldError{Field: "end_time", Message: fmt.Sprintf("%s must be after start_time %s", slot.EndTime, slot.StartTime)}
+	}
+	return nil
+}
+
+// ValidateScheduleSlots 檢查每個時段的格式，並確認同一天的時段沒有重疊
+func ValidateScheduleSlots(slots []ScheduleTemplateSlot) error {
+	sorted := make([]ScheduleTemplateSlot, len(slots))
+	copy(sorted, slots)
+	for _, slot := range sorted {
+		if err := ValidateScheduleSlot(slot); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].DayOfWeek != sorted[j].DayOfWeek {
+			return sorted[i].DayOfWeek < sorted[j].DayOfWeek
+		}
+		return sorted[i].StartTime < sorted[j].StartTime
+	})
+	for i := 1; i < len(sorted); i++ {
+		prev, cur := sorted[i-1], sorted[i]
+		if prev.DayOfWeek == cur.DayOfWeek && cur.StartTime < prev.EndTime {
+			return fmt.Errorf("overlapping time ranges on day %d: %s-%s and %s-%s",
+				cur.DayOfWeek, prev.StartTime, prev.EndTime, cur.StartTime, cur.EndTime)
+		}
+	}
+	return nil
+}
+
+func isClockTime(s string) bool {
+	t, err := time.Parse("15:04", s)
+	return err == nil && t.Format("15:04") == s
+}
+
+// DefaultSetting 啟動時寫入的預設設定
+type DefaultSetting struct {
+	Key      string
+	Category string
+	Value    interface{}
+}
+
+// DefaultSettings 全新資料庫應具備的標準設定，啟動時只補上尚未存在的項目
+func DefaultSettings() []DefaultSetting {
+	return []DefaultSetting{
+		{Key: SettingsKeyBranding, Category: "branding", Value: DefaultBrandingConfig()},
+		{Key: SettingsKeyPWAIcons, Category: "pwa", Value: PWAIconConfig{}},
+		{Key: SettingsKeyPWAServiceWorker, Category: "pwa", Value: DefaultPWAServiceWorkerConfig()},
+		{Key: SettingsKeySalonContact, Category: "general", Value: SalonContactConfig{}},
+		{Key: SettingsKeyStylistServicesFallback, Category: "booking", Value: DefaultStylistServicesFallback},
+		{Key: SettingsKeyTaxRate, Category: "booking", Value: 0.0},
+		{Key: SettingsKeyAutoConfirm, Category: "booking", Value: false},
+		{Key: SettingsKeyNextAvailableHorizonDays, Category: "booking", Value: DefaultNextAvailableHorizonDays},
+		{Key: SettingsKeyMaxAdvanceDays, Category: "booking", Value: DefaultMaxAdvanceDays},
+		{Key: SettingsKeyCancellationNoticeHours, Category: "booking", Value: DefaultCancellationNoticeHours},
+		{Key: SettingsKeyMinDurationMinutes, Category: "booking", Value: 0},
+		{Key: SettingsKeyMaxDurationMinutes, Category: "booking", Value: 0},
+		{Key: SettingsKeyBookingTagAllowlist, Category: "booking", Value: []string{}},
+		{Key: SettingsKeyAdminNotificationRecipients, Category: "notifications", Value: []string{}},
+		{Key: SettingsKeyScheduleTemplates, Category: "stylist", Value: DefaultScheduleTemplates()},
+		{Key: SettingsKeyReminderHoursBefore, Category: "notifications", Value: DefaultReminderHoursBefore},
+		{Key: SettingsKeyLoyaltyPointsRate, Category: "loyalty", Value: DefaultLoyaltyPointsRate},
+	}
+}
+
 // PWA Configuration
 type PWAConfig struct {
 	Icons       PWAIconConfig `json:"icons"`
@@ -49,4 +215,73 @@ const (
 	SettingsKeyPWAIcons   = "pwa.icons"
 	SettingsKeyBranding   = "branding"
 	SettingsKeyScreenshots = "pwa.screenshots"
+	SettingsKeySalonContact = "salon.contact"
+	SettingsKeyPWAServiceWorker = "pwa.sw_config"
+
+	// 設計師服務對應：尚未設定任何對應時，是否視為所有設計師都能提供所有服務
+	SettingsKeyStylistServicesFallback = "stylist.services_fallback_all"
+	// 預設開啟，升級後尚未設定對應的店家仍可照常預約
+	DefaultStylistServicesFallback = true
+
+	// 預約稅率 (0~1)，0 表示不計稅
+	SettingsKeyTaxRate = "booking.tax_rate"
+
+	// 新預約是否直接成立為 confirmed（略過管理員審核）。
+	// 不影響可預約時段：pending 與 confirmed 的預約同樣會佔用時段。
+	SettingsKeyAutoConfirm = "booking.auto_confirm"
+
+	// 預約標籤允許清單 ([]string)，空清單表示不限制
+	SettingsKeyBookingTagAllowlist = "booking.tag_allowlist"
+
+	// 「最快可預約時段」往後搜尋的天數
+	SettingsKeyNextAvailableHorizonDays = "booking.next_available_horizon_days"
+
+	// 顧客最多可預約幾天後的日期，可預約時段與行事曆也不會超過這個範圍
+	SettingsKeyMaxAdvanceDays = "booking.max_advance_days"
+
+	// 顧客最晚需在預約開始前幾小時取消，0 表示開始前都可取消（管理員不受限制）
+	SettingsKeyCancellationNoticeHours = "booking.cancellation_notice_hours"
+
+	// 顧客自行預約時，所選服務加總時長的下限／上限（分鐘），0 表示不限制（管理員不受限制）
+	SettingsKeyMinDurationMinutes = "booking.min_duration_minutes"
+	SettingsKeyMaxDurationMinutes = "booking.max_duration_minutes"
+
+	// 新預約、取消通知的管理員收件人清單 ([]string)
+	SettingsKeyAdminNotificationRecipients = "notifications.admin_recipients"
+
+	// 設計師每週班表範本 (ScheduleTemplates)
+	SettingsKeyScheduleTemplates = "stylist.schedule_templates"
+
+	// 預約提醒預設提前小時數，顧客可在個人資料中自訂
+	SettingsKeyReminderHoursBefore = "notifications.reminder_hours_before"
+
+	// 完成預約時每 NT$1（未稅）累積的點數 (0~1)，0 表示不累積
+	SettingsKeyLoyaltyPointsRate = "loyalty.points_rate"
+)
+
+// DefaultLoyaltyPointsRate 預設每消費 NT$100 累積 1 點
+const DefaultLoyaltyPointsRate = 0.01
+
+// DefaultReminderHoursBefore 尚未設定時的預約提醒提前小時數
+const DefaultReminderHoursBefore = 24
+
+// 最快可預約時段的搜尋天數範圍
+const (
+	DefaultNextAvailableHorizonDays = 30
+	MaxNextAvailableHorizonDays     = 90
+)
+
+// 最多可提前預約的天數
+const (
+	DefaultMaxAdvanceDays = 90
+	MaxMaxAdvanceDays     = 365
+)
+
+// 顧客自行預約的時長上下限設定值的最大值（一整天）
+const MaxBookingDurationLimitMinutes = 24 * 60
+
+// 取消預約最少需提前的小時數
+const (
+	DefaultCancellationNoticeHours = 2
+	MaxCancellationNoticeHours     = 168
 )
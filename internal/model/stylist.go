@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -19,9 +20,55 @@ type Stylist struct {
 	Avatar      string `gorm:"type:varchar(500)" json:"avatar"`
 	IsActive    bool   `gorm:"default:true" json:"is_active"`
 
+	// Pricing premium applied on top of the summed service price
+	PriceModifierType  string `gorm:"type:varchar(10)" json:"price_modifier_type,omitempty"` // "", percent, flat
+	PriceModifierValue int    `gorm:"default:0" json:"price_modifier_value"`                 // percent points or NT$
+
 	// Relationships
-	Schedules []StylistSchedule `gorm:"foreignKey:StylistID" json:"schedules,omitempty"`
-	Bookings  []Booking         `gorm:"foreignKey:StylistID" json:"bookings,omitempty"`
+	Schedules    []StylistSchedule `gorm:"foreignKey:StylistID" json:"schedules,omitempty"`
+	Bookings     []Booking         `gorm:"foreignKey:StylistID" json:"bookings,omitempty"`
+	Capabilities []StylistService  `gorm:"foreignKey:StylistID" json:"-"` // 可提供的服務
+}
+
+// CanPerform reports whether the stylist is mapped to the service. Requires
+// Capabilities to be loaded.
+func (s *Stylist) CanPerform(serviceID uint) bool {
+	for _, capability := range s.Capabilities {
+		if capability.ServiceID == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceModifier type constants
+const (
+	PriceModifierPercent = "percent"
+	PriceModifierFlat    = "flat"
+)
+
+// Allowed PriceModifierValue ranges per type
+const (
+	MinPriceModifierPercent = -50
+	MaxPriceModifierPercent = 200
+	MinPriceModifierFlat    = -5000
+	MaxPriceModifierFlat    = 10000
+)
+
+// ApplyPriceModifier returns the price the stylist charges for services whose
+// base prices sum to basePrice. The result is never negative.
+func (s *Stylist) ApplyPriceModifier(basePrice int) int {
+	price := basePrice
+	switch s.PriceModifierType {
+	case PriceModifierPercent:
+		price = basePrice + basePrice*s.PriceModifierValue/100
+	case PriceModifierFlat:
+		price = basePrice + s.PriceModifierValue
+	}
+	if price < 0 {
+		return 0
+	}
+	return price
 }
 
 type StylistSchedule struct {
@@ -38,3 +85,46 @@ type StylistSchedule struct {
 	EndTime   string `gorm:"type:varchar(5);not null" json:"end_time"`   // HH:MM format
 	IsActive  bool   `gorm:"default:true" json:"is_active"`
 }
+
+// StylistService maps a stylist to a service they are qualified to perform
+type StylistService struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	StylistID uint `gorm:"not null;uniqueIndex:idx_stylist_service" json:"stylist_id"`
+	ServiceID uint `gorm:"not null;uniqueIndex:idx_stylist_service;index" json:"service_id"`
+}
+
+// StylistTimeOff 設計師的休假或臨時不接客時段，優先於每週排班。
+// StartTime/EndTime 留空表示整天休假。
+type StylistTimeOff struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	StylistID uint      `gorm:"not null;index" json:"stylist_id"`
+	Date      time.Time `gorm:"not null;index" json:"date"`
+	StartTime string    `gorm:"type:varchar(5)" json:"start_time,omitempty"` // HH:MM, empty for a full day
+	EndTime   string    `gorm:"type:varchar(5)" json:"end_time,omitempty"`   // HH:MM, empty for a full day
+	Reason    string    `gorm:"type:varchar(200)" json:"reason"`
+}
+
+// IsFullDay reports whether the time off covers the whole day
+func (t *StylistTimeOff) IsFullDay() bool {
+	return t.StartTime == "" && t.EndTime == ""
+}
+
+// Validate checks that a partial day has a well-formed HH:MM range
+func (t *StylistTimeOff) Validate() error {
+	if t.IsFullDay() {
+		return nil
+	}
+	if !isClockTime(t.StartTime) || !isClockTime(t.EndTime) {
+		return fmt.Errorf("start_time and end_time must both be HH:MM, or both empty for a full day")
+	}
+	if t.StartTime >= t.EndTime {
+		return fmt.Errorf("start_time %s must be before end_time %s", t.StartTime, t.EndTime)
+	}
+	return nil
+}
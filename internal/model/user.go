@@ -24,6 +24,23 @@ type User struct {
 	GoogleID *string `gorm:"type:varchar(255);uniqueIndex" json:"google_id,omitempty"` // 改為指標，允許 NULL
 	LineID   *string `gorm:"type:varchar(255);uniqueIndex" json:"line_id,omitempty"`   // 改為指標，允許 NULL
 
+	// Blocking (e.g. repeated no-shows); blocked users can log in but not book
+	IsBlocked   bool       `gorm:"default:false;index" json:"is_blocked"`
+	BlockReason string     `gorm:"type:varchar(500)" json:"block_reason,omitempty"`
+	BlockedAt   *time.Time `json:"blocked_at,omitempty"`
+
+	// 預約提醒要提前幾小時寄出，nil 表示使用店家預設值
+	ReminderHoursBefore *int `json:"reminder_hours_before"`
+
+	// 常用設計師，預約畫面預先選取；設計師之後停用時保留，由 API 標示為不可預約
+	DefaultStylistID *uint `gorm:"index" json:"default_stylist_id"`
+
+	// 顧客自填的長期備註（過敏、偏好等），跨預約保留，只給本人與管理員看
+	ProfileNote string `gorm:"type:varchar(500)" json:"profile_note,omitempty"`
+
+	// 集點餘額，每次異動都記錄在 LoyaltyEntry
+	LoyaltyPoints int `gorm:"not null;default:0" json:"loyalty_points"`
+
 	// Relationships
 	Bookings []Booking `gorm:"foreignKey:UserID" json:"bookings,omitempty"`
 }
@@ -44,6 +61,15 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// Allowed ReminderHoursBefore range
+const (
+	MinReminderHoursBefore = 1
+	MaxReminderHoursBefore = 72
+)
+
+// MaxProfileNoteLength caps ProfileNote, in characters
+const MaxProfileNoteLength = 500
+
 // IsAdmin checks if user has admin role
 func (u *User) IsAdmin() bool {
 	return u.Role == "admin"
@@ -0,0 +1,72 @@
+// Package notify delivers outgoing emails.
+package notify
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"linda-salon-api/config"
+)
+
+// EmailSender sends a plain-text email
+type EmailSender interface {
+	Send(to, subject, body string) error
+}
+
+// NewEmailSender returns an SMTP sender when SMTP is configured, otherwise
+// one that only logs the messages
+func NewEmailSender(cfg *config.SMTPConfig) EmailSender {
+	if !cfg.Enabled() {
+		return LogSender{}
+	}
+	return &SMTPSender{cfg: cfg}
+}
+
+// SMTPSender sends emails through an SMTP server, using STARTTLS when the
+// server offers it
+type SMTPSender struct {
+	cfg *config.SMTPConfig
+}
+
+func (s *SMTPSender) Send(to, subject, body string) error {
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	var auth smtp.Auth
+	if s.cfg.User != "" {
+		auth = smtp.PlainAuth("", s.cfg.User, s.cfg.Password, s.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{to}, buildMessage(from.String(), to, subject, body))
+}
+
+// LogSender writes emails to the log instead of sending them
+type LogSender struct{}
+
+func (LogSender) Send(to, subject, body string) error {
+	log.Printf("📧 [Notify] to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
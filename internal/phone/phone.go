@@ -0,0 +1,59 @@
+// Package phone normalizes phone numbers to E.164 (+<country code><number>)
+// so the same number entered as "0912-345-678", "0912345678" or
+// "+886912345678" is stored and compared as one value.
+package phone
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// DefaultRegion is the region assumed for numbers written in national format
+// until SetDefaultRegion is called, since the salon's customers are in Taiwan
+const DefaultRegion = "TW"
+
+// defaultRegion is the ISO 3166-1 region national numbers are parsed in
+// (PHONE_DEFAULT_REGION). Set once at startup with SetDefaultRegion.
+var defaultRegion = DefaultRegion
+
+// ErrInvalid is returned for input that can't be read as a phone number
+var ErrInvalid = errors.New("invalid phone number")
+
+// ErrUnknownRegion is returned by SetDefaultRegion for a region code the
+// phone number metadata doesn't cover
+var ErrUnknownRegion = errors.New("unknown phone region")
+
+// IsSupportedRegion reports whether region is an ISO 3166-1 alpha-2 code
+// that numbers can be parsed in, e.g. TW or JP
+func IsSupportedRegion(region string) bool {
+	return phonenumbers.GetSupportedRegions()[strings.ToUpper(region)]
+}
+
+// SetDefaultRegion sets the region numbers without a country code are
+// assumed to be in
+func SetDefaultRegion(region string) error {
+	if !IsSupportedRegion(region) {
+		return ErrUnknownRegion
+	}
+	defaultRegion = strings.ToUpper(region)
+	return nil
+}
+
+// Normalize returns raw in E.164 form. Numbers starting with + or the
+// default region's international prefix keep their country code; any other
+// number is read as a national number in the default region. The number
+// must be valid for its region, not just the right length.
+func Normalize(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", ErrInvalid
+	}
+
+	num, err := phonenumbers.Parse(s, defaultRegion)
+	if err != nil || !phonenumbers.IsValidNumber(num) {
+		return "", ErrInvalid
+	}
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
@@ -0,0 +1,75 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+// setRegion switches the default region for the rest of the test
+func setRegion(t *testing.T, region string) {
+	t.Helper()
+	previous := defaultRegion
+	if err := SetDefaultRegion(region); err != nil {
+		t.Fatalf("SetDefaultRegion(%q) error = %v", region, err)
+	}
+	t.Cleanup(func() { defaultRegion = previous })
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		region  string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"TW", "0912-345-678", "+886912345678", false},
+		{"TW", "0912345678", "+886912345678", false},
+		{"TW", " 0912 345 678 ", "+886912345678", false},
+		{"TW", "+886912345678", "+886912345678", false},
+		{"TW", "+886 912-345-678", "+886912345678", false},
+		{"TW", "(02) 2345-6789", "+886223456789", false},
+		{"TW", "+81 90-1234-5678", "+819012345678", false},
+		{"JP", "090-1234-5678", "+819012345678", false},
+		{"JP", "+886912345678", "+886912345678", false},
+		{"TW", "", "", true},
+		{"TW", "not a number", "", true},
+		{"TW", "12345", "", true},
+		{"TW", "0912", "", true},
+		{"TW", "+886 12", "", true},
+		{"TW", "091234567890123456", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region+" "+tt.raw, func(t *testing.T) {
+			setRegion(t, tt.region)
+			got, err := Normalize(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("Normalize(%q) = %q, %v, want ErrInvalid", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q) error = %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDefaultRegion(t *testing.T) {
+	previous := defaultRegion
+	t.Cleanup(func() { defaultRegion = previous })
+
+	for _, region := range []string{"TW", "jp", "US"} {
+		if err := SetDefaultRegion(region); err != nil {
+			t.Errorf("SetDefaultRegion(%q) error = %v", region, err)
+		}
+	}
+	for _, region := range []string{"", "XX", "Taiwan", "886"} {
+		if err := SetDefaultRegion(region); !errors.Is(err, ErrUnknownRegion) {
+			t.Errorf("SetDefaultRegion(%q) error = %v, want ErrUnknownRegion", region, err)
+		}
+	}
+}
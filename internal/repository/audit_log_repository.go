@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"linda-salon-api/internal/model"
+)
+
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// AuditLogFilter narrows an audit log listing; zero values don't filter
+type AuditLogFilter struct {
+	ActorUserID uint
+	EntityType  string
+	EntityID    uint
+	From        *time.Time // inclusive
+	To          *time.Time // exclusive
+}
+
+func (r *AuditLogRepository) Create(entry *model.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// List returns matching entries newest first, along with the total count
+func (r *AuditLogRepository) List(filter AuditLogFilter, limit, offset int) ([]model.AuditLog, int64, error) {
+	var logs []model.AuditLog
+	var total int64
+
+	query := r.db.Model(&model.AuditLog{})
+	if filter.ActorUserID != 0 {
+		query = query.Where("actor_user_id = ?", filter.ActorUserID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Actor").
+		Order("created_at DESC, id DESC").
+		Limit(limit).Offset(offset).
+		Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"linda-salon-api/internal/model"
+)
+
+type BookingHoldRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingHoldRepository(db *gorm.DB) *BookingHoldRepository {
+	return &BookingHoldRepository{db: db}
+}
+
+func (r *BookingHoldRepository) Create(hold *model.BookingHold) error {
+	return r.db.Create(hold).Error
+}
+
+func (r *BookingHoldRepository) GetByID(id uint) (*model.BookingHold, error) {
+	var hold model.BookingHold
+	err := r.db.First(&hold, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *BookingHoldRepository) Delete(id uint) error {
+	return r.db.Delete(&model.BookingHold{}, id).Error
+}
+
+// DeleteByUser releases every hold owned by the user and returns the
+// released holds
+func (r *BookingHoldRepository) DeleteByUser(userID uint) ([]model.BookingHold, error) {
+	var holds []model.BookingHold
+	err := r.db.Clauses(clause.Returning{}).Where("user_id = ?", userID).Delete(&holds).Error
+	return holds, err
+}
+
+// DeleteExpired removes holds whose reservation window has passed
+func (r *BookingHoldRepository) DeleteExpired() (int64, error) {
+	result := r.db.Where("expires_at <= ?", time.Now()).Delete(&model.BookingHold{})
+	return result.RowsAffected, result.Error
+}
+
+// GetActiveByStylistsAndDateRange returns unexpired holds for the given
+// stylists between two dates (inclusive)
+func (r *BookingHoldRepository) GetActiveByStylistsAndDateRange(stylistIDs []uint, startDate, endDate time.Time) ([]model.BookingHold, error) {
+	var holds []model.BookingHold
+	err := r.db.
+		Where("stylist_id IN ? AND booking_date BETWEEN ? AND ? AND expires_at > ?",
+			stylistIDs, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), time.Now()).
+		Order("booking_date, start_time").
+		Find(&holds).Error
+	return holds, err
+}
@@ -1,13 +1,21 @@
 package repository
 
 import (
+	"encoding/json"
 	"errors"
+	"math"
+	"sort"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"linda-salon-api/internal/model"
 )
 
+// ErrSlotTaken is returned when another booking already occupies the slot
+var ErrSlotTaken = errors.New("time slot is already booked")
+
 type BookingRepository struct {
 	db *gorm.DB
 }
@@ -17,7 +25,146 @@ func NewBookingRepository(db *gorm.DB) *BookingRepository {
 }
 
 func (r *BookingRepository) Create(booking *model.Booking) error {
-	return r.db.Create(booking).Error
+	return createWithReference(r.db, booking)
+}
+
+// bookingReferenceIndex is the unique index GORM creates for Booking.Reference
+const bookingReferenceIndex = "idx_bookings_reference"
+
+// maxReferenceAttempts bounds how many generated references are tried
+// before giving up on a booking
+const maxReferenceAttempts = 5
+
+// pgUniqueViolation is the Postgres SQLSTATE for unique_violation
+const pgUniqueViolation = "23505"
+
+// createWithReference inserts a booking, generating its lookup reference
+// when it has none. Uniqueness is left to the database: when the insert
+// violates the reference index (another booking got the same code first) a
+// new reference is generated and the insert retried. Each attempt runs in
+// its own savepoint so a collision inside a transaction doesn't abort it.
+func createWithReference(db *gorm.DB, booking *model.Booking) error {
+	generated := booking.Reference == ""
+	for attempt := 1; ; attempt++ {
+		if generated {
+			booking.Reference = model.NewBookingReference()
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(booking).Error
+		})
+		if err == nil || !generated || !isReferenceCollision(err) || attempt == maxReferenceAttempts {
+			return err
+		}
+	}
+}
+
+// isReferenceCollision reports whether err is Postgres rejecting a
+// duplicate booking reference
+func isReferenceCollision(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == bookingReferenceIndex
+}
+
+// CreateWithAvailabilityCheck creates a booking only if no active booking of
+// the same stylist overlaps startTime–endTime on its date. The stylist row is
+// locked (SELECT ... FOR UPDATE) for the duration of the transaction, so
+// concurrent requests for the same stylist are checked and inserted one at a
+// time. Returns ErrSlotTaken when the slot is already booked.
+func (r *BookingRepository) CreateWithAvailabilityCheck(booking *model.Booking, startTime, endTime string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var stylist model.Stylist
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&stylist, booking.StylistID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		err := overlappingBookings(tx, booking.StylistID, booking.BookingDate, startTime, endTime).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSlotTaken
+		}
+
+		return createWithReference(tx, booking)
+	})
+}
+
+// CreateGroup creates a group booking and its bookings in one transaction.
+// Every stylist involved is locked, in ID order so concurrent groups can't
+// deadlock, and each booking is checked for overlaps as in
+// CreateWithAvailabilityCheck. Returns ErrSlotTaken when any slot is taken,
+// in which case nothing is created.
+func (r *BookingRepository) CreateGroup(group *model.BookingGroup, bookings []model.Booking) error {
+	stylistIDs := make([]uint, 0, len(bookings))
+	seen := make(map[uint]bool, len(bookings))
+	for _, booking := range bookings {
+		if !seen[booking.StylistID] {
+			seen[booking.StylistID] = true
+			stylistIDs = append(stylistIDs, booking.StylistID)
+		}
+	}
+	sort.Slice(stylistIDs, func(i, j int) bool { return stylistIDs[i] < stylistIDs[j] })
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, stylistID := range stylistIDs {
+			var stylist model.Stylist
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id").First(&stylist, stylistID).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, booking := range bookings {
+			var count int64
+			err := overlappingBookings(tx, booking.StylistID, booking.BookingDate, booking.StartTime, booking.OccupiedEndTime).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrSlotTaken
+			}
+		}
+
+		if err := tx.Omit("Bookings").Create(group).Error; err != nil {
+			return err
+		}
+		for i := range bookings {
+			bookings[i].GroupID = &group.ID
+			if err := createWithReference(tx, &bookings[i]); err != nil {
+				return err
+			}
+		}
+		group.Bookings = bookings
+		return nil
+	})
+}
+
+// GetGroup returns a group booking with its bookings in start order, or nil
+func (r *BookingRepository) GetGroup(id uint) (*model.BookingGroup, error) {
+	var group model.BookingGroup
+	err := r.db.Preload("Bookings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("start_time ASC, id ASC")
+	}).Preload("Bookings.User").Preload("Bookings.Stylist").First(&group, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// overlappingBookings scopes to the stylist's pending/confirmed bookings on
+// date whose occupied time overlaps startTime–endTime
+func overlappingBookings(db *gorm.DB, stylistID uint, date time.Time, startTime, endTime string) *gorm.DB {
+	return db.Model(&model.Booking{}).
+		Where("stylist_id = ? AND booking_date = ? AND status IN ?",
+			stylistID, date.Format("2006-01-02"), []string{"pending", "confirmed"}).
+		Where("NOT (occupied_end_time <= ? OR start_time >= ?)", startTime, endTime)
 }
 
 func (r *BookingRepository) GetByID(id uint) (*model.Booking, error) {
@@ -32,6 +179,19 @@ func (r *BookingRepository) GetByID(id uint) (*model.Booking, error) {
 	return &booking, nil
 }
 
+// GetByReference returns the booking with the given reference, or nil
+func (r *BookingRepository) GetByReference(reference string) (*model.Booking, error) {
+	var booking model.Booking
+	err := r.db.Preload("Stylist").Where("reference = ?", reference).First(&booking).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &booking, nil
+}
+
 func (r *BookingRepository) Update(booking *model.Booking) error {
 	return r.db.Save(booking).Error
 }
@@ -40,39 +200,113 @@ func (r *BookingRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Booking{}, id).Error
 }
 
-func (r *BookingRepository) List(userID *uint, status string, startDate, endDate *time.Time, limit, offset int) ([]model.Booking, int64, error) {
-	var bookings []model.Booking
-	var total int64
+// BookingListFilter narrows a booking listing; nil and empty fields don't
+// filter. StartDate/EndDate compare whole days; StartDateTime/EndDateTime
+// also compare the HH:MM start time. Tag keeps bookings carrying that tag.
+type BookingListFilter struct {
+	UserID        *uint
+	StylistID     *uint
+	Status        string
+	Tag           string
+	StartDate     *time.Time
+	EndDate       *time.Time
+	StartDateTime *time.Time
+	EndDateTime   *time.Time
+}
 
-	query := r.db.Model(&model.Booking{}).Preload("User").Preload("Stylist")
+// apply adds the filter's conditions to query. The status condition is left
+// to the caller so status counts can share the other filters.
+func (f BookingListFilter) apply(query *gorm.DB) (*gorm.DB, error) {
+	if f.UserID != nil {
+		query = query.Where("user_id = ?", *f.UserID)
+	}
 
-	if userID != nil {
-		query = query.Where("user_id = ?", *userID)
+	if f.StylistID != nil {
+		query = query.Where("stylist_id = ?", *f.StylistID)
 	}
 
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if f.Tag != "" {
+		encoded, err := json.Marshal([]string{f.Tag})
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("tags @> ?::jsonb", string(encoded))
 	}
 
-	if startDate != nil {
-		query = query.Where("booking_date >= ?", *startDate)
+	if f.StartDate != nil {
+		query = query.Where("booking_date >= ?", *f.StartDate)
 	}
 
-	if endDate != nil {
-		query = query.Where("booking_date <= ?", *endDate)
+	if f.EndDate != nil {
+		query = query.Where("booking_date <= ?", *f.EndDate)
+	}
+
+	if f.StartDateTime != nil {
+		query = query.Where("(booking_date, start_time) >= (?, ?)",
+			f.StartDateTime.Format("2006-01-02"), f.StartDateTime.Format("15:04"))
+	}
+
+	if f.EndDateTime != nil {
+		query = query.Where("(booking_date, start_time) <= (?, ?)",
+			f.EndDateTime.Format("2006-01-02"), f.EndDateTime.Format("15:04"))
+	}
+
+	return query, nil
+}
+
+// List returns bookings matching the filter, newest first, along with the
+// total count
+func (r *BookingRepository) List(filter BookingListFilter, limit, offset int) ([]model.Booking, int64, error) {
+	var bookings []model.Booking
+	var total int64
+
+	query, err := filter.apply(r.db.Model(&model.Booking{}).Preload("User").Preload("Stylist"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
 	}
 
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := query.Order("booking_date DESC, start_time DESC").
+	err = query.Order("booking_date DESC, start_time DESC").
 		Limit(limit).Offset(offset).
 		Find(&bookings).Error
 
 	return bookings, total, err
 }
 
+// CountByStatusFiltered counts bookings matching filter per status, ignoring
+// filter.Status, with one GROUP BY query. Every status is present in the
+// result, with 0 when it has no bookings.
+func (r *BookingRepository) CountByStatusFiltered(filter BookingListFilter) (map[string]int64, error) {
+	query, err := filter.apply(r.db.Model(&model.Booking{}))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := query.Select("status, COUNT(*) as count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(model.BookingStatuses))
+	for _, status := range model.BookingStatuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *BookingRepository) GetUserBookings(userID uint, upcoming bool) ([]model.Booking, error) {
 	var bookings []model.Booking
 	query := r.db.Preload("Stylist").
@@ -97,6 +331,39 @@ func (r *BookingRepository) GetByDate(date time.Time) ([]model.Booking, error) {
 	return bookings, err
 }
 
+// PurgeDeleted hard-deletes bookings soft-deleted before cutoff. Bookings
+// dated on or after cutoff are kept so reports over the retention window
+// still see them.
+func (r *BookingRepository) PurgeDeleted(cutoff time.Time) (int64, error) {
+	return purgeSoftDeleted(r.db, &model.Booking{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("deleted_at < ? AND booking_date < ?", cutoff, cutoff)
+	}, nil)
+}
+
+// BookingConflictPair identifies two bookings of the same stylist whose times overlap
+type BookingConflictPair struct {
+	FirstID  uint
+	SecondID uint
+}
+
+// FindConflicts returns every pair of non-cancelled bookings on the date that
+// overlap for the same stylist
+func (r *BookingRepository) FindConflicts(date time.Time) ([]BookingConflictPair, error) {
+	var pairs []BookingConflictPair
+	day := date.Format("2006-01-02")
+	err := r.db.Raw(`
+		SELECT a.id AS first_id, b.id AS second_id
+		FROM bookings a
+		JOIN bookings b ON b.stylist_id = a.stylist_id AND b.booking_date = a.booking_date AND b.id > a.id
+		WHERE a.booking_date = ?
+			AND a.status <> ? AND b.status <> ?
+			AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND a.start_time < b.end_time AND b.start_time < a.end_time
+		ORDER BY a.stylist_id, a.start_time, b.start_time
+	`, day, model.BookingStatusCancelled, model.BookingStatusCancelled).Scan(&pairs).Error
+	return pairs, err
+}
+
 func (r *BookingRepository) GetByStylistAndDate(stylistID uint, date time.Time) ([]model.Booking, error) {
 	var bookings []model.Booking
 	err := r.db.Preload("User").
@@ -119,8 +386,157 @@ func (r *BookingRepository) GetByStylistAndDateString(stylistID uint, dateStr st
 	return bookings, err
 }
 
-func (r *BookingRepository) UpdateStatus(id uint, status string) error {
-	return r.db.Model(&model.Booking{}).Where("id = ?", id).Update("status", status).Error
+// GetActiveByStylistsAndDateRange returns pending/confirmed bookings for the
+// given stylists between two dates (inclusive)
+func (r *BookingRepository) GetActiveByStylistsAndDateRange(stylistIDs []uint, startDate, endDate time.Time) ([]model.Booking, error) {
+	var bookings []model.Booking
+	err := r.db.
+		Where("stylist_id IN ? AND booking_date BETWEEN ? AND ? AND status IN ?",
+			stylistIDs, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"),
+			[]string{model.BookingStatusPending, model.BookingStatusConfirmed}).
+		Order("booking_date, start_time").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// GetByDateAndStatuses returns a date's bookings in any of the statuses, with
+// their user and stylist loaded
+func (r *BookingRepository) GetByDateAndStatuses(date time.Time, statuses []string) ([]model.Booking, error) {
+	var bookings []model.Booking
+	err := r.db.Preload("User").Preload("Stylist").
+		Where("booking_date = ? AND status IN ?", date.Format("2006-01-02"), statuses).
+		Order("start_time").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// GetAwaitingReminder returns pending/confirmed bookings between two dates
+// (inclusive) that haven't had a reminder sent, with their user loaded
+func (r *BookingRepository) GetAwaitingReminder(startDate, endDate time.Time) ([]model.Booking, error) {
+	var bookings []model.Booking
+	err := r.db.Preload("User").
+		Where("booking_date BETWEEN ? AND ? AND status IN ? AND reminder_sent_at IS NULL",
+			startDate.Format("2006-01-02"), endDate.Format("2006-01-02"),
+			[]string{model.BookingStatusPending, model.BookingStatusConfirmed}).
+		Order("booking_date, start_time").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// MarkReminderSent records that a booking's reminder went out. It reports
+// false if another worker already claimed it.
+func (r *BookingRepository) MarkReminderSent(id uint, sentAt time.Time) (bool, error) {
+	result := r.db.Model(&model.Booking{}).
+		Where("id = ? AND reminder_sent_at IS NULL", id).
+		Update("reminder_sent_at", sentAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetRecentlyCreated returns bookings created since the given time, newest first
+func (r *BookingRepository) GetRecentlyCreated(since time.Time, limit int) ([]model.Booking, error) {
+	var bookings []model.Booking
+	err := r.db.Preload("Stylist").
+		Where("created_at >= ?", since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// GetRecentStatusChanges returns bookings whose status changed since the
+// given time, most recent change first
+func (r *BookingRepository) GetRecentStatusChanges(since time.Time, limit int) ([]model.Booking, error) {
+	var bookings []model.Booking
+	err := r.db.Preload("Stylist").
+		Where("status_changed_at >= ?", since).
+		Order("status_changed_at DESC").
+		Limit(limit).
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// statusUpdates returns the columns to write when actorID sets a booking's
+// status. status_changed_at/status_changed_by_id only move on rows whose
+// status actually differs.
+func statusUpdates(status string, actorID uint) map[string]interface{} {
+	return map[string]interface{}{
+		"status":               status,
+		"updated_by_id":        actorID,
+		"status_changed_at":    gorm.Expr("CASE WHEN status = ? THEN status_changed_at ELSE NOW() END", status),
+		"status_changed_by_id": gorm.Expr("CASE WHEN status = ? THEN status_changed_by_id ELSE ? END", status, actorID),
+	}
+}
+
+func (r *BookingRepository) UpdateStatus(id uint, status string, actorID uint) error {
+	return r.db.Model(&model.Booking{}).Where("id = ?", id).Updates(statusUpdates(status, actorID)).Error
+}
+
+// Cancel marks a booking cancelled with an optional reason
+func (r *BookingRepository) Cancel(id uint, reason string, actorID uint) error {
+	updates := statusUpdates(model.BookingStatusCancelled, actorID)
+	updates["cancellation_reason"] = reason
+	return r.db.Model(&model.Booking{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// CancelGroup cancels every pending/confirmed booking of a group booking
+func (r *BookingRepository) CancelGroup(groupID uint, reason string, actorID uint) error {
+	updates := statusUpdates(model.BookingStatusCancelled, actorID)
+	updates["cancellation_reason"] = reason
+	return r.db.Model(&model.Booking{}).
+		Where("group_id = ? AND status IN ?", groupID, []string{model.BookingStatusPending, model.BookingStatusConfirmed}).
+		Updates(updates).Error
+}
+
+// Reschedule saves a booking's new stylist, date, time range and services.
+// Like CreateWithAvailabilityCheck, the stylist row is locked while checking
+// for overlapping bookings; returns ErrSlotTaken on a conflict.
+func (r *BookingRepository) Reschedule(booking *model.Booking, actorID uint) error {
+	booking.UpdatedByID = &actorID
+	booking.ReminderSentAt = nil // 新時間需要重新提醒
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var stylist model.Stylist
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&stylist, booking.StylistID).Error; err != nil {
+			return err
+		}
+
+		// The booking itself doesn't count as a conflict
+		var count int64
+		err := overlappingBookings(tx, booking.StylistID, booking.BookingDate, booking.StartTime, booking.OccupiedEndTime).
+			Where("id <> ?", booking.ID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSlotTaken
+		}
+
+		return tx.Model(booking).
+			Select("stylist_id", "booking_date", "start_time", "end_time", "occupied_end_time",
+				"services", "duration", "buffer_minutes", "price", "tax_amount", "total_with_tax",
+				"updated_by_id", "reminder_sent_at").
+			Updates(booking).Error
+	})
+}
+
+// SetTags replaces a booking's tags
+func (r *BookingRepository) SetTags(id uint, tags []string, actorID uint) error {
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&model.Booking{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"tags":          gorm.Expr("?::jsonb", string(encoded)),
+		"updated_by_id": actorID,
+	}).Error
+}
+
+func (r *BookingRepository) UpdateAdminNotes(id uint, notes string, actorID uint) error {
+	return r.db.Model(&model.Booking{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"admin_notes":   notes,
+		"updated_by_id": actorID,
+	}).Error
 }
 
 // Statistics queries
@@ -137,6 +553,203 @@ func (r *BookingRepository) CountByDateRange(startDate, endDate time.Time, statu
 	return count, err
 }
 
+// CountByStatus returns booking counts keyed by status between two dates
+func (r *BookingRepository) CountByStatus(startDate, endDate time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.Model(&model.Booking{}).
+		Select("status, COUNT(*) as count").
+		Where("booking_date BETWEEN ? AND ?", startDate, endDate).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CountUniqueCustomers counts distinct customers with a booking between two dates
+func (r *BookingRepository) CountUniqueCustomers(startDate, endDate time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Booking{}).
+		Where("booking_date BETWEEN ? AND ?", startDate, endDate).
+		Distinct("user_id").
+		Count(&count).Error
+	return count, err
+}
+
+// CountNewCustomers counts customers whose first ever booking falls between two dates
+func (r *BookingRepository) CountNewCustomers(startDate, endDate time.Time) (int64, error) {
+	var count int64
+	query := `
+		SELECT COUNT(*) FROM (
+			SELECT user_id, MIN(booking_date) as first_booking
+			FROM bookings
+			WHERE deleted_at IS NULL
+			GROUP BY user_id
+		) firsts
+		WHERE first_booking BETWEEN ? AND ?
+	`
+	err := r.db.Raw(query, startDate, endDate).Scan(&count).Error
+	return count, err
+}
+
+// CustomerNoShowStats is a customer's no-show record over a period
+type CustomerNoShowStats struct {
+	UserID        uint    `json:"user_id"`
+	Name          string  `json:"name"`
+	Email         string  `json:"email"`
+	Phone         string  `json:"phone"`
+	TotalBookings int64   `json:"total_bookings"`
+	NoShows       int64   `json:"no_shows"`
+	NoShowRate    float64 `json:"no_show_rate"`
+}
+
+// GetNoShowStats ranks customers with at least one no-show in the period by
+// no-show count, then rate. Cancelled bookings don't count towards the total.
+func (r *BookingRepository) GetNoShowStats(startDate, endDate time.Time, limit int) ([]CustomerNoShowStats, error) {
+	var stats []CustomerNoShowStats
+	query := `
+		SELECT
+			users.id AS user_id,
+			users.name AS name,
+			users.email AS email,
+			COALESCE(users.phone, '') AS phone,
+			COUNT(*) AS total_bookings,
+			COUNT(*) FILTER (WHERE bookings.status = ?) AS no_shows,
+			ROUND(COUNT(*) FILTER (WHERE bookings.status = ?)::numeric / COUNT(*), 4) AS no_show_rate
+		FROM bookings
+		JOIN users ON users.id = bookings.user_id
+		WHERE bookings.booking_date BETWEEN ? AND ?
+			AND bookings.status <> ?
+			AND bookings.deleted_at IS NULL
+		GROUP BY users.id, users.name, users.email, users.phone
+		HAVING COUNT(*) FILTER (WHERE bookings.status = ?) > 0
+		ORDER BY no_shows DESC, no_show_rate DESC
+		LIMIT ?
+	`
+	err := r.db.Raw(query,
+		model.BookingStatusNoShow, model.BookingStatusNoShow,
+		startDate, endDate, model.BookingStatusCancelled,
+		model.BookingStatusNoShow, limit,
+	).Scan(&stats).Error
+	return stats, err
+}
+
+// CustomerLifetimeValue summarises a customer's completed bookings
+type CustomerLifetimeValue struct {
+	UserID              uint       `json:"user_id"`
+	CompletedBookings   int64      `json:"completed_bookings"`
+	TotalRevenue        int        `json:"total_revenue"`
+	AverageBookingValue int        `json:"average_booking_value"`
+	FirstVisit          *time.Time `json:"first_visit"`
+	LastVisit           *time.Time `json:"last_visit"`
+	// AverageDaysBetweenVisits is nil until the customer has visited on at
+	// least two different days
+	AverageDaysBetweenVisits *float64           `json:"average_days_between_visits"`
+	FavoriteStylist          *FavoriteStylist   `json:"favorite_stylist"`
+	MostBookedService        *MostBookedService `json:"most_booked_service"`
+}
+
+type FavoriteStylist struct {
+	StylistID uint   `json:"stylist_id"`
+	Name      string `json:"name"`
+	Bookings  int64  `json:"bookings"`
+}
+
+type MostBookedService struct {
+	ServiceID uint   `json:"service_id"`
+	Name      string `json:"name"`
+	Count     int64  `json:"count"`
+}
+
+// GetCustomerLifetimeValue computes a customer's lifetime value from their
+// completed bookings. Customers without any return zero totals and nil
+// dates, stylist and service.
+func (r *BookingRepository) GetCustomerLifetimeValue(userID uint) (*CustomerLifetimeValue, error) {
+	ltv := &CustomerLifetimeValue{UserID: userID}
+
+	var totals struct {
+		CompletedBookings int64
+		TotalRevenue      int
+		FirstVisit        *time.Time
+		LastVisit         *time.Time
+		VisitDays         int64
+	}
+	err := r.db.Model(&model.Booking{}).
+		Select(`COUNT(*) AS completed_bookings,
+			COALESCE(SUM(price), 0) AS total_revenue,
+			MIN(booking_date) AS first_visit,
+			MAX(booking_date) AS last_visit,
+			COUNT(DISTINCT booking_date) AS visit_days`).
+		Where("user_id = ? AND status = ?", userID, model.BookingStatusCompleted).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	if totals.CompletedBookings == 0 {
+		return ltv, nil
+	}
+
+	ltv.CompletedBookings = totals.CompletedBookings
+	ltv.TotalRevenue = totals.TotalRevenue
+	ltv.AverageBookingValue = totals.TotalRevenue / int(totals.CompletedBookings)
+	ltv.FirstVisit = totals.FirstVisit
+	ltv.LastVisit = totals.LastVisit
+	if totals.VisitDays > 1 && totals.FirstVisit != nil && totals.LastVisit != nil {
+		days := totals.LastVisit.Sub(*totals.FirstVisit).Hours() / 24 / float64(totals.VisitDays-1)
+		days = math.Round(days*10) / 10
+		ltv.AverageDaysBetweenVisits = &days
+	}
+
+	var stylists []FavoriteStylist
+	err = r.db.Model(&model.Booking{}).
+		Select("stylists.id AS stylist_id, stylists.name AS name, COUNT(*) AS bookings").
+		Joins("JOIN stylists ON stylists.id = bookings.stylist_id").
+		Where("bookings.user_id = ? AND bookings.status = ?", userID, model.BookingStatusCompleted).
+		Group("stylists.id, stylists.name").
+		Order("bookings DESC, MAX(bookings.booking_date) DESC").
+		Limit(1).
+		Scan(&stylists).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(stylists) > 0 {
+		ltv.FavoriteStylist = &stylists[0]
+	}
+
+	var services []MostBookedService
+	err = r.db.Raw(`
+		SELECT
+			(service->>'id')::bigint AS service_id,
+			service->>'name' AS name,
+			COUNT(*) AS count
+		FROM bookings,
+		jsonb_array_elements(services) AS service
+		WHERE user_id = ?
+			AND status = ?
+			AND deleted_at IS NULL
+		GROUP BY service->>'id', service->>'name'
+		ORDER BY count DESC, MAX(booking_date) DESC
+		LIMIT 1
+	`, userID, model.BookingStatusCompleted).Scan(&services).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(services) > 0 {
+		ltv.MostBookedService = &services[0]
+	}
+
+	return ltv, nil
+}
+
 func (r *BookingRepository) GetRevenueByDateRange(startDate, endDate time.Time) (int, error) {
 	var result struct {
 		TotalRevenue int
@@ -150,6 +763,20 @@ func (r *BookingRepository) GetRevenueByDateRange(startDate, endDate time.Time)
 	return result.TotalRevenue, err
 }
 
+// GetTaxByDateRange sums the tax collected on completed bookings
+func (r *BookingRepository) GetTaxByDateRange(startDate, endDate time.Time) (int, error) {
+	var result struct {
+		TotalTax int
+	}
+	err := r.db.Model(&model.Booking{}).
+		Select("COALESCE(SUM(tax_amount), 0) as total_tax").
+		Where("booking_date BETWEEN ? AND ? AND status = ?",
+			startDate, endDate, model.BookingStatusCompleted).
+		Scan(&result).Error
+
+	return result.TotalTax, err
+}
+
 func (r *BookingRepository) GetRevenueByDay(startDate, endDate time.Time) ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
 	err := r.db.Model(&model.Booking{}).
@@ -163,19 +790,46 @@ func (r *BookingRepository) GetRevenueByDay(startDate, endDate time.Time) ([]map
 	return results, err
 }
 
-func (r *BookingRepository) GetPopularServices(limit int, startDate, endDate time.Time) ([]map[string]interface{}, error) {
+// WeekdayRevenue is the completed bookings and revenue on one day of the week
+type WeekdayRevenue struct {
+	DayOfWeek int // 0=Sunday ... 6=Saturday
+	Bookings  int64
+	Revenue   int
+}
+
+// GetRevenueByWeekday totals completed bookings per day of the week. Only
+// weekdays with bookings are returned. booking_date already holds the
+// salon-local date, so its day of week is the salon's.
+func (r *BookingRepository) GetRevenueByWeekday(startDate, endDate time.Time) ([]WeekdayRevenue, error) {
+	var results []WeekdayRevenue
+	err := r.db.Model(&model.Booking{}).
+		Select("EXTRACT(DOW FROM booking_date)::int AS day_of_week, COUNT(*) AS bookings, COALESCE(SUM(price), 0) AS revenue").
+		Where("booking_date BETWEEN ? AND ? AND status = ?",
+			startDate, endDate, model.BookingStatusCompleted).
+		Group("day_of_week").
+		Scan(&results).Error
+
+	return results, err
+}
+
+// GetPopularServices returns the most booked services between two dates,
+// counting only bookings whose status is in statuses, with the revenue from
+// each service's booked price
+func (r *BookingRepository) GetPopularServices(limit int, startDate, endDate time.Time, statuses []string) ([]map[string]interface{}, error) {
 	// Since services are now stored as JSONB array in bookings, we need to:
 	// 1. Extract service items from the JSONB array
-	// 2. Count each service across all bookings
+	// 2. Count each service across the matching bookings
 	var results []map[string]interface{}
 
 	query := `
 		SELECT
 			service->>'name' as name,
-			COUNT(*) as count
+			COUNT(*) as count,
+			COALESCE(SUM((service->>'price')::int), 0) as revenue
 		FROM bookings,
 		jsonb_array_elements(services) as service
 		WHERE booking_date BETWEEN ? AND ?
+		AND status IN ?
 		AND services IS NOT NULL
 		AND jsonb_array_length(services) > 0
 		AND deleted_at IS NULL
@@ -184,6 +838,6 @@ func (r *BookingRepository) GetPopularServices(limit int, startDate, endDate tim
 		LIMIT ?
 	`
 
-	err := r.db.Raw(query, startDate, endDate, limit).Scan(&results).Error
+	err := r.db.Raw(query, startDate, endDate, statuses, limit).Scan(&results).Error
 	return results, err
 }
@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
+	"gorm.io/gorm"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/testutil"
+)
+
+var testBookingDate = time.Date(2030, 6, 3, 0, 0, 0, 0, time.UTC)
+
+func newTestBooking(userID, stylistID uint, startTime, endTime string) *model.Booking {
+	return &model.Booking{
+		UserID:    userID,
+		StylistID: stylistID,
+		Services: []model.BookingServiceItem{
+			{ID: 1, Name: "Cut", Price: 800, Duration: 60},
+		},
+		BookingDate:     testBookingDate,
+		StartTime:       startTime,
+		EndTime:         endTime,
+		OccupiedEndTime: endTime,
+		Duration:        60,
+		Price:           800,
+		TotalWithTax:    800,
+		Status:          "confirmed",
+		CustomerName:    "Customer",
+		CustomerPhone:   "+886912345678",
+		Tags:            []string{},
+	}
+}
+
+// seedBookingFixtures creates the customer and stylist bookings refer to
+func seedBookingFixtures(t *testing.T, db *gorm.DB) (*model.User, *model.Stylist) {
+	t.Helper()
+	user := &model.User{Name: "Customer", Email: "customer@example.com", PasswordHash: "x"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	stylist := &model.Stylist{Name: "Linda", IsActive: true}
+	if err := db.Create(stylist).Error; err != nil {
+		t.Fatalf("failed to create stylist: %v", err)
+	}
+	return user, stylist
+}
+
+func TestCreateWithAvailabilityCheckSlotTaken(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	repo := NewBookingRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT "id" FROM "stylists" WHERE "stylists"\."id" = \$1 .* FOR UPDATE`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "bookings" WHERE \(stylist_id = \$1 AND booking_date = \$2 AND status IN \(\$3,\$4\)\) AND \(NOT \(occupied_end_time <= \$5 OR start_time >= \$6\)\)`).
+		WithArgs(2, "2030-06-03", "pending", "confirmed", "10:30", "11:30").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := repo.CreateWithAvailabilityCheck(newTestBooking(1, 2, "10:30", "11:30"), "10:30", "11:30")
+	if !errors.Is(err, ErrSlotTaken) {
+		t.Fatalf("CreateWithAvailabilityCheck() error = %v, want ErrSlotTaken", err)
+	}
+}
+
+// Two customers booking the same slot at the same moment: the stylist lock
+// serialises the check-then-insert, so exactly one of them gets the slot
+func TestCreateWithAvailabilityCheckConcurrent(t *testing.T) {
+	db := testutil.PostgresDB(t)
+	repo := NewBookingRepository(db)
+	user, stylist := seedBookingFixtures(t, db)
+
+	start := make(chan struct{})
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.CreateWithAvailabilityCheck(newTestBooking(user.ID, stylist.ID, "10:00", "11:00"), "10:00", "11:00")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrSlotTaken):
+			t.Fatalf("CreateWithAvailabilityCheck() error = %v, want nil or ErrSlotTaken", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d bookings created for the same slot, want exactly 1", created)
+	}
+
+	var count int64
+	if err := db.Model(&model.Booking{}).Where("stylist_id = ?", stylist.ID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count bookings: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("%d bookings stored, want 1", count)
+	}
+}
+
+// referenceCollision is the error Postgres returns when an insert reuses an
+// existing booking reference
+var referenceCollision = &pgconn.PgError{
+	Code:           pgUniqueViolation,
+	ConstraintName: bookingReferenceIndex,
+	Message:        `duplicate key value violates unique constraint "idx_bookings_reference"`,
+}
+
+func TestCreateRetriesReferenceCollision(t *testing.T) {
+	tests := []struct {
+		name       string
+		reference  string  // set on the booking before Create
+		insertErrs []error // result of each insert attempt, nil for success
+		wantErr    bool
+	}{
+		{"no collision", "", []error{nil}, false},
+		{"collision then success", "", []error{referenceCollision, referenceCollision, nil}, false},
+		{"collides on every attempt", "", []error{referenceCollision, referenceCollision, referenceCollision, referenceCollision, referenceCollision}, true},
+		{"caller-chosen reference is not regenerated", "LS-FIXED", []error{referenceCollision}, true},
+		{"other unique violation is not retried", "", []error{&pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "bookings_pkey"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testutil.MockDB(t)
+			repo := NewBookingRepository(db)
+
+			var references []string
+			for _, insertErr := range tt.insertErrs {
+				mock.ExpectBegin()
+				insert := mock.ExpectQuery(`INSERT INTO "bookings"`)
+				if insertErr != nil {
+					insert.WillReturnError(insertErr)
+					mock.ExpectRollback()
+				} else {
+					insert.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+					mock.ExpectCommit()
+				}
+			}
+
+			booking := newTestBooking(1, 2, "10:00", "11:00")
+			booking.Reference = tt.reference
+			db.Callback().Create().Before("gorm:create").Register("test:record_reference", func(tx *gorm.DB) {
+				references = append(references, booking.Reference)
+			})
+
+			err := repo.Create(booking)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(references) != len(tt.insertErrs) {
+				t.Fatalf("%d inserts attempted, want %d", len(references), len(tt.insertErrs))
+			}
+			seen := make(map[string]bool)
+			for _, reference := range references {
+				if tt.reference == "" && seen[reference] {
+					t.Errorf("reference %s was tried twice", reference)
+				}
+				seen[reference] = true
+			}
+			if tt.reference != "" && booking.Reference != tt.reference {
+				t.Errorf("reference = %s, want the caller's %s", booking.Reference, tt.reference)
+			}
+		})
+	}
+}
+
+func TestGetPopularServicesStatusParameter(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	repo := NewBookingRepository(db)
+
+	endDate := testBookingDate.AddDate(0, 0, 30)
+	mock.ExpectQuery(`FROM bookings,\s+jsonb_array_elements\(services\) as service\s+WHERE booking_date BETWEEN \$1 AND \$2\s+AND status IN \(\$3,\$4\)`).
+		WithArgs(testBookingDate, endDate, "completed", "confirmed", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count", "revenue"}).AddRow("Cut", 2, 1600))
+
+	results, err := repo.GetPopularServices(5, testBookingDate, endDate, []string{"completed", "confirmed"})
+	if err != nil {
+		t.Fatalf("GetPopularServices() error = %v", err)
+	}
+	if len(results) != 1 || results[0]["name"] != "Cut" {
+		t.Errorf("GetPopularServices() = %v, want one row for Cut", results)
+	}
+}
+
+// Cancelled bookings must not count towards a service's popularity or revenue
+func TestGetPopularServicesExcludesCancelled(t *testing.T) {
+	db := testutil.PostgresDB(t)
+	repo := NewBookingRepository(db)
+	user, stylist := seedBookingFixtures(t, db)
+
+	seed := []struct {
+		service string
+		price   int
+		status  string
+	}{
+		{"Cut", 800, "completed"},
+		{"Cut", 900, "completed"},
+		{"Cut", 800, "cancelled"},
+		{"Color", 2000, "cancelled"},
+		{"Perm", 3000, "completed"},
+	}
+	for i, s := range seed {
+		booking := newTestBooking(user.ID, stylist.ID, fmt.Sprintf("%02d:00", 10+i), fmt.Sprintf("%02d:00", 11+i))
+		booking.Services = []model.BookingServiceItem{{ID: uint(i + 1), Name: s.service, Price: s.price, Duration: 60}}
+		booking.Status = s.status
+		if err := db.Create(booking).Error; err != nil {
+			t.Fatalf("failed to create booking: %v", err)
+		}
+	}
+
+	results, err := repo.GetPopularServices(10, testBookingDate, testBookingDate, []string{"completed"})
+	if err != nil {
+		t.Fatalf("GetPopularServices() error = %v", err)
+	}
+
+	type stat struct{ count, revenue string }
+	got := make(map[string]stat)
+	for _, row := range results {
+		got[fmt.Sprint(row["name"])] = stat{fmt.Sprint(row["count"]), fmt.Sprint(row["revenue"])}
+	}
+	want := map[string]stat{
+		"Cut":  {"2", "1700"},
+		"Perm": {"1", "3000"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetPopularServices() = %v, want %v", got, want)
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %+v, want %+v", name, got[name], w)
+		}
+	}
+}
+
+// status_changed_at/by only move when the new status differs from the stored
+// one, which the UPDATE decides from the row's current status
+func TestUpdateStatusSQL(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	repo := NewBookingRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "bookings" SET "status"=\$1,` +
+		`"status_changed_at"=CASE WHEN status = \$2 THEN status_changed_at ELSE NOW\(\) END,` +
+		`"status_changed_by_id"=CASE WHEN status = \$3 THEN status_changed_by_id ELSE \$4 END,` +
+		`"updated_by_id"=\$5,"updated_at"=\$6 WHERE id = \$7`).
+		WithArgs("confirmed", "confirmed", "confirmed", 9, 9, sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.UpdateStatus(42, "confirmed", 9); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+}
+
+// Editing a booking or re-saving its current status doesn't count as a
+// status change; changing the status does
+func TestGetRecentStatusChanges(t *testing.T) {
+	db := testutil.PostgresDB(t)
+	repo := NewBookingRepository(db)
+	user, stylist := seedBookingFixtures(t, db)
+	since := time.Now().Add(-time.Minute)
+
+	edited := newTestBooking(user.ID, stylist.ID, "10:00", "11:00")
+	unchanged := newTestBooking(user.ID, stylist.ID, "11:00", "12:00")
+	changed := newTestBooking(user.ID, stylist.ID, "12:00", "13:00")
+	cancelled := newTestBooking(user.ID, stylist.ID, "13:00", "14:00")
+	for _, booking := range []*model.Booking{edited, unchanged, changed, cancelled} {
+		if err := db.Create(booking).Error; err != nil {
+			t.Fatalf("failed to create booking: %v", err)
+		}
+	}
+
+	edited.AdminNotes = "prefers a quiet chair"
+	edited.UpdatedByID = &user.ID
+	if err := repo.Update(edited); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := repo.UpdateStatus(unchanged.ID, unchanged.Status, user.ID); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := repo.UpdateStatus(changed.ID, model.BookingStatusCompleted, user.ID); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := repo.Cancel(cancelled.ID, "sick", user.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	bookings, err := repo.GetRecentStatusChanges(since, 10)
+	if err != nil {
+		t.Fatalf("GetRecentStatusChanges() error = %v", err)
+	}
+	got := map[uint]bool{}
+	for _, booking := range bookings {
+		got[booking.ID] = true
+		if booking.StatusChangedByID == nil || *booking.StatusChangedByID != user.ID {
+			t.Errorf("booking %d StatusChangedByID = %v, want %d", booking.ID, booking.StatusChangedByID, user.ID)
+		}
+	}
+	if len(got) != 2 || !got[changed.ID] || !got[cancelled.ID] {
+		t.Errorf("GetRecentStatusChanges() = %v, want bookings %d and %d", got, changed.ID, cancelled.ID)
+	}
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"linda-salon-api/internal/model"
+)
+
+// ErrInsufficientPoints is returned when an adjustment would make a
+// customer's loyalty balance negative
+var ErrInsufficientPoints = errors.New("loyalty balance cannot go negative")
+
+// errAlreadyAwarded rolls back an award for a booking that was already credited
+var errAlreadyAwarded = errors.New("booking already credited")
+
+type LoyaltyRepository struct {
+	db *gorm.DB
+}
+
+func NewLoyaltyRepository(db *gorm.DB) *LoyaltyRepository {
+	return &LoyaltyRepository{db: db}
+}
+
+// AwardForBooking credits a completed booking's points to its customer.
+// Each booking is credited at most once; it returns false, without changing
+// anything, when the booking was already credited.
+func (r *LoyaltyRepository) AwardForBooking(booking *model.Booking, points int) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// The increment locks the user's row until the transaction ends, so a
+		// concurrent award for the same booking waits here and then sees this
+		// one's entry below
+		balance, err := addLoyaltyPoints(tx, booking.UserID, points)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.LoyaltyEntry{}).Where("booking_id = ?", booking.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errAlreadyAwarded
+		}
+
+		bookingID := booking.ID
+		return tx.Create(&model.LoyaltyEntry{
+			UserID:    booking.UserID,
+			Points:    points,
+			Balance:   balance,
+			Reason:    "Booking " + booking.Reference + " completed",
+			BookingID: &bookingID,
+		}).Error
+	})
+	if errors.Is(err, errAlreadyAwarded) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Adjust adds (or, when negative, deducts) points by hand. Returns
+// ErrInsufficientPoints when the balance would go below zero.
+func (r *LoyaltyRepository) Adjust(userID uint, points int, reason string, actorID uint) (*model.LoyaltyEntry, error) {
+	var entry *model.LoyaltyEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		balance, err := addLoyaltyPoints(tx, userID, points)
+		if err != nil {
+			return err
+		}
+
+		entry = &model.LoyaltyEntry{
+			UserID:      userID,
+			Points:      points,
+			Balance:     balance,
+			Reason:      reason,
+			ActorUserID: &actorID,
+		}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListByUser returns a customer's ledger entries newest first, along with
+// the total count
+func (r *LoyaltyRepository) ListByUser(userID uint, limit, offset int) ([]model.LoyaltyEntry, int64, error) {
+	var entries []model.LoyaltyEntry
+	var total int64
+
+	query := r.db.Model(&model.LoyaltyEntry{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").
+		Limit(limit).Offset(offset).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// addLoyaltyPoints adds delta (negative to deduct) to a user's loyalty
+// balance with a single UPDATE and returns the new balance. The arithmetic
+// happens in SQL rather than as a read-modify-write in Go, so concurrent
+// changes can't overwrite each other. A deduction that would make the
+// balance negative changes nothing and returns ErrInsufficientPoints; an
+// unknown user returns gorm.ErrRecordNotFound.
+func addLoyaltyPoints(tx *gorm.DB, userID uint, delta int) (int, error) {
+	var balances []int
+	err := tx.Raw(`
+		UPDATE users
+		SET loyalty_points = loyalty_points + ?, updated_at = NOW()
+		WHERE id = ? AND deleted_at IS NULL AND loyalty_points + ? >= 0
+		RETURNING loyalty_points
+	`, delta, userID, delta).Scan(&balances).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(balances) > 0 {
+		return balances[0], nil
+	}
+
+	var count int64
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return 0, ErrInsufficientPoints
+}
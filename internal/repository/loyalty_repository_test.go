@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/testutil"
+)
+
+// The balance must change through loyalty_points = loyalty_points + ?, never
+// by writing back a value computed from an earlier read
+const incrementSQL = `UPDATE users\s+SET loyalty_points = loyalty_points \+ \$1, updated_at = NOW\(\)\s+WHERE id = \$2 AND deleted_at IS NULL AND loyalty_points \+ \$3 >= 0\s+RETURNING loyalty_points`
+
+func TestLoyaltyAdjust(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    int
+		balance   int   // returned by the UPDATE, -1 when no row matched
+		userCount int64 // users found when no row matched
+		wantErr   error
+	}{
+		{"add points", 50, 150, 0, nil},
+		{"deduct points", -30, 70, 0, nil},
+		{"deduct more than the balance", -500, -1, 1, ErrInsufficientPoints},
+		{"unknown user", 10, -1, 0, gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testutil.MockDB(t)
+			repo := NewLoyaltyRepository(db)
+
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"loyalty_points"})
+			if tt.balance >= 0 {
+				rows.AddRow(tt.balance)
+			}
+			mock.ExpectQuery(incrementSQL).WithArgs(tt.points, 7, tt.points).WillReturnRows(rows)
+			if tt.wantErr == nil {
+				mock.ExpectQuery(`INSERT INTO "loyalty_entries"`).
+					WithArgs(sqlmock.AnyArg(), 7, tt.points, tt.balance, "manual", nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE id = \$1`).
+					WithArgs(7).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.userCount))
+				mock.ExpectRollback()
+			}
+
+			entry, err := repo.Adjust(7, tt.points, "manual", 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Adjust() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && entry.Balance != tt.balance {
+				t.Errorf("entry balance = %d, want %d", entry.Balance, tt.balance)
+			}
+		})
+	}
+}
+
+func TestLoyaltyAwardForBookingAlreadyCredited(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	repo := NewLoyaltyRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(incrementSQL).WithArgs(20, 7, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"loyalty_points"}).AddRow(120))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "loyalty_entries" WHERE booking_id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	// The increment is rolled back along with the rest of the transaction
+	mock.ExpectRollback()
+
+	awarded, err := repo.AwardForBooking(&model.Booking{ID: 3, UserID: 7, Reference: "LS-TEST"}, 20)
+	if err != nil {
+		t.Fatalf("AwardForBooking() error = %v", err)
+	}
+	if awarded {
+		t.Error("AwardForBooking() = true for a booking that was already credited")
+	}
+}
+
+// Concurrent completions and adjustments must all land: with a
+// read-modify-write some of them would overwrite each other's balance
+func TestLoyaltyConcurrentIncrements(t *testing.T) {
+	db := testutil.PostgresDB(t)
+	repo := NewLoyaltyRepository(db)
+
+	user := &model.User{Name: "Customer", Email: "customer@example.com", PasswordHash: "x"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	const bookings = 20
+	const adjustments = 20
+	const awardsPerBooking = 3 // each booking is completed several times at once
+
+	var wg sync.WaitGroup
+	errs := make(chan error, bookings*awardsPerBooking+adjustments)
+	awards := make(chan bool, bookings*awardsPerBooking)
+	for i := 0; i < bookings; i++ {
+		booking := &model.Booking{ID: uint(1000 + i), UserID: user.ID, Reference: fmt.Sprintf("LS-%d", i)}
+		for j := 0; j < awardsPerBooking; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				awarded, err := repo.AwardForBooking(booking, 10)
+				errs <- err
+				awards <- awarded
+			}()
+		}
+	}
+	for i := 0; i < adjustments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Adjust(user.ID, 1, "bonus", 1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	close(awards)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent update failed: %v", err)
+		}
+	}
+	awarded := 0
+	for ok := range awards {
+		if ok {
+			awarded++
+		}
+	}
+	if awarded != bookings {
+		t.Errorf("%d awards succeeded, want one per booking (%d)", awarded, bookings)
+	}
+
+	var got model.User
+	if err := db.First(&got, user.ID).Error; err != nil {
+		t.Fatalf("failed to reload user: %v", err)
+	}
+	if want := bookings*10 + adjustments; got.LoyaltyPoints != want {
+		t.Errorf("loyalty_points = %d, want %d", got.LoyaltyPoints, want)
+	}
+
+	// Every ledger entry's balance is distinct, i.e. no two changes were
+	// applied on top of the same starting balance
+	var entries []model.LoyaltyEntry
+	if err := db.Where("user_id = ?", user.ID).Order("balance").Find(&entries).Error; err != nil {
+		t.Fatalf("failed to load ledger: %v", err)
+	}
+	if len(entries) != bookings+adjustments {
+		t.Fatalf("%d ledger entries, want %d", len(entries), bookings+adjustments)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Balance == entries[i-1].Balance {
+			t.Errorf("two ledger entries share balance %d", entries[i].Balance)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// PurgeBatchSize is how many soft-deleted rows are hard-deleted per statement
+const PurgeBatchSize = 500
+
+// purgeSoftDeleted hard-deletes soft-deleted rows of model matching query in
+// batches of PurgeBatchSize. before, when set, runs inside each batch's
+// transaction to clean up rows that reference the IDs about to be removed.
+func purgeSoftDeleted(db *gorm.DB, model interface{}, query func(tx *gorm.DB) *gorm.DB, before func(tx *gorm.DB, ids []uint) error) (int64, error) {
+	var purged int64
+	for {
+		var ids []uint
+		err := query(db.Unscoped().Model(model)).
+			Where("deleted_at IS NOT NULL").
+			Order("id").
+			Limit(PurgeBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		var deleted int64
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if before != nil {
+				if err := before(tx, ids); err != nil {
+					return err
+				}
+			}
+			result := tx.Unscoped().Delete(model, ids)
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += deleted
+
+		if len(ids) < PurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
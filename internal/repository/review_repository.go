@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"linda-salon-api/internal/model"
+)
+
+type ReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+func (r *ReviewRepository) Create(review *model.Review) error {
+	return r.db.Create(review).Error
+}
+
+// ReviewedBookingIDs returns which of the given bookings already have a review
+func (r *ReviewRepository) ReviewedBookingIDs(bookingIDs []uint) (map[uint]bool, error) {
+	reviewed := make(map[uint]bool)
+	if len(bookingIDs) == 0 {
+		return reviewed, nil
+	}
+
+	var ids []uint
+	err := r.db.Model(&model.Review{}).
+		Where("booking_id IN ?", bookingIDs).
+		Pluck("booking_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		reviewed[id] = true
+	}
+	return reviewed, nil
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"errors"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 	"linda-salon-api/internal/model"
@@ -39,20 +42,260 @@ func (r *ServiceRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Service{}, id).Error
 }
 
-func (r *ServiceRepository) List(category string, activeOnly bool) ([]model.Service, error) {
+// Restore undoes a soft delete. It reports false if the service doesn't
+// exist or isn't deleted.
+func (r *ServiceRepository) Restore(id uint) (bool, error) {
+	result := r.db.Unscoped().Model(&model.Service{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetVariants returns a service's variants ordered by price
+func (r *ServiceRepository) GetVariants(serviceID uint) ([]model.ServiceVariant, error) {
+	var variants []model.ServiceVariant
+	err := r.db.Where("service_id = ?", serviceID).
+		Order("price_delta, id").
+		Find(&variants).Error
+	return variants, err
+}
+
+func (r *ServiceRepository) GetVariant(id uint) (*model.ServiceVariant, error) {
+	var variant model.ServiceVariant
+	err := r.db.First(&variant, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *ServiceRepository) CreateVariant(variant *model.ServiceVariant) error {
+	return r.db.Create(variant).Error
+}
+
+func (r *ServiceRepository) UpdateVariant(variant *model.ServiceVariant) error {
+	return r.db.Save(variant).Error
+}
+
+func (r *ServiceRepository) DeleteVariant(id uint) error {
+	return r.db.Delete(&model.ServiceVariant{}, id).Error
+}
+
+func (r *ServiceRepository) CreateBlackout(blackout *model.ServiceBlackout) error {
+	return r.db.Create(blackout).Error
+}
+
+func (r *ServiceRepository) UpdateBlackout(blackout *model.ServiceBlackout) error {
+	return r.db.Save(blackout).Error
+}
+
+func (r *ServiceRepository) DeleteBlackout(id uint) error {
+	return r.db.Delete(&model.ServiceBlackout{}, id).Error
+}
+
+func (r *ServiceRepository) GetBlackout(id uint) (*model.ServiceBlackout, error) {
+	var blackout model.ServiceBlackout
+	err := r.db.First(&blackout, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &blackout, nil
+}
+
+// GetBlackouts returns blackouts overlapping startDate..endDate (inclusive),
+// ordered by start date. A nil serviceIDs matches every service.
+func (r *ServiceRepository) GetBlackouts(serviceIDs []uint, startDate, endDate time.Time) ([]model.ServiceBlackout, error) {
+	var blackouts []model.ServiceBlackout
+	query := r.db.Where("start_date <= ? AND end_date >= ?",
+		endDate.Format("2006-01-02"), startDate.Format("2006-01-02"))
+	if serviceIDs != nil {
+		query = query.Where("service_id IN ?", serviceIDs)
+	}
+	err := query.Order("start_date, service_id, id").Find(&blackouts).Error
+	return blackouts, err
+}
+
+// GetBlackoutsFrom returns blackouts that haven't ended before date, for
+// services that are still active
+func (r *ServiceRepository) GetBlackoutsFrom(date time.Time) ([]model.ServiceBlackout, error) {
+	var blackouts []model.ServiceBlackout
+	err := r.db.
+		Joins("JOIN services ON services.id = service_blackouts.service_id AND services.deleted_at IS NULL AND services.is_active").
+		Where("service_blackouts.end_date >= ?", date.Format("2006-01-02")).
+		Order("service_blackouts.start_date, service_blackouts.service_id, service_blackouts.id").
+		Find(&blackouts).Error
+	return blackouts, err
+}
+
+// ErrCategoryInUse is returned when deleting a category services still use
+var ErrCategoryInUse = errors.New("category is used by services")
+
+// ListCategories returns categories by display order, then name
+func (r *ServiceRepository) ListCategories(activeOnly bool) ([]model.Category, error) {
+	var categories []model.Category
+	query := r.db.Order("display_order, name, id")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Find(&categories).Error
+	return categories, err
+}
+
+func (r *ServiceRepository) GetCategory(id uint) (*model.Category, error) {
+	var category model.Category
+	err := r.db.First(&category, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *ServiceRepository) GetCategoryBySlug(slug string) (*model.Category, error) {
+	var category model.Category
+	err := r.db.Where("slug = ?", slug).First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindCategory looks a category up by ID when ref is numeric, otherwise by slug
+func (r *ServiceRepository) FindCategory(ref string) (*model.Category, error) {
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return r.GetCategory(uint(id))
+	}
+	return r.GetCategoryBySlug(ref)
+}
+
+func (r *ServiceRepository) CreateCategory(category *model.Category) error {
+	return r.db.Create(category).Error
+}
+
+// UpdateCategory saves category and, when its slug changed from oldSlug,
+// updates the category string mirrored on its services (including
+// soft-deleted ones, so a restore comes back consistent)
+func (r *ServiceRepository) UpdateCategory(category *model.Category, oldSlug string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		if category.Slug == oldSlug {
+			return nil
+		}
+		return tx.Unscoped().Model(&model.Service{}).
+			Where("category_id = ?", category.ID).
+			Update("category", category.Slug).Error
+	})
+}
+
+// DeleteCategory deletes a category no service refers to, soft-deleted
+// services included; otherwise it returns ErrCategoryInUse
+func (r *ServiceRepository) DeleteCategory(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&model.Service{}).Where("category_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCategoryInUse
+		}
+		return tx.Delete(&model.Category{}, id).Error
+	})
+}
+
+// Service list sort orders
+const (
+	ServiceSortPriceAsc  = "price_asc"
+	ServiceSortPriceDesc = "price_desc"
+	ServiceSortName      = "name"
+	ServiceSortDuration  = "duration"
+)
+
+var serviceSortOrders = map[string]string{
+	"":                   "category, name",
+	ServiceSortPriceAsc:  "price, name",
+	ServiceSortPriceDesc: "price DESC, name",
+	ServiceSortName:      "name",
+	ServiceSortDuration:  "duration, name",
+}
+
+// ValidServiceSort reports whether sort is a supported ServiceListOptions.Sort
+func ValidServiceSort(sort string) bool {
+	_, ok := serviceSortOrders[sort]
+	return ok
+}
+
+// ServiceListOptions filters and pages ServiceRepository.List
+type ServiceListOptions struct {
+	Category   string // category slug
+	CategoryID uint
+	ActiveOnly bool
+	Search     string // case-insensitive match on name or description
+	Sort       string // one of the ServiceSort constants; default is category, name
+	Limit      int    // 0 means no limit
+	Offset     int
+
+	IncludeDeleted bool // also return soft-deleted services
+}
+
+// List returns the services matching opts and the total number of matches
+// before paging
+func (r *ServiceRepository) List(opts ServiceListOptions) ([]model.Service, int64, error) {
 	var services []model.Service
+	var total int64
 	query := r.db.Model(&model.Service{})
+	if opts.IncludeDeleted {
+		query = r.db.Unscoped().Model(&model.Service{})
+	}
 
-	if category != "" {
-		query = query.Where("category = ?", category)
+	if opts.Category != "" {
+		query = query.Where("category = ?", opts.Category)
+	}
+	if opts.CategoryID != 0 {
+		query = query.Where("category_id = ?", opts.CategoryID)
 	}
 
-	if activeOnly {
+	if opts.ActiveOnly {
 		query = query.Where("is_active = ?", true)
 	}
 
-	err := query.Order("category, name").Find(&services).Error
-	return services, err
+	if opts.Search != "" {
+		pattern := "%" + escapeLike(opts.Search) + "%"
+		query = query.Where("(name ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order, ok := serviceSortOrders[opts.Sort]
+	if !ok {
+		order = serviceSortOrders[""]
+	}
+	query = query.Order(order).Offset(opts.Offset)
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	err := query.Find(&services).Error
+	return services, total, err
+}
+
+// escapeLike escapes LIKE wildcards so s is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func (r *ServiceRepository) GetByCategory(category string) ([]model.Service, error) {
@@ -63,6 +306,21 @@ func (r *ServiceRepository) GetByCategory(category string) ([]model.Service, err
 	return services, err
 }
 
+// CategoryExists reports whether any service uses the category
+func (r *ServiceRepository) CategoryExists(category string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Service{}).Where("category = ?", category).Count(&count).Error
+	return count > 0, err
+}
+
+// SetActiveByCategory activates or deactivates every service in a category
+func (r *ServiceRepository) SetActiveByCategory(category string, active bool) (int64, error) {
+	result := r.db.Model(&model.Service{}).
+		Where("category = ?", category).
+		Update("is_active", active)
+	return result.RowsAffected, result.Error
+}
+
 func (r *ServiceRepository) GetPopular(limit int) ([]model.Service, error) {
 	var services []model.Service
 	err := r.db.
@@ -75,3 +333,16 @@ func (r *ServiceRepository) GetPopular(limit int) ([]model.Service, error) {
 		Find(&services).Error
 	return services, err
 }
+
+// PurgeDeleted hard-deletes services soft-deleted before cutoff along with
+// their variants and stylist mappings. Bookings keep their own copy of service details.
+func (r *ServiceRepository) PurgeDeleted(cutoff time.Time) (int64, error) {
+	return purgeSoftDeleted(r.db, &model.Service{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("deleted_at < ?", cutoff)
+	}, func(tx *gorm.DB, ids []uint) error {
+		if err := tx.Unscoped().Where("service_id IN ?", ids).Delete(&model.ServiceVariant{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("service_id IN ?", ids).Delete(&model.StylistService{}).Error
+	})
+}
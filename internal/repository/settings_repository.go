@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"encoding/json"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"linda-salon-api/internal/model"
 )
 
@@ -23,6 +26,39 @@ func (r *SettingsRepository) Get(key string) (*model.Settings, error) {
 	return &settings, nil
 }
 
+// GetValue 取得設定並將 JSON 值解析到 dest，設定不存在時回傳 false
+func (r *SettingsRepository) GetValue(key string, dest interface{}) (bool, error) {
+	settings, err := r.Get(key)
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(settings.Value), dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetBool 取得布林設定，設定不存在時回傳 defaultValue
+func (r *SettingsRepository) GetBool(key string, defaultValue bool) (bool, error) {
+	value := defaultValue
+	if _, err := r.GetValue(key, &value); err != nil {
+		return defaultValue, err
+	}
+	return value, nil
+}
+
+// GetFloat 取得數值設定，設定不存在時回傳 defaultValue
+func (r *SettingsRepository) GetFloat(key string, defaultValue float64) (float64, error) {
+	value := defaultValue
+	if _, err := r.GetValue(key, &value); err != nil {
+		return defaultValue, err
+	}
+	return value, nil
+}
+
 // GetAll 取得所有設定
 func (r *SettingsRepository) GetAll() ([]model.Settings, error) {
 	var settings []model.Settings
@@ -56,6 +92,54 @@ func (r *SettingsRepository) Upsert(settings *model.Settings) error {
 	return r.db.Save(settings).Error
 }
 
+// CreateIfAbsent 僅在設定不存在時建立，回傳是否有新增
+func (r *SettingsRepository) CreateIfAbsent(settings *model.Settings) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoNothing: true,
+	}).Create(settings)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// IncrementJSONInt 以單一 UPDATE 原子地遞增 JSON 設定中的整數欄位，回傳新值。
+// 設定不存在時回傳 gorm.ErrRecordNotFound
+func (r *SettingsRepository) IncrementJSONInt(key, field string, delta int) (int, error) {
+	return r.incrementJSON(key, "value::jsonb", nil, field, delta)
+}
+
+// ReplaceJSONIncrementing 以 value 取代 JSON 設定，並在同一個 UPDATE 中把 field
+// 設為資料庫目前的值加上 delta，避免與同時進行的遞增互相覆蓋。回傳新值
+func (r *SettingsRepository) ReplaceJSONIncrementing(key string, value interface{}, field string, delta int) (int, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	return r.incrementJSON(key, "?::jsonb", []interface{}{string(raw)}, field, delta)
+}
+
+func (r *SettingsRepository) incrementJSON(key, base string, baseArgs []interface{}, field string, delta int) (int, error) {
+	var results []int
+	query := `
+		UPDATE settings
+		SET value = jsonb_set(` + base + `, ARRAY[?::text],
+				to_jsonb(COALESCE((value::jsonb->>?)::int, 0) + ?))::text,
+			updated_at = NOW()
+		WHERE key = ?
+		RETURNING (value::jsonb->>?)::int
+	`
+	args := append(baseArgs, field, field, delta, key, field)
+	if err := r.db.Raw(query, args...).Scan(&results).Error; err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return results[0], nil
+}
+
 // Delete 刪除設定
 func (r *SettingsRepository) Delete(key string) error {
 	return r.db.Where("key = ?", key).Delete(&model.Settings{}).Error
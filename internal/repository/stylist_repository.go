@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"linda-salon-api/internal/model"
 )
 
@@ -22,7 +23,7 @@ func (r *StylistRepository) Create(stylist *model.Stylist) error {
 
 func (r *StylistRepository) GetByID(id uint) (*model.Stylist, error) {
 	var stylist model.Stylist
-	err := r.db.Preload("Schedules").First(&stylist, id).Error
+	err := r.db.Preload("Schedules").Preload("Capabilities").First(&stylist, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -36,13 +37,31 @@ func (r *StylistRepository) Update(stylist *model.Stylist) error {
 	return r.db.Save(stylist).Error
 }
 
+// Restore undoes a soft delete. It reports false if the stylist doesn't
+// exist or isn't deleted.
+func (r *StylistRepository) Restore(id uint) (bool, error) {
+	result := r.db.Unscoped().Model(&model.Stylist{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	return result.RowsAffected > 0, result.Error
+}
+
 func (r *StylistRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Stylist{}, id).Error
 }
 
 func (r *StylistRepository) List(activeOnly bool) ([]model.Stylist, error) {
+	return r.list(r.db, activeOnly)
+}
+
+// ListIncludingDeleted is List with soft-deleted stylists too
+func (r *StylistRepository) ListIncludingDeleted(activeOnly bool) ([]model.Stylist, error) {
+	return r.list(r.db.Unscoped(), activeOnly)
+}
+
+func (r *StylistRepository) list(db *gorm.DB, activeOnly bool) ([]model.Stylist, error) {
 	var stylists []model.Stylist
-	query := r.db.Preload("Schedules")
+	query := db.Preload("Schedules")
 
 	if activeOnly {
 		query = query.Where("is_active = ?", true)
@@ -52,6 +71,25 @@ func (r *StylistRepository) List(activeOnly bool) ([]model.Stylist, error) {
 	return stylists, err
 }
 
+// SpecialtyCount is a stylist specialty and how many active stylists have it
+type SpecialtyCount struct {
+	Specialty string `json:"specialty"`
+	Count     int64  `json:"count"`
+}
+
+// GetSpecialties returns the distinct non-empty specialties of active
+// stylists, most common first
+func (r *StylistRepository) GetSpecialties() ([]SpecialtyCount, error) {
+	var specialties []SpecialtyCount
+	err := r.db.Model(&model.Stylist{}).
+		Select("TRIM(specialty) AS specialty, COUNT(*) AS count").
+		Where("is_active = ? AND TRIM(COALESCE(specialty, '')) <> ''", true).
+		Group("TRIM(specialty)").
+		Order("count DESC, specialty").
+		Scan(&specialties).Error
+	return specialties, err
+}
+
 // Schedule management
 func (r *StylistRepository) CreateSchedule(schedule *model.StylistSchedule) error {
 	return r.db.Create(schedule).Error
@@ -65,6 +103,27 @@ func (r *StylistRepository) DeleteSchedule(id uint) error {
 	return r.db.Delete(&model.StylistSchedule{}, id).Error
 }
 
+// CreateSchedules inserts several schedules in a single transaction
+func (r *StylistRepository) CreateSchedules(schedules []model.StylistSchedule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&schedules).Error
+	})
+}
+
+// ReplaceSchedules deletes all of a stylist's schedules and creates the given
+// ones in a single transaction
+func (r *StylistRepository) ReplaceSchedules(stylistID uint, schedules []model.StylistSchedule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("stylist_id = ?", stylistID).Delete(&model.StylistSchedule{}).Error; err != nil {
+			return err
+		}
+		if len(schedules) == 0 {
+			return nil
+		}
+		return tx.Create(&schedules).Error
+	})
+}
+
 func (r *StylistRepository) GetSchedulesByStylistID(stylistID uint) ([]model.StylistSchedule, error) {
 	var schedules []model.StylistSchedule
 	err := r.db.Where("stylist_id = ? AND is_active = ?", stylistID, true).
@@ -73,8 +132,114 @@ func (r *StylistRepository) GetSchedulesByStylistID(stylistID uint) ([]model.Sty
 	return schedules, err
 }
 
-// Check if stylist is available at given time
-func (r *StylistRepository) IsAvailable(stylistID uint, date time.Time, startTime, endTime string) (bool, error) {
+func (r *StylistRepository) GetSchedulesByStylistIDs(stylistIDs []uint) ([]model.StylistSchedule, error) {
+	var schedules []model.StylistSchedule
+	err := r.db.Where("stylist_id IN ? AND is_active = ?", stylistIDs, true).
+		Order("stylist_id, day_of_week, start_time").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// Service capability mapping
+func (r *StylistRepository) HasServiceMappings() (bool, error) {
+	var count int64
+	err := r.db.Model(&model.StylistService{}).Count(&count).Error
+	return count > 0, err
+}
+
+// GetServiceMappings returns the service mappings of the given stylists
+func (r *StylistRepository) GetServiceMappings(stylistIDs []uint) ([]model.StylistService, error) {
+	var mappings []model.StylistService
+	err := r.db.Where("stylist_id IN ?", stylistIDs).
+		Order("stylist_id, service_id").
+		Find(&mappings).Error
+	return mappings, err
+}
+
+// GetServices returns the active services the stylist is qualified for
+func (r *StylistRepository) GetServices(stylistID uint) ([]model.Service, error) {
+	var services []model.Service
+	err := r.db.Model(&model.Service{}).
+		Joins("JOIN stylist_services ON stylist_services.service_id = services.id").
+		Where("stylist_services.stylist_id = ? AND services.is_active = ?", stylistID, true).
+		Order("services.category, services.name").
+		Find(&services).Error
+	return services, err
+}
+
+// AddServices maps the stylist to the services, skipping existing mappings
+func (r *StylistRepository) AddServices(stylistID uint, serviceIDs []uint) error {
+	if len(serviceIDs) == 0 {
+		return nil
+	}
+	mappings := make([]model.StylistService, 0, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		mappings = append(mappings, model.StylistService{StylistID: stylistID, ServiceID: serviceID})
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&mappings).Error
+}
+
+// RemoveService removes a stylist–service mapping, returning whether one existed
+func (r *StylistRepository) RemoveService(stylistID, serviceID uint) (bool, error) {
+	result := r.db.Where("stylist_id = ? AND service_id = ?", stylistID, serviceID).
+		Delete(&model.StylistService{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetStylistIDsForService returns the active stylists mapped to a service
+func (r *StylistRepository) GetStylistIDsForService(serviceID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Stylist{}).
+		Joins("JOIN stylist_services ON stylist_services.stylist_id = stylists.id").
+		Where("stylist_services.service_id = ? AND stylists.is_active = ?", serviceID, true).
+		Order("stylists.id").
+		Pluck("stylists.id", &ids).Error
+	return ids, err
+}
+
+func (r *StylistRepository) CreateTimeOff(timeOff *model.StylistTimeOff) error {
+	return r.db.Create(timeOff).Error
+}
+
+func (r *StylistRepository) UpdateTimeOff(timeOff *model.StylistTimeOff) error {
+	return r.db.Save(timeOff).Error
+}
+
+func (r *StylistRepository) DeleteTimeOff(id uint) error {
+	return r.db.Delete(&model.StylistTimeOff{}, id).Error
+}
+
+func (r *StylistRepository) GetTimeOff(id uint) (*model.StylistTimeOff, error) {
+	var timeOff model.StylistTimeOff
+	err := r.db.First(&timeOff, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &timeOff, nil
+}
+
+// GetTimeOffByStylistsAndDateRange returns time off for the given stylists
+// between two dates (inclusive)
+func (r *StylistRepository) GetTimeOffByStylistsAndDateRange(stylistIDs []uint, startDate, endDate time.Time) ([]model.StylistTimeOff, error) {
+	var timeOff []model.StylistTimeOff
+	err := r.db.
+		Where("stylist_id IN ? AND date BETWEEN ? AND ?",
+			stylistIDs, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")).
+		Order("date, start_time").
+		Find(&timeOff).Error
+	return timeOff, err
+}
+
+// Check if stylist is available at given time. endTime should include any
+// cleanup buffer, and existing bookings and holds are compared by their
+// occupied end. Time off blocks the slot regardless of the weekly schedule. Unexpired holds block the slot too, except those owned by
+// holderID (the caller's own holds).
+// excludeBookingID, when set, is left out of the conflict check so a booking
+// being rescheduled doesn't conflict with itself.
+func (r *StylistRepository) IsAvailable(stylistID uint, date time.Time, startTime, endTime string, holderID uint, excludeBookingID *uint) (bool, error) {
 	dayOfWeek := int(date.Weekday())
 
 	// Check if stylist has schedule for this day
@@ -93,12 +258,38 @@ func (r *StylistRepository) IsAvailable(stylistID uint, date time.Time, startTim
 		return false, nil
 	}
 
-	// Check for conflicting bookings
+	// Check for time off: a full day (empty times) or an overlapping range
 	var count int64
-	err = r.db.Model(&model.Booking{}).
-		Where("stylist_id = ? AND booking_date = ? AND status IN ?",
-			stylistID, date.Format("2006-01-02"), []string{"pending", "confirmed"}).
-		Where("NOT (end_time <= ? OR start_time >= ?)", startTime, endTime).
+	err = r.db.Model(&model.StylistTimeOff{}).
+		Where("stylist_id = ? AND date = ?", stylistID, date.Format("2006-01-02")).
+		Where("(start_time = '' OR start_time IS NULL OR NOT (end_time <= ? OR start_time >= ?))", startTime, endTime).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	// Check for conflicting bookings
+	query := overlappingBookings(r.db, stylistID, date, startTime, endTime)
+	if excludeBookingID != nil {
+		query = query.Where("id <> ?", *excludeBookingID)
+	}
+	err = query.Count(&count).Error
+
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	// Check for unexpired holds by other customers
+	err = r.db.Model(&model.BookingHold{}).
+		Where("stylist_id = ? AND booking_date = ? AND expires_at > ? AND user_id <> ?",
+			stylistID, date.Format("2006-01-02"), time.Now(), holderID).
+		Where("NOT (occupied_end_time <= ? OR start_time >= ?)", startTime, endTime).
 		Count(&count).Error
 
 	if err != nil {
@@ -124,3 +315,52 @@ func (r *StylistRepository) GetTopStylists(limit int, startDate, endDate time.Ti
 
 	return results, err
 }
+
+// StylistStats is one stylist's bookings and revenue over a date range.
+// Bookings counts every booking except cancelled ones; Revenue is the
+// pre-tax price of completed bookings.
+type StylistStats struct {
+	StylistID uint   `json:"stylist_id"`
+	Name      string `json:"name"`
+	IsActive  bool   `json:"is_active"`
+	Bookings  int64  `json:"bookings"`
+	Completed int64  `json:"completed"`
+	NoShows   int64  `json:"no_shows"`
+	Revenue   int    `json:"revenue"`
+}
+
+// Sort orders for GetStylistStats
+const (
+	StylistStatsSortRevenue  = "revenue"
+	StylistStatsSortBookings = "bookings"
+)
+
+// GetStylistStats returns booking and revenue totals between two dates for
+// every stylist, including those without bookings. Inactive stylists are
+// left out unless includeInactive is set; deleted ones always are.
+func (r *StylistRepository) GetStylistStats(startDate, endDate time.Time, includeInactive bool, sort string) ([]StylistStats, error) {
+	query := r.db.Model(&model.Stylist{}).
+		Select(`stylists.id AS stylist_id, stylists.name, stylists.is_active,
+			COUNT(bookings.id) AS bookings,
+			COUNT(CASE WHEN bookings.status = ? THEN 1 END) AS completed,
+			COUNT(CASE WHEN bookings.status = ? THEN 1 END) AS no_shows,
+			COALESCE(SUM(CASE WHEN bookings.status = ? THEN bookings.price ELSE 0 END), 0) AS revenue`,
+			model.BookingStatusCompleted, model.BookingStatusNoShow, model.BookingStatusCompleted).
+		Joins("LEFT JOIN bookings ON bookings.stylist_id = stylists.id AND bookings.status <> ? AND bookings.booking_date BETWEEN ? AND ? AND bookings.deleted_at IS NULL",
+			model.BookingStatusCancelled, startDate, endDate).
+		Group("stylists.id, stylists.name, stylists.is_active")
+
+	if !includeInactive {
+		query = query.Where("stylists.is_active = ?", true)
+	}
+
+	if sort == StylistStatsSortBookings {
+		query = query.Order("bookings DESC, revenue DESC, stylists.name")
+	} else {
+		query = query.Order("revenue DESC, bookings DESC, stylists.name")
+	}
+
+	stats := []StylistStats{}
+	err := query.Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/testutil"
+)
+
+// Rescheduling a booking by a small amount must not conflict with the
+// booking itself, but still conflicts with every other booking
+func TestIsAvailableExcludesBooking(t *testing.T) {
+	db := testutil.PostgresDB(t)
+	repo := NewStylistRepository(db)
+	user, stylist := seedBookingFixtures(t, db)
+
+	schedule := &model.StylistSchedule{
+		StylistID: stylist.ID,
+		DayOfWeek: int(testBookingDate.Weekday()),
+		StartTime: "10:00",
+		EndTime:   "19:00",
+		IsActive:  true,
+	}
+	if err := db.Create(schedule).Error; err != nil {
+		t.Fatalf("failed to create schedule: %v", err)
+	}
+	edited := newTestBooking(user.ID, stylist.ID, "10:00", "11:00")
+	other := newTestBooking(user.ID, stylist.ID, "13:00", "14:00")
+	for _, booking := range []*model.Booking{edited, other} {
+		if err := db.Create(booking).Error; err != nil {
+			t.Fatalf("failed to create booking: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		startTime string
+		endTime   string
+		exclude   *uint
+		want      bool
+	}{
+		{"moved 30 minutes, excluding itself", "10:30", "11:30", &edited.ID, true},
+		{"moved 30 minutes, without exclusion", "10:30", "11:30", nil, false},
+		{"moved onto another booking", "12:30", "13:30", &edited.ID, false},
+		{"excluding a different booking", "10:30", "11:30", &other.ID, false},
+		{"free slot", "15:00", "16:00", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.IsAvailable(stylist.ID, testBookingDate, tt.startTime, tt.endTime, user.ID, tt.exclude)
+			if err != nil {
+				t.Fatalf("IsAvailable() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsAvailable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAvailableExcludeQuery(t *testing.T) {
+	bookingID := uint(42)
+	tests := []struct {
+		name         string
+		exclude      *uint
+		bookingQuery string
+		bookingArgs  []driver.Value
+	}{
+		{
+			name:         "without exclusion",
+			bookingQuery: `SELECT count\(\*\) FROM "bookings" WHERE \(stylist_id = \$1 AND booking_date = \$2 AND status IN \(\$3,\$4\)\) AND \(NOT \(occupied_end_time <= \$5 OR start_time >= \$6\)\) AND "bookings"\."deleted_at" IS NULL`,
+			bookingArgs:  []driver.Value{2, "2030-06-03", "pending", "confirmed", "10:30", "11:30"},
+		},
+		{
+			name:         "excluding the edited booking",
+			exclude:      &bookingID,
+			bookingQuery: `SELECT count\(\*\) FROM "bookings" WHERE \(stylist_id = \$1 AND booking_date = \$2 AND status IN \(\$3,\$4\)\) AND \(NOT \(occupied_end_time <= \$5 OR start_time >= \$6\)\) AND id <> \$7 AND "bookings"\."deleted_at" IS NULL`,
+			bookingArgs:  []driver.Value{2, "2030-06-03", "pending", "confirmed", "10:30", "11:30", 42},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := testutil.MockDB(t)
+			repo := NewStylistRepository(db)
+
+			mock.ExpectQuery(`SELECT \* FROM "stylist_schedules"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "stylist_id", "day_of_week", "start_time", "end_time", "is_active"}).
+					AddRow(1, 2, int(testBookingDate.Weekday()), "10:00", "19:00", true))
+			mock.ExpectQuery(`SELECT count\(\*\) FROM "stylist_time_offs"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(tt.bookingQuery).WithArgs(tt.bookingArgs...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(`SELECT count\(\*\) FROM "booking_holds"`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+			available, err := repo.IsAvailable(2, testBookingDate, "10:30", "11:30", 1, tt.exclude)
+			if err != nil {
+				t.Fatalf("IsAvailable() error = %v", err)
+			}
+			if !available {
+				t.Error("IsAvailable() = false, want true")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 	"linda-salon-api/internal/model"
@@ -79,6 +80,66 @@ func (r *UserRepository) GetByLineID(lineID string) (*model.User, error) {
 	return &user, nil
 }
 
+// GetByIDs returns the users with the given IDs
+func (r *UserRepository) GetByIDs(ids []uint) ([]model.User, error) {
+	var users []model.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// GetRecentSignups returns users created since the given time, newest first
+func (r *UserRepository) GetRecentSignups(since time.Time, limit int) ([]model.User, error) {
+	var users []model.User
+	err := r.db.Where("created_at >= ?", since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// PurgeDeleted hard-deletes users soft-deleted before cutoff. Users who still
+// own a booking, hold, group booking, loyalty entry or review, or who appear
+// in the audit log, are kept. Bookings and loyalty entries that only name a
+// purged user as the staff member who made them lose that reference.
+func (r *UserRepository) PurgeDeleted(cutoff time.Time) (int64, error) {
+	return purgeSoftDeleted(r.db, &model.User{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("deleted_at < ?", cutoff).
+			Where("NOT EXISTS (SELECT 1 FROM bookings WHERE bookings.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM booking_holds WHERE booking_holds.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM booking_groups WHERE booking_groups.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM loyalty_entries WHERE loyalty_entries.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM audit_logs WHERE audit_logs.actor_user_id = users.id)")
+	}, func(tx *gorm.DB, ids []uint) error {
+		if err := tx.Model(&model.LoyaltyEntry{}).Where("actor_user_id IN ?", ids).
+			UpdateColumn("actor_user_id", nil).Error; err != nil {
+			return err
+		}
+		// UpdateColumn leaves updated_at alone, so detaching doesn't make the
+		// bookings look recently edited
+		if err := tx.Unscoped().Model(&model.Booking{}).Where("created_by_id IN ?", ids).
+			UpdateColumn("created_by_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Booking{}).Where("updated_by_id IN ?", ids).
+			UpdateColumn("updated_by_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.Booking{}).Where("status_changed_by_id IN ?", ids).
+			UpdateColumn("status_changed_by_id", nil).Error
+	})
+}
+
+// HasAdmin reports whether at least one admin account exists
+func (r *UserRepository) HasAdmin() (bool, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ?", "admin").Count(&count).Error
+	return count > 0, err
+}
+
 func (r *UserRepository) Update(user *model.User) error {
 	return r.db.Save(user).Error
 }
@@ -98,3 +159,30 @@ func (r *UserRepository) List(limit, offset int) ([]model.User, int64, error) {
 	err := r.db.Limit(limit).Offset(offset).Find(&users).Error
 	return users, total, err
 }
+
+// ListBlocked returns blocked users, most recently blocked first
+func (r *UserRepository) ListBlocked(limit, offset int) ([]model.User, int64, error) {
+	var users []model.User
+	var total int64
+
+	query := r.db.Model(&model.User{}).Where("is_blocked = ?", true)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("blocked_at DESC").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+// SetBlocked blocks or unblocks a user, keeping their history intact
+func (r *UserRepository) SetBlocked(id uint, blocked bool, reason string) error {
+	updates := map[string]interface{}{
+		"is_blocked":   blocked,
+		"block_reason": reason,
+		"blocked_at":   nil,
+	}
+	if blocked {
+		updates["blocked_at"] = time.Now()
+	}
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(updates).Error
+}
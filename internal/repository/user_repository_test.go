@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"linda-salon-api/internal/testutil"
+)
+
+// Users still referenced by their own records or by the audit log are never
+// selected for purging, and staff references to purged users are cleared
+// before the rows are deleted
+func TestUserPurgeDeleted(t *testing.T) {
+	db, mock := testutil.MockDB(t)
+	repo := NewUserRepository(db)
+	cutoff := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	selectIDs := `SELECT "id" FROM "users" WHERE deleted_at < \$1` +
+		` AND NOT EXISTS \(SELECT 1 FROM bookings WHERE bookings\.user_id = users\.id\)` +
+		` AND NOT EXISTS \(SELECT 1 FROM booking_holds WHERE booking_holds\.user_id = users\.id\)` +
+		` AND NOT EXISTS \(SELECT 1 FROM booking_groups WHERE booking_groups\.user_id = users\.id\)` +
+		` AND NOT EXISTS \(SELECT 1 FROM loyalty_entries WHERE loyalty_entries\.user_id = users\.id\)` +
+		` AND NOT EXISTS \(SELECT 1 FROM reviews WHERE reviews\.user_id = users\.id\)` +
+		` AND NOT EXISTS \(SELECT 1 FROM audit_logs WHERE audit_logs\.actor_user_id = users\.id\)` +
+		` AND deleted_at IS NOT NULL ORDER BY id`
+	mock.ExpectQuery(selectIDs).WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(5))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "loyalty_entries" SET "actor_user_id"=\$1 WHERE actor_user_id IN \(\$2,\$3\)`).
+		WithArgs(nil, 3, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "bookings" SET "created_by_id"=\$1 WHERE created_by_id IN \(\$2,\$3\)$`).
+		WithArgs(nil, 3, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE "bookings" SET "updated_by_id"=\$1 WHERE updated_by_id IN \(\$2,\$3\)$`).
+		WithArgs(nil, 3, 5).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE "bookings" SET "status_changed_by_id"=\$1 WHERE status_changed_by_id IN \(\$2,\$3\)$`).
+		WithArgs(nil, 3, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM "users" WHERE "users"\."id" IN \(\$1,\$2\)`).
+		WithArgs(3, 5).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	purged, err := repo.PurgeDeleted(cutoff)
+	if err != nil {
+		t.Fatalf("PurgeDeleted() error = %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("PurgeDeleted() = %d, want 2", purged)
+	}
+}
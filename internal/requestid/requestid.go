@@ -0,0 +1,33 @@
+// Package requestid carries the per-request correlation ID through
+// context.Context so code outside the gin handlers (background
+// notification goroutines, error rendering) can tag its logs with it.
+package requestid
+
+import "context"
+
+// Header is the HTTP header the ID is read from and echoed in
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// LogPrefix returns "[req <id>] " for log lines, or "" without an ID
+func LogPrefix(ctx context.Context) string {
+	if id := FromContext(ctx); id != "" {
+		return "[req " + id + "] "
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"time"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// LastBookableDate returns the last date customers may book: today (a
+// salon-local date at UTC midnight, like AvailabilityService.Today) plus the
+// booking.max_advance_days setting
+func LastBookableDate(settingsRepo *repository.SettingsRepository, today time.Time) (time.Time, int, error) {
+	days, err := settingsRepo.GetFloat(model.SettingsKeyMaxAdvanceDays, model.DefaultMaxAdvanceDays)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return today.AddDate(0, 0, int(days)), int(days), nil
+}
+
+// LastBookableDate is LastBookableDate counted from the salon's today
+func (s *AvailabilityService) LastBookableDate() (time.Time, int, error) {
+	return LastBookableDate(s.settingsRepo, s.Today())
+}
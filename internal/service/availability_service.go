@@ -0,0 +1,755 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// SlotInterval is the spacing between generated start times, in minutes
+const SlotInterval = 30
+
+// TimeSlot represents an available time slot
+type TimeSlot struct {
+	Time      string `json:"time"`
+	Available bool   `json:"available"`
+}
+
+// TimeRange is an occupied HH:MM interval [Start, End) on a single day
+type TimeRange struct {
+	Start string
+	End   string
+}
+
+// ScoredSlot is an available start time ranked by how little idle time it
+// leaves around it; 100 means it fits flush between bookings or shift edges
+type ScoredSlot struct {
+	Time  string `json:"time"`
+	Score int    `json:"score"`
+}
+
+// DateAvailability summarizes whether a date has at least one open slot
+type DateAvailability struct {
+	Date         string `json:"date"`
+	Available    bool   `json:"available"`
+	EarliestTime string `json:"earliest_time,omitempty"`
+	BlackedOut   bool   `json:"blacked_out,omitempty"` // the service is blacked out that day
+}
+
+// NextSlot is the soonest open start time for a stylist
+type NextSlot struct {
+	Date string `json:"date"`
+	Time string `json:"time"`
+}
+
+// WalkInAvailability tells reception whether a stylist can take a walk-in now
+type WalkInAvailability struct {
+	StylistID   uint   `json:"stylist_id"`
+	Name        string `json:"name"`
+	Free        bool   `json:"free"`
+	FreeUntil   string `json:"free_until,omitempty"` // HH:MM, next booking or end of shift
+	FreeMinutes int    `json:"free_minutes"`
+}
+
+// ServiceDayAvailability summarizes whether a service can still be booked on a date
+type ServiceDayAvailability struct {
+	ServiceID    uint   `json:"service_id"`
+	Name         string `json:"name"`
+	Duration     int    `json:"duration"`
+	Available    bool   `json:"available"`
+	EarliestTime string `json:"earliest_time,omitempty"`
+	BlackedOut   bool   `json:"blacked_out,omitempty"`
+}
+
+type AvailabilityService struct {
+	stylistRepo  *repository.StylistRepository
+	bookingRepo  *repository.BookingRepository
+	holdRepo     *repository.BookingHoldRepository
+	settingsRepo *repository.SettingsRepository
+	location     *time.Location // salon timezone
+	slots        *slotCache
+}
+
+func NewAvailabilityService(
+	stylistRepo *repository.StylistRepository,
+	bookingRepo *repository.BookingRepository,
+	holdRepo *repository.BookingHoldRepository,
+	settingsRepo *repository.SettingsRepository,
+	location *time.Location,
+) *AvailabilityService {
+	return &AvailabilityService{
+		stylistRepo:  stylistRepo,
+		bookingRepo:  bookingRepo,
+		holdRepo:     holdRepo,
+		settingsRepo: settingsRepo,
+		location:     location,
+		slots:        newSlotCache(SlotCacheTTL),
+	}
+}
+
+// Invalidate drops the cached slots of a stylist's date. Call it whenever a
+// booking or hold on that date is created, cancelled, moved or released.
+func (s *AvailabilityService) Invalidate(stylistID uint, date time.Time) {
+	s.slots.invalidate(stylistID, date.Format("2006-01-02"))
+}
+
+// InvalidateStylist drops all of a stylist's cached slots, e.g. after a
+// schedule or time off change
+func (s *AvailabilityService) InvalidateStylist(stylistID uint) {
+	s.slots.invalidate(stylistID, "")
+}
+
+// InvalidateAll drops every cached slot
+func (s *AvailabilityService) InvalidateAll() {
+	s.slots.clear()
+}
+
+// Today returns the salon's current date as a UTC midnight, matching how
+// booking dates are stored
+func (s *AvailabilityService) Today() time.Time {
+	now := time.Now().In(s.location)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BookingRanges converts non-cancelled bookings into occupied ranges,
+// including each booking's cleanup buffer
+func BookingRanges(bookings []model.Booking) []TimeRange {
+	ranges := make([]TimeRange, 0, len(bookings))
+	for _, booking := range bookings {
+		if booking.Status == model.BookingStatusCancelled {
+			continue
+		}
+		bookingTime, err := time.Parse("15:04", booking.StartTime)
+		if err != nil {
+			continue
+		}
+		bookingEnd := bookingTime.Add(time.Duration(booking.Duration+booking.BufferMinutes) * time.Minute)
+		ranges = append(ranges, TimeRange{Start: booking.StartTime, End: bookingEnd.Format("15:04")})
+	}
+	return ranges
+}
+
+// HoldRanges converts unexpired holds into occupied ranges
+func HoldRanges(holds []model.BookingHold) []TimeRange {
+	ranges := make([]TimeRange, 0, len(holds))
+	for _, hold := range holds {
+		if hold.IsExpired() {
+			continue
+		}
+		end := hold.OccupiedEndTime
+		if end == "" {
+			end = hold.EndTime
+		}
+		ranges = append(ranges, TimeRange{Start: hold.StartTime, End: end})
+	}
+	return ranges
+}
+
+// BuildSlots generates the day's time slots from the schedules that apply to
+// that day and the ranges already occupied on it.
+func BuildSlots(schedules []model.StylistSchedule, busy []TimeRange, duration int) []TimeSlot {
+	slots := []TimeSlot{}
+
+	for _, schedule := range schedules {
+		if !schedule.IsActive {
+			continue
+		}
+
+		startTime, err := time.Parse("15:04", schedule.StartTime)
+		if err != nil {
+			continue
+		}
+		endTime, err := time.Parse("15:04", schedule.EndTime)
+		if err != nil {
+			continue
+		}
+
+		currentTime := startTime
+		for currentTime.Before(endTime) {
+			// Check if this slot has enough time for the service
+			slotEnd := currentTime.Add(time.Duration(duration) * time.Minute)
+			if slotEnd.After(endTime) {
+				break // Not enough time before end of work day
+			}
+
+			// Check if this slot conflicts with occupied ranges
+			available := true
+			for _, block := range busy {
+				blockStart, err := time.Parse("15:04", block.Start)
+				if err != nil {
+					continue
+				}
+				blockEnd, err := time.Parse("15:04", block.End)
+				if err != nil {
+					continue
+				}
+
+				if currentTime.Before(blockEnd) && slotEnd.After(blockStart) {
+					available = false
+					break
+				}
+			}
+
+			slots = append(slots, TimeSlot{
+				Time:      currentTime.Format("15:04"),
+				Available: available,
+			})
+
+			currentTime = currentTime.Add(SlotInterval * time.Minute)
+		}
+	}
+
+	return slots
+}
+
+// TimeOffRanges converts partial-day time off into occupied ranges. Full
+// days have no range; callers drop the whole day instead.
+func TimeOffRanges(timeOff []model.StylistTimeOff) []TimeRange {
+	ranges := []TimeRange{}
+	for _, t := range timeOff {
+		if !t.IsFullDay() {
+			ranges = append(ranges, TimeRange{Start: t.StartTime, End: t.EndTime})
+		}
+	}
+	return ranges
+}
+
+// hasFullDayOff reports whether any of the time off covers the whole day
+func hasFullDayOff(timeOff []model.StylistTimeOff) bool {
+	for _, t := range timeOff {
+		if t.IsFullDay() {
+			return true
+		}
+	}
+	return false
+}
+
+// DaySlots generates a stylist's time slots for a date, accounting for
+// time off, existing bookings and other customers' unexpired holds. excludeBookingID,
+// when set, is treated as free so an edit flow can offer its current slot.
+// Results without excludeBookingID are cached for SlotCacheTTL.
+func (s *AvailabilityService) DaySlots(stylistID uint, date time.Time, duration int, excludeBookingID *uint) ([]TimeSlot, error) {
+	key := slotCacheKey{stylistID: stylistID, date: date.Format("2006-01-02"), duration: duration, interval: SlotInterval}
+	if excludeBookingID == nil {
+		if slots, ok := s.slots.get(key); ok {
+			return slots, nil
+		}
+	}
+
+	version := s.slots.currentVersion()
+	daySchedules, busy, err := s.dayOccupancy(stylistID, date, excludeBookingID)
+	if err != nil {
+		return nil, err
+	}
+	slots := BuildSlots(daySchedules, busy, duration)
+	if excludeBookingID == nil {
+		s.slots.set(key, slots, version)
+	}
+	return slots, nil
+}
+
+// SuggestedSlots returns the stylist's available slots on date ranked to
+// prefer times adjacent to existing bookings or the edges of a shift, so the
+// day fills up without short unbookable gaps. Ties keep chronological order.
+// Slots that have already started today are left out.
+func (s *AvailabilityService) SuggestedSlots(stylistID uint, date time.Time, duration int) ([]ScoredSlot, error) {
+	daySchedules, busy, err := s.dayOccupancy(stylistID, date, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := ""
+	if now := time.Now().In(s.location); now.Format("2006-01-02") == date.Format("2006-01-02") {
+		notBefore = now.Format("15:04")
+	}
+
+	scored := []ScoredSlot{}
+	for _, slot := range BuildSlots(daySchedules, busy, duration) {
+		if !slot.Available || slot.Time < notBefore {
+			continue
+		}
+		end := addClockMinutes(slot.Time, duration)
+		before, after := idleAround(daySchedules, busy, slot.Time, end)
+		scored = append(scored, ScoredSlot{
+			Time:  slot.Time,
+			Score: int(math.Round(50 * (adjacencyScore(before) + adjacencyScore(after)))),
+		})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored, nil
+}
+
+// idleAround returns the idle minutes between [start, end) and the nearest
+// busy range or edge of the enclosing shift on each side
+func idleAround(schedules []model.StylistSchedule, busy []TimeRange, start, end string) (int, int) {
+	prevEdge, nextEdge := "", ""
+	for _, schedule := range schedules {
+		if schedule.IsActive && schedule.StartTime <= start && end <= schedule.EndTime {
+			prevEdge, nextEdge = schedule.StartTime, schedule.EndTime
+			break
+		}
+	}
+	for _, block := range busy {
+		if block.End <= start && block.End > prevEdge {
+			prevEdge = block.End
+		}
+		if block.Start >= end && (nextEdge == "" || block.Start < nextEdge) {
+			nextEdge = block.Start
+		}
+	}
+	return minutesBetween(prevEdge, start), minutesBetween(end, nextEdge)
+}
+
+// adjacencyScore is 1 for no idle time and falls off as the gap grows
+func adjacencyScore(idleMinutes int) float64 {
+	if idleMinutes <= 0 {
+		return 1
+	}
+	return float64(SlotInterval) / float64(idleMinutes+SlotInterval)
+}
+
+// addClockMinutes adds minutes to an HH:MM time
+func addClockMinutes(clock string, minutes int) string {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return clock
+	}
+	return t.Add(time.Duration(minutes) * time.Minute).Format("15:04")
+}
+
+// dayOccupancy loads a stylist's schedules for the date's weekday and the
+// ranges occupied on it. Schedules are empty on a full day off.
+func (s *AvailabilityService) dayOccupancy(stylistID uint, date time.Time, excludeBookingID *uint) ([]model.StylistSchedule, []TimeRange, error) {
+	schedules, err := s.stylistRepo.GetSchedulesByStylistID(stylistID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var daySchedules []model.StylistSchedule
+	for _, schedule := range schedules {
+		if schedule.DayOfWeek == int(date.Weekday()) && schedule.IsActive {
+			daySchedules = append(daySchedules, schedule)
+		}
+	}
+	if len(daySchedules) == 0 {
+		return nil, nil, nil
+	}
+
+	timeOff, err := s.stylistRepo.GetTimeOffByStylistsAndDateRange([]uint{stylistID}, date, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hasFullDayOff(timeOff) {
+		return nil, nil, nil
+	}
+
+	bookings, err := s.bookingRepo.GetByStylistAndDateString(stylistID, date.Format("2006-01-02"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if excludeBookingID != nil {
+		kept := bookings[:0]
+		for _, booking := range bookings {
+			if booking.ID != *excludeBookingID {
+				kept = append(kept, booking)
+			}
+		}
+		bookings = kept
+	}
+	holds, err := s.holdRepo.GetActiveByStylistsAndDateRange([]uint{stylistID}, date, date)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	busy := append(BookingRanges(bookings), HoldRanges(holds)...)
+	busy = append(busy, TimeOffRanges(timeOff)...)
+	return daySchedules, busy, nil
+}
+
+// QualifiedForAll reports whether every stylist counts as qualified for
+// every service: the salon hasn't configured any capability mappings yet and
+// the fallback setting (on by default) hasn't been turned off.
+func (s *AvailabilityService) QualifiedForAll() (bool, error) {
+	fallbackAll, err := s.settingsRepo.GetBool(model.SettingsKeyStylistServicesFallback, model.DefaultStylistServicesFallback)
+	if err != nil || !fallbackAll {
+		return false, err
+	}
+	configured, err := s.stylistRepo.HasServiceMappings()
+	if err != nil {
+		return false, err
+	}
+	return !configured, nil
+}
+
+// QualifiedStylistIDs returns the active stylists qualified to perform the
+// service. See QualifiedForAll for when every active stylist qualifies.
+func (s *AvailabilityService) QualifiedStylistIDs(serviceID uint) ([]uint, error) {
+	qualifiedForAll, err := s.QualifiedForAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if qualifiedForAll {
+		stylists, err := s.stylistRepo.List(true)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]uint, 0, len(stylists))
+		for _, stylist := range stylists {
+			ids = append(ids, stylist.ID)
+		}
+		return ids, nil
+	}
+
+	return s.stylistRepo.GetStylistIDsForService(serviceID)
+}
+
+// ServiceAvailability reports, for each of the `days` dates starting at
+// start, whether any of the given stylists has a slot of `duration` minutes.
+// Schedules and bookings are loaded with one query each for the whole range,
+// for the stylists whose days aren't cached.
+func (s *AvailabilityService) ServiceAvailability(stylistIDs []uint, duration int, start time.Time, days int) ([]DateAvailability, error) {
+	result := make([]DateAvailability, 0, days)
+
+	if len(stylistIDs) == 0 {
+		for d := 0; d < days; d++ {
+			result = append(result, DateAvailability{Date: start.AddDate(0, 0, d).Format("2006-01-02")})
+		}
+		return result, nil
+	}
+
+	slots, err := s.rangeSlots(stylistIDs, start, days, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(s.location)
+	for d := 0; d < days; d++ {
+		dateStr := start.AddDate(0, 0, d).Format("2006-01-02")
+		earliest := earliestSlot(slots, stylistIDs, dateStr, notBeforeOn(now, dateStr))
+		result = append(result, DateAvailability{
+			Date:         dateStr,
+			Available:    earliest != "",
+			EarliestTime: earliest,
+		})
+	}
+
+	return result, nil
+}
+
+// NextAvailable scans forward from today for up to `days` dates and returns
+// the stylist's first open slot of `duration` minutes, or nil if there is
+// none. Uses the same slot generation as DaySlots, loaded in one batch.
+func (s *AvailabilityService) NextAvailable(stylistID uint, duration int, days int) (*NextSlot, error) {
+	start := s.Today()
+	slots, err := s.rangeSlots([]uint{stylistID}, start, days, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(s.location)
+	for d := 0; d < days; d++ {
+		dateStr := start.AddDate(0, 0, d).Format("2006-01-02")
+		if earliest := earliestSlot(slots, []uint{stylistID}, dateStr, notBeforeOn(now, dateStr)); earliest != "" {
+			return &NextSlot{Date: dateStr, Time: earliest}, nil
+		}
+	}
+	return nil, nil
+}
+
+// rangeSlots returns the stylists' slots of `duration` minutes for the
+// `days` dates starting at start, by stylist → date. Cached days are reused
+// and the index is loaded, in one batch, only for stylists missing a day.
+func (s *AvailabilityService) rangeSlots(stylistIDs []uint, start time.Time, days, duration int) (map[uint]map[string][]TimeSlot, error) {
+	result := make(map[uint]map[string][]TimeSlot, len(stylistIDs))
+	var missing []uint
+	for _, stylistID := range stylistIDs {
+		result[stylistID] = make(map[string][]TimeSlot, days)
+		for d := 0; d < days; d++ {
+			dateStr := start.AddDate(0, 0, d).Format("2006-01-02")
+			slots, ok := s.slots.get(rangeSlotKey(stylistID, dateStr, duration))
+			if !ok {
+				missing = append(missing, stylistID)
+				break
+			}
+			result[stylistID][dateStr] = slots
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	version := s.slots.currentVersion()
+	index, err := s.loadIndex(missing, start, start.AddDate(0, 0, days-1))
+	if err != nil {
+		return nil, err
+	}
+	for _, stylistID := range missing {
+		for d := 0; d < days; d++ {
+			date := start.AddDate(0, 0, d)
+			dateStr := date.Format("2006-01-02")
+			slots := index.slots(stylistID, date, duration)
+			result[stylistID][dateStr] = slots
+			s.slots.set(rangeSlotKey(stylistID, dateStr, duration), slots, version)
+		}
+	}
+	return result, nil
+}
+
+// rangeSlotKey is the cache key of slots generated from an availabilityIndex,
+// which only counts active bookings, so they're kept apart from DaySlots'
+func rangeSlotKey(stylistID uint, date string, duration int) slotCacheKey {
+	return slotCacheKey{stylistID: stylistID, date: date, duration: duration, interval: SlotInterval, batch: true}
+}
+
+// earliestSlot returns the earliest open slot on date across the stylists,
+// at or after notBefore, or "" if none
+func earliestSlot(slots map[uint]map[string][]TimeSlot, stylistIDs []uint, date, notBefore string) string {
+	earliest := ""
+	for _, stylistID := range stylistIDs {
+		if first := firstAvailable(slots[stylistID][date], notBefore); first != "" && (earliest == "" || first < earliest) {
+			earliest = first
+		}
+	}
+	return earliest
+}
+
+// notBeforeOn is the earliest start time still bookable on date: the current
+// time when date is today, otherwise no limit ("")
+func notBeforeOn(now time.Time, date string) string {
+	if now.Format("2006-01-02") == date {
+		return now.Format("15:04")
+	}
+	return ""
+}
+
+// WalkIns reports, at the current salon time, which of the stylists are on
+// shift and not busy, and how long until their next booking or the end of
+// their shift. It also returns the salon-local time it was computed for.
+func (s *AvailabilityService) WalkIns(stylists []model.Stylist) ([]WalkInAvailability, time.Time, error) {
+	today := s.Today()
+	result := make([]WalkInAvailability, 0, len(stylists))
+	if len(stylists) == 0 {
+		return result, time.Now().In(s.location), nil
+	}
+
+	ids := make([]uint, 0, len(stylists))
+	for _, stylist := range stylists {
+		ids = append(ids, stylist.ID)
+	}
+	index, err := s.loadIndex(ids, today, today)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	dateStr := today.Format("2006-01-02")
+	now := index.now.Format("15:04")
+	for _, stylist := range stylists {
+		entry := WalkInAvailability{StylistID: stylist.ID, Name: stylist.Name}
+		if !index.dayOff[stylist.ID][dateStr] {
+			entry.FreeUntil = freeUntil(index.schedulesByDay[stylist.ID][int(today.Weekday())], index.busyByDate[stylist.ID][dateStr], now)
+		}
+		if entry.FreeUntil != "" {
+			entry.Free = true
+			entry.FreeMinutes = minutesBetween(now, entry.FreeUntil)
+		}
+		result = append(result, entry)
+	}
+
+	return result, index.now, nil
+}
+
+// freeUntil returns when the stylist stops being free if they are on an
+// active shift at `now` and no busy range covers it, or "" otherwise
+func freeUntil(schedules []model.StylistSchedule, busy []TimeRange, now string) string {
+	until := ""
+	for _, schedule := range schedules {
+		if schedule.IsActive && schedule.StartTime <= now && now < schedule.EndTime {
+			until = schedule.EndTime
+			break
+		}
+	}
+	if until == "" {
+		return ""
+	}
+
+	for _, block := range busy {
+		if block.Start <= now && now < block.End {
+			return ""
+		}
+		if block.Start > now && block.Start < until {
+			until = block.Start
+		}
+	}
+	return until
+}
+
+// minutesBetween returns the minutes from one HH:MM time to a later one
+func minutesBetween(from, to string) int {
+	start, err := time.Parse("15:04", from)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse("15:04", to)
+	if err != nil {
+		return 0
+	}
+	return int(end.Sub(start).Minutes())
+}
+
+// ServicesAvailability reports, for each service, whether any qualified
+// stylist still has a slot of the service's duration on the given date.
+// Stylist data is loaded once for all services.
+func (s *AvailabilityService) ServicesAvailability(services []model.Service, date time.Time) ([]ServiceDayAvailability, error) {
+	qualified := make(map[uint][]uint, len(services))
+	seen := make(map[uint]bool)
+	allIDs := []uint{}
+	for _, svc := range services {
+		ids, err := s.QualifiedStylistIDs(svc.ID)
+		if err != nil {
+			return nil, err
+		}
+		qualified[svc.ID] = ids
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				allIDs = append(allIDs, id)
+			}
+		}
+	}
+
+	index := &availabilityIndex{}
+	if len(allIDs) > 0 {
+		var err error
+		index, err = s.loadIndex(allIDs, date, date)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]ServiceDayAvailability, 0, len(services))
+	for _, svc := range services {
+		earliest := index.earliest(qualified[svc.ID], date, svc.Duration+svc.BufferMinutes)
+		result = append(result, ServiceDayAvailability{
+			ServiceID:    svc.ID,
+			Name:         svc.Name,
+			Duration:     svc.Duration,
+			Available:    earliest != "",
+			EarliestTime: earliest,
+		})
+	}
+
+	return result, nil
+}
+
+// availabilityIndex holds schedules by stylist → weekday and occupied ranges
+// by stylist → date for a batch of stylists over a date range
+type availabilityIndex struct {
+	schedulesByDay map[uint]map[int][]model.StylistSchedule
+	busyByDate     map[uint]map[string][]TimeRange
+	dayOff         map[uint]map[string]bool // full days off by stylist → date
+	now            time.Time                // salon-local time when the index was loaded
+}
+
+// loadIndex loads schedules, bookings and holds for the stylists between two
+// dates (inclusive) with one query each
+func (s *AvailabilityService) loadIndex(stylistIDs []uint, start, end time.Time) (*availabilityIndex, error) {
+	schedules, err := s.stylistRepo.GetSchedulesByStylistIDs(stylistIDs)
+	if err != nil {
+		return nil, err
+	}
+	bookings, err := s.bookingRepo.GetActiveByStylistsAndDateRange(stylistIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	holds, err := s.holdRepo.GetActiveByStylistsAndDateRange(stylistIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	timeOff, err := s.stylistRepo.GetTimeOffByStylistsAndDateRange(stylistIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &availabilityIndex{
+		schedulesByDay: make(map[uint]map[int][]model.StylistSchedule),
+		busyByDate:     make(map[uint]map[string][]TimeRange),
+		dayOff:         make(map[uint]map[string]bool),
+		now:            time.Now().In(s.location),
+	}
+	for _, schedule := range schedules {
+		if index.schedulesByDay[schedule.StylistID] == nil {
+			index.schedulesByDay[schedule.StylistID] = make(map[int][]model.StylistSchedule)
+		}
+		index.schedulesByDay[schedule.StylistID][schedule.DayOfWeek] = append(index.schedulesByDay[schedule.StylistID][schedule.DayOfWeek], schedule)
+	}
+	for _, booking := range bookings {
+		index.addBusy(booking.StylistID, booking.BookingDate, BookingRanges([]model.Booking{booking}))
+	}
+	for _, hold := range holds {
+		index.addBusy(hold.StylistID, hold.BookingDate, HoldRanges([]model.BookingHold{hold}))
+	}
+	for _, t := range timeOff {
+		if t.IsFullDay() {
+			if index.dayOff[t.StylistID] == nil {
+				index.dayOff[t.StylistID] = make(map[string]bool)
+			}
+			index.dayOff[t.StylistID][t.Date.UTC().Format("2006-01-02")] = true
+			continue
+		}
+		index.addBusy(t.StylistID, t.Date, TimeOffRanges([]model.StylistTimeOff{t}))
+	}
+
+	return index, nil
+}
+
+func (idx *availabilityIndex) addBusy(stylistID uint, date time.Time, ranges []TimeRange) {
+	dateStr := date.UTC().Format("2006-01-02")
+	if idx.busyByDate[stylistID] == nil {
+		idx.busyByDate[stylistID] = make(map[string][]TimeRange)
+	}
+	idx.busyByDate[stylistID][dateStr] = append(idx.busyByDate[stylistID][dateStr], ranges...)
+}
+
+// earliest returns the earliest open slot of `duration` minutes on date
+// across the stylists, or "" if none. Slots that have already started today
+// are not counted.
+func (idx *availabilityIndex) earliest(stylistIDs []uint, date time.Time, duration int) string {
+	notBefore := notBeforeOn(idx.now, date.Format("2006-01-02"))
+
+	earliest := ""
+	for _, stylistID := range stylistIDs {
+		if first := firstAvailable(idx.slots(stylistID, date, duration), notBefore); first != "" && (earliest == "" || first < earliest) {
+			earliest = first
+		}
+	}
+	return earliest
+}
+
+// slots generates a stylist's slots of `duration` minutes on date; none on
+// a full day off
+func (idx *availabilityIndex) slots(stylistID uint, date time.Time, duration int) []TimeSlot {
+	dateStr := date.Format("2006-01-02")
+	if idx.dayOff[stylistID][dateStr] {
+		return []TimeSlot{}
+	}
+	return BuildSlots(idx.schedulesByDay[stylistID][int(date.Weekday())], idx.busyByDate[stylistID][dateStr], duration)
+}
+
+// firstAvailable returns the earliest available slot time at or after
+// notBefore (HH:MM, "" for no limit), or "" if none
+func firstAvailable(slots []TimeSlot, notBefore string) string {
+	earliest := ""
+	for _, slot := range slots {
+		if slot.Available && slot.Time >= notBefore && (earliest == "" || slot.Time < earliest) {
+			earliest = slot.Time
+		}
+	}
+	return earliest
+}
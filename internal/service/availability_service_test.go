@@ -0,0 +1,94 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/testutil"
+)
+
+func newMockAvailabilityService(t *testing.T) (*AvailabilityService, sqlmock.Sqlmock) {
+	db, mock := testutil.MockDB(t)
+	svc := NewAvailabilityService(
+		repository.NewStylistRepository(db),
+		repository.NewBookingRepository(db),
+		repository.NewBookingHoldRepository(db),
+		repository.NewSettingsRepository(db),
+		time.UTC,
+	)
+	return svc, mock
+}
+
+func expectFallbackSetting(mock sqlmock.Sqlmock, value string) {
+	rows := sqlmock.NewRows([]string{"id", "key", "value", "category"})
+	if value != "" {
+		rows.AddRow(1, "stylist.services_fallback_all", value, "booking")
+	}
+	mock.ExpectQuery(`SELECT \* FROM "settings" WHERE key = \$1`).
+		WithArgs("stylist.services_fallback_all").
+		WillReturnRows(rows)
+}
+
+func expectMappingCount(mock sqlmock.Sqlmock, count int) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "stylist_services"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func TestQualifiedForAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		setting  string // stored JSON value, "" when the setting doesn't exist
+		mappings int    // -1 when the mappings aren't counted
+		want     bool
+	}{
+		{"upgrade: no setting and no mappings", "", 0, true},
+		{"fallback on and no mappings", "true", 0, true},
+		{"fallback on but mappings configured", "true", 3, false},
+		{"no setting but mappings configured", "", 3, false},
+		{"fallback turned off", "false", -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, mock := newMockAvailabilityService(t)
+			expectFallbackSetting(mock, tt.setting)
+			if tt.mappings >= 0 {
+				expectMappingCount(mock, tt.mappings)
+			}
+
+			got, err := svc.QualifiedForAll()
+			if err != nil {
+				t.Fatalf("QualifiedForAll() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("QualifiedForAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// With no mappings configured, which is every deployment right after the
+// upgrade, every active stylist must be offered for a service
+func TestQualifiedStylistIDsWithoutMappings(t *testing.T) {
+	svc, mock := newMockAvailabilityService(t)
+	expectFallbackSetting(mock, "")
+	expectMappingCount(mock, 0)
+	mock.ExpectQuery(`SELECT \* FROM "stylists" WHERE is_active = \$1`).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_active"}).
+			AddRow(1, "Linda", true).
+			AddRow(2, "Amy", true))
+	mock.ExpectQuery(`SELECT \* FROM "stylist_schedules" WHERE "stylist_schedules"."stylist_id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stylist_id"}))
+
+	ids, err := svc.QualifiedStylistIDs(5)
+	if err != nil {
+		t.Fatalf("QualifiedStylistIDs() error = %v", err)
+	}
+	if want := []uint{1, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("QualifiedStylistIDs() = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"linda-salon-api/internal/model"
+)
+
+// DaySheet is the printable schedule of a day's bookings grouped by stylist
+type DaySheet struct {
+	Date     string            `json:"date"`
+	Total    int               `json:"total"`
+	Stylists []DaySheetStylist `json:"stylists"`
+}
+
+// DaySheetStylist is one stylist's bookings on a day sheet, in start order
+type DaySheetStylist struct {
+	StylistID uint            `json:"stylist_id"`
+	Name      string          `json:"name"`
+	Bookings  []DaySheetEntry `json:"bookings"`
+}
+
+// DaySheetEntry is a single booking line on a day sheet
+type DaySheetEntry struct {
+	BookingID     uint     `json:"booking_id"`
+	StartTime     string   `json:"start_time"`
+	EndTime       string   `json:"end_time"`
+	Status        string   `json:"status"`
+	CustomerName  string   `json:"customer_name"`
+	CustomerPhone string   `json:"customer_phone"`
+	Services      []string `json:"services"`
+	Notes         string   `json:"notes,omitempty"`
+}
+
+// BuildDaySheet groups the date's bookings by stylist (ordered by name).
+// Cancelled bookings are left off since reception doesn't need them.
+func BuildDaySheet(date time.Time, bookings []model.Booking) DaySheet {
+	sheet := DaySheet{Date: date.Format("2006-01-02"), Stylists: []DaySheetStylist{}}
+	byStylist := make(map[uint]int)
+
+	for _, booking := range bookings {
+		if booking.Status == model.BookingStatusCancelled {
+			continue
+		}
+
+		i, ok := byStylist[booking.StylistID]
+		if !ok {
+			i = len(sheet.Stylists)
+			byStylist[booking.StylistID] = i
+			sheet.Stylists = append(sheet.Stylists, DaySheetStylist{
+				StylistID: booking.StylistID,
+				Name:      booking.Stylist.Name,
+			})
+		}
+
+		services := make([]string, 0, len(booking.Services))
+		for _, item := range booking.Services {
+			name := item.Name
+			if item.VariantLabel != "" {
+				name = fmt.Sprintf("%s (%s)", name, item.VariantLabel)
+			}
+			services = append(services, name)
+		}
+
+		sheet.Stylists[i].Bookings = append(sheet.Stylists[i].Bookings, DaySheetEntry{
+			BookingID:     booking.ID,
+			StartTime:     booking.StartTime,
+			EndTime:       booking.EndTime,
+			Status:        booking.Status,
+			CustomerName:  booking.CustomerName,
+			CustomerPhone: booking.CustomerPhone,
+			Services:      services,
+			Notes:         booking.Notes,
+		})
+		sheet.Total++
+	}
+
+	sort.SliceStable(sheet.Stylists, func(a, b int) bool {
+		return sheet.Stylists[a].Name < sheet.Stylists[b].Name
+	})
+	for i := range sheet.Stylists {
+		entries := sheet.Stylists[i].Bookings
+		sort.SliceStable(entries, func(a, b int) bool {
+			return entries[a].StartTime < entries[b].StartTime
+		})
+	}
+
+	return sheet
+}
+
+// DaySheetFilename returns the download filename for a day sheet PDF
+func DaySheetFilename(sheet DaySheet) string {
+	return fmt.Sprintf("day-sheet-%s.pdf", sheet.Date)
+}
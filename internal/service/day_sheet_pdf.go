@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// A4 portrait in PDF points, with the margins and type sizes of the day sheet
+const (
+	pdfPageHeight  = 842.0
+	pdfMargin      = 40.0
+	pdfTitleSize   = 16.0
+	pdfHeadingSize = 12.0
+	pdfBodySize    = 10.0
+	pdfLineSpacing = 1.4
+	pdfIndent      = 90.0 // offset of the details column from the time column
+)
+
+// daySheetFont is WenQuanYi Micro Hei (Apache License 2.0, see fonts/README.md),
+// which covers both Traditional and Simplified Chinese. Only the glyphs a
+// day sheet uses are embedded in the PDF.
+//
+//go:embed fonts/wqy-microhei.ttf
+var daySheetFont []byte
+
+const daySheetFontFamily = "WenQuanYiMicroHei"
+
+// RenderDaySheetPDF lays the day sheet out as a plain, printable PDF with the
+// CJK font embedded, so Chinese customer names print the same in any viewer
+func RenderDaySheetPDF(sheet DaySheet) ([]byte, error) {
+	pdf := fpdf.New("P", "pt", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle("Day Sheet "+sheet.Date, true)
+	pdf.AddUTF8FontFromBytes(daySheetFontFamily, "", daySheetFont)
+	pdf.AddPage()
+
+	line := func(size float64, s string) {
+		pdf.SetFont(daySheetFontFamily, "", size)
+		pdf.MultiCell(0, size*pdfLineSpacing, s, "", "L", false)
+	}
+	// ensureRoom starts a new page unless height points still fit on this one
+	ensureRoom := func(height float64) {
+		if pdf.GetY()+height > pdfPageHeight-pdfMargin {
+			pdf.AddPage()
+		}
+	}
+
+	line(pdfTitleSize, fmt.Sprintf("Day Sheet  %s", sheet.Date))
+	line(pdfBodySize, fmt.Sprintf("%d bookings", sheet.Total))
+	pdf.Ln(pdfBodySize)
+
+	if len(sheet.Stylists) == 0 {
+		line(pdfBodySize, "No bookings.")
+	}
+
+	for _, stylist := range sheet.Stylists {
+		// keep a heading together with at least its first booking
+		ensureRoom(pdfHeadingSize*pdfLineSpacing + 2*pdfBodySize*pdfLineSpacing)
+		pdf.SetFont(daySheetFontFamily, "", pdfHeadingSize)
+		pdf.CellFormat(0, pdfHeadingSize*pdfLineSpacing, fmt.Sprintf("%s  (%d)", stylist.Name, len(stylist.Bookings)),
+			"B", 1, "L", false, 0, "")
+		pdf.Ln(pdfBodySize / 2)
+
+		for _, entry := range stylist.Bookings {
+			ensureRoom(2 * pdfBodySize * pdfLineSpacing)
+			pdf.SetFont(daySheetFontFamily, "", pdfBodySize)
+			pdf.CellFormat(pdfIndent, pdfBodySize*pdfLineSpacing, entry.StartTime+" - "+entry.EndTime, "", 0, "L", false, 0, "")
+
+			customer := entry.CustomerName
+			if entry.CustomerPhone != "" {
+				customer += "  " + entry.CustomerPhone
+			}
+			if entry.Status != "" {
+				customer += "  [" + entry.Status + "]"
+			}
+			details := []string{customer, strings.Join(entry.Services, ", ")}
+			if entry.Notes != "" {
+				details = append(details, "Notes: "+entry.Notes)
+			}
+			for _, detail := range details {
+				pdf.SetX(pdfMargin + pdfIndent)
+				line(pdfBodySize, detail)
+			}
+			pdf.Ln(pdfBodySize / 2)
+		}
+		pdf.Ln(pdfBodySize)
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderDaySheetPDF(t *testing.T) {
+	bookings := make([]DaySheetEntry, 0, 60)
+	for i := 0; i < 60; i++ {
+		bookings = append(bookings, DaySheetEntry{
+			BookingID:     uint(i + 1),
+			StartTime:     fmt.Sprintf("%02d:00", 9+i%10),
+			EndTime:       fmt.Sprintf("%02d:00", 10+i%10),
+			Status:        "confirmed",
+			CustomerName:  "王小明",
+			CustomerPhone: "+886912345678",
+			Services:      []string{"剪髮", "染髮"},
+			Notes:         strings.Repeat("頭皮敏感，請使用溫和的洗髮精。", 6),
+		})
+	}
+
+	tests := []struct {
+		name      string
+		sheet     DaySheet
+		wantPages int
+	}{
+		{"no bookings", DaySheet{Date: "2030-06-03", Stylists: []DaySheetStylist{}}, 1},
+		{"one booking", DaySheet{Date: "2030-06-03", Total: 1, Stylists: []DaySheetStylist{
+			{StylistID: 1, Name: "Linda 林", Bookings: bookings[:1]},
+		}}, 1},
+		{"spans several pages", DaySheet{Date: "2030-06-03", Total: len(bookings), Stylists: []DaySheetStylist{
+			{StylistID: 1, Name: "Linda 林", Bookings: bookings},
+		}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, err := RenderDaySheetPDF(tt.sheet)
+			if err != nil {
+				t.Fatalf("RenderDaySheetPDF() error = %v", err)
+			}
+			if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+				t.Fatalf("output doesn't start with a PDF header: %q", pdf[:16])
+			}
+			// The CJK font must travel with the document, not rely on the viewer
+			if !bytes.Contains(pdf, []byte("/FontFile2")) {
+				t.Error("PDF doesn't embed its font")
+			}
+			if pages := bytes.Count(pdf, []byte("/Type /Page\n")); pages < tt.wantPages {
+				t.Errorf("PDF has %d pages, want at least %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
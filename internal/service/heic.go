@@ -0,0 +1,46 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+)
+
+// heicJPEGQuality is the quality HEIC photos are re-encoded at; they're
+// camera photos, so keep it high
+const heicJPEGQuality = 90
+
+// ErrHEICUnsupported is returned when the server was built without a HEIC decoder
+var ErrHEICUnsupported = errors.New("HEIC images are not supported by this server")
+
+// heicDecoder decodes HEIC/HEIF images. It's nil unless the server is built
+// with the heic build tag (see heic_decoder.go), which needs cgo and the
+// github.com/jdeng/goheif module.
+var heicDecoder func(io.Reader) (image.Image, error)
+
+// HEICSupported reports whether HEIC/HEIF uploads can be converted
+func HEICSupported() bool {
+	return heicDecoder != nil
+}
+
+// ConvertHEICToJPEG decodes a HEIC/HEIF photo (as taken by iPhones) and
+// re-encodes it as JPEG so browsers can display it
+func ConvertHEICToJPEG(data []byte) ([]byte, error) {
+	if heicDecoder == nil {
+		return nil, ErrHEICUnsupported
+	}
+
+	img, err := heicDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode HEIC image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: heicJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,14 @@
+//go:build heic
+
+package service
+
+import "github.com/jdeng/goheif"
+
+// Built with -tags heic: decode HEIC/HEIF uploads with libde265 via goheif,
+// which is compiled from source and needs cgo and a C++ compiler
+func init() {
+	// Without safe encoding the decoded pixels point into libde265 buffers
+	// that are freed before Decode returns, and encoding them as JPEG crashes
+	goheif.SafeEncoding = true
+	heicDecoder = goheif.Decode
+}
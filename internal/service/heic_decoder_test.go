@@ -0,0 +1,27 @@
+//go:build heic
+
+package service
+
+import "testing"
+
+func TestConvertHEICToJPEG(t *testing.T) {
+	if !HEICSupported() {
+		t.Fatal("HEICSupported() = false with the heic build tag")
+	}
+
+	jpegData, err := ConvertHEICToJPEG(readTestdata(t, "camel.heic"))
+	if err != nil {
+		t.Fatalf("ConvertHEICToJPEG() error = %v", err)
+	}
+	width, height, err := ImageDimensions(jpegData)
+	if err != nil {
+		t.Fatalf("converted image doesn't decode: %v", err)
+	}
+	if width == 0 || height == 0 {
+		t.Errorf("converted image is %dx%d", width, height)
+	}
+
+	if _, err := ConvertHEICToJPEG([]byte("not a heic file")); err == nil {
+		t.Error("ConvertHEICToJPEG() accepted data that isn't HEIC")
+	}
+}
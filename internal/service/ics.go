@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"linda-salon-api/internal/model"
+)
+
+// icsProdID identifies this API as the producer of generated calendars
+const icsProdID = "-//Linda Salon//Booking API//ZH-TW"
+
+// BuildCalendar renders bookings as an iCalendar (RFC 5545) document with
+// one VEVENT per booking, located at the salon. Times are emitted as floating
+// local times since bookings are stored as salon-local dates and HH:MM strings.
+func BuildCalendar(bookings []model.Booking, contact model.SalonContactConfig) string {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:"+icsProdID)
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	for i := range bookings {
+		writeBookingEvent(&b, &bookings[i], contact)
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// writeBookingEvent appends the VEVENT for a single booking
+func writeBookingEvent(b *strings.Builder, booking *model.Booking, contact model.SalonContactConfig) {
+	date := booking.BookingDate.Format("20060102")
+	status := "CONFIRMED"
+	if booking.Status == model.BookingStatusPending {
+		status = "TENTATIVE"
+	} else if booking.Status == model.BookingStatusCancelled {
+		status = "CANCELLED"
+	}
+
+	names := make([]string, 0, len(booking.Services))
+	for _, item := range booking.Services {
+		names = append(names, item.Name)
+	}
+	summary := strings.Join(names, " + ")
+	if booking.Stylist.Name != "" {
+		summary = fmt.Sprintf("%s (%s)", summary, booking.Stylist.Name)
+	}
+
+	writeICSLine(b, "BEGIN:VEVENT")
+	writeICSLine(b, fmt.Sprintf("UID:booking-%d@linda-salon", booking.ID))
+	writeICSLine(b, "DTSTAMP:"+booking.UpdatedAt.UTC().Format("20060102T150405Z"))
+	writeICSLine(b, "DTSTART:"+date+"T"+strings.ReplaceAll(booking.StartTime, ":", "")+"00")
+	writeICSLine(b, "DTEND:"+date+"T"+strings.ReplaceAll(booking.EndTime, ":", "")+"00")
+	writeICSLine(b, "SUMMARY:"+escapeICSText(summary))
+	if location := contact.Location(); location != "" {
+		writeICSLine(b, "LOCATION:"+escapeICSText(location))
+	}
+	description := booking.Notes
+	if contact.Phone != "" {
+		description = strings.TrimSpace(description + "\n" + contact.Name + " " + contact.Phone)
+	}
+	if description != "" {
+		writeICSLine(b, "DESCRIPTION:"+escapeICSText(description))
+	}
+	writeICSLine(b, "STATUS:"+status)
+	writeICSLine(b, "END:VEVENT")
+}
+
+// writeICSLine writes a content line, folding it at 75 octets as RFC 5545
+// requires without splitting multi-byte characters
+func writeICSLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// escapeICSText escapes TEXT property values
+func escapeICSText(s string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		";", "\\;",
+		",", "\\,",
+		"\r\n", "\\n",
+		"\n", "\\n",
+	)
+	return replacer.Replace(s)
+}
+
+// ICSFilename returns the download filename for a booking's calendar file
+func ICSFilename(booking *model.Booking) string {
+	return fmt.Sprintf("booking-%d-%s.ics", booking.ID, booking.BookingDate.Format("20060102"))
+}
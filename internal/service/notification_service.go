@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/notify"
+	"linda-salon-api/internal/repository"
+	"linda-salon-api/internal/requestid"
+)
+
+// Notification channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// ErrSMSNotConfigured is returned when an SMS is requested but no SMS provider is set up
+var ErrSMSNotConfigured = errors.New("SMS delivery is not configured")
+
+// NotificationService sends staff and customer notifications about booking
+// activity
+type NotificationService struct {
+	settingsRepo *repository.SettingsRepository
+	email        notify.EmailSender
+}
+
+func NewNotificationService(settingsRepo *repository.SettingsRepository, email notify.EmailSender) *NotificationService {
+	return &NotificationService{
+		settingsRepo: settingsRepo,
+		email:        email,
+	}
+}
+
+// AdminRecipients returns the configured admin notification emails
+func (s *NotificationService) AdminRecipients() ([]string, error) {
+	recipients := []string{}
+	if _, err := s.settingsRepo.GetValue(model.SettingsKeyAdminNotificationRecipients, &recipients); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// The background senders below take the request's context only to tag their
+// logs with its request ID; they keep running after the request finishes.
+
+// NotifyNewBooking tells every admin recipient about a new booking
+func (s *NotificationService) NotifyNewBooking(ctx context.Context, booking *model.Booking) {
+	if booking == nil {
+		return
+	}
+	subject := fmt.Sprintf("New booking #%d", booking.ID)
+	s.notifyAdmins(ctx, subject, bookingSummary(booking))
+}
+
+// NotifyCancellation tells every admin recipient that a booking was cancelled
+func (s *NotificationService) NotifyCancellation(ctx context.Context, booking *model.Booking) {
+	if booking == nil {
+		return
+	}
+	subject := fmt.Sprintf("Booking #%d cancelled", booking.ID)
+	s.notifyAdmins(ctx, subject, bookingSummary(booking))
+}
+
+// notifyAdmins fans a message out to all recipients in the background so a
+// slow or failing delivery never holds up the request
+func (s *NotificationService) notifyAdmins(ctx context.Context, subject, body string) {
+	prefix := requestid.LogPrefix(ctx)
+	go func() {
+		recipients, err := s.AdminRecipients()
+		if err != nil {
+			log.Printf("%s⚠️  Failed to load admin notification recipients: %v", prefix, err)
+			return
+		}
+
+		contact, err := LoadSalonContact(s.settingsRepo)
+		if err != nil {
+			log.Printf("%s⚠️  Failed to load salon contact: %v", prefix, err)
+		}
+		body += emailFooter(contact)
+
+		for _, to := range recipients {
+			if err := s.deliver(to, subject, body); err != nil {
+				log.Printf("%s⚠️  Failed to notify %s: %v", prefix, to, err)
+			}
+		}
+	}()
+}
+
+// SendPasswordReset emails a password reset link to a user in the background
+func (s *NotificationService) SendPasswordReset(ctx context.Context, to, resetURL string) {
+	prefix := requestid.LogPrefix(ctx)
+	go func() {
+		contact, err := LoadSalonContact(s.settingsRepo)
+		if err != nil {
+			log.Printf("%s⚠️  Failed to load salon contact: %v", prefix, err)
+		}
+		body := "We received a request to reset your password. Open the link below to choose a new one:\n\n" +
+			resetURL + "\n\nIf you didn't request this, you can ignore this email." + emailFooter(contact)
+		if err := s.deliver(to, "Reset your password", body); err != nil {
+			log.Printf("%s⚠️  Failed to send password reset to %s: %v", prefix, to, err)
+		}
+	}()
+}
+
+// bookingConfirmationTemplate is the body of the email sent to customers when
+// a booking is created or confirmed
+var bookingConfirmationTemplate = template.Must(template.New("confirmation").Parse(
+	`Hi {{.CustomerName}},
+
+{{if .Confirmed}}Your booking has been confirmed.{{else}}We've received your booking. We'll let you know once it's confirmed.{{end}}
+
+Date:     {{.Date}}
+Time:     {{.StartTime}}-{{.EndTime}}
+Stylist:  {{.Stylist}}
+Services: {{.Services}}
+Price:    NT${{.Price}}
+
+Booking #{{.ID}}{{if .Reference}}
+Reference: {{.Reference}}{{end}}`))
+
+// SendBookingConfirmation emails the customer the details of their booking in
+// the background; failures are only logged
+func (s *NotificationService) SendBookingConfirmation(ctx context.Context, booking *model.Booking) {
+	if booking == nil || booking.CustomerEmail == "" {
+		return
+	}
+
+	names := make([]string, 0, len(booking.Services))
+	for _, item := range booking.Services {
+		names = append(names, item.Name)
+	}
+	price := booking.Price
+	if booking.TotalWithTax > 0 {
+		price = booking.TotalWithTax
+	}
+	data := struct {
+		ID                       uint
+		Reference                string
+		CustomerName, Stylist    string
+		Date, StartTime, EndTime string
+		Services                 string
+		Price                    int
+		Confirmed                bool
+	}{
+		ID:           booking.ID,
+		Reference:    booking.Reference,
+		CustomerName: booking.CustomerName,
+		Stylist:      booking.Stylist.Name,
+		Date:         booking.BookingDate.Format("2006-01-02"),
+		StartTime:    booking.StartTime,
+		EndTime:      booking.EndTime,
+		Services:     strings.Join(names, ", "),
+		Price:        price,
+		Confirmed:    booking.Status == model.BookingStatusConfirmed,
+	}
+
+	prefix := requestid.LogPrefix(ctx)
+	var body strings.Builder
+	if err := bookingConfirmationTemplate.Execute(&body, data); err != nil {
+		log.Printf("%s⚠️  Failed to render confirmation for booking #%d: %v", prefix, booking.ID, err)
+		return
+	}
+	subject := fmt.Sprintf("Booking received: %s %s", data.Date, data.StartTime)
+	if data.Confirmed {
+		subject = fmt.Sprintf("Booking confirmed: %s %s", data.Date, data.StartTime)
+	}
+	to := booking.CustomerEmail
+
+	go func() {
+		contact, err := LoadSalonContact(s.settingsRepo)
+		if err != nil {
+			log.Printf("%s⚠️  Failed to load salon contact: %v", prefix, err)
+		}
+		if err := s.deliver(to, subject, body.String()+emailFooter(contact)); err != nil {
+			log.Printf("%s⚠️  Failed to send confirmation for booking #%d to %s: %v", prefix, data.ID, to, err)
+		}
+	}()
+}
+
+// SendBookingReminder reminds a customer of their upcoming booking
+func (s *NotificationService) SendBookingReminder(to string, booking *model.Booking) error {
+	contact, err := LoadSalonContact(s.settingsRepo)
+	if err != nil {
+		log.Printf("⚠️  Failed to load salon contact: %v", err)
+	}
+	subject := fmt.Sprintf("Reminder: your appointment on %s at %s", booking.BookingDate.Format("2006-01-02"), booking.StartTime)
+	body := fmt.Sprintf("Hi %s,\n\nThis is a reminder of your appointment on %s from %s to %s.\n\nSee you soon!",
+		booking.CustomerName, booking.BookingDate.Format("2006-01-02"), booking.StartTime, booking.EndTime) + emailFooter(contact)
+	return s.deliver(to, subject, body)
+}
+
+// SendTest synchronously sends a test message over the given channel so
+// admins can verify their delivery configuration
+func (s *NotificationService) SendTest(channel, to string) error {
+	switch channel {
+	case ChannelEmail:
+		contact, err := LoadSalonContact(s.settingsRepo)
+		if err != nil {
+			return fmt.Errorf("failed to load salon contact: %w", err)
+		}
+		body := "This is a test notification. If you received it, email delivery is working." + emailFooter(contact)
+		return s.deliver(to, "Test notification", body)
+	case ChannelSMS:
+		return ErrSMSNotConfigured
+	default:
+		return fmt.Errorf("unknown channel: %s", channel)
+	}
+}
+
+// deliver sends a single notification
+func (s *NotificationService) deliver(to, subject, body string) error {
+	return s.email.Send(to, subject, body)
+}
+
+func bookingSummary(booking *model.Booking) string {
+	return fmt.Sprintf("%s %s-%s\nCustomer: %s (%s)\nStylist ID: %d\nPrice: %d",
+		booking.BookingDate.Format("2006-01-02"), booking.StartTime, booking.EndTime,
+		booking.CustomerName, booking.CustomerPhone, booking.StylistID, booking.Price)
+}
+
+// emailFooter renders the salon contact block appended to outgoing emails
+func emailFooter(contact model.SalonContactConfig) string {
+	lines := []string{}
+	for _, line := range []string{contact.Name, contact.Address, contact.Phone, contact.Website} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n--\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+// A booking that couldn't be reloaded after it was saved is skipped rather
+// than dereferenced
+func TestNotifyNilBooking(t *testing.T) {
+	s := NewNotificationService(nil, nil)
+	ctx := context.Background()
+
+	s.NotifyNewBooking(ctx, nil)
+	s.NotifyCancellation(ctx, nil)
+	s.SendBookingConfirmation(ctx, nil)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// ReminderService emails customers ahead of their bookings. Each customer's
+// reminder_hours_before preference decides how early, falling back to the
+// salon-wide setting.
+type ReminderService struct {
+	bookingRepo  *repository.BookingRepository
+	settingsRepo *repository.SettingsRepository
+	notifier     *NotificationService
+	location     *time.Location // salon timezone
+}
+
+func NewReminderService(
+	bookingRepo *repository.BookingRepository,
+	settingsRepo *repository.SettingsRepository,
+	notifier *NotificationService,
+	location *time.Location,
+) *ReminderService {
+	return &ReminderService{
+		bookingRepo:  bookingRepo,
+		settingsRepo: settingsRepo,
+		notifier:     notifier,
+		location:     location,
+	}
+}
+
+// SendDue sends reminders for bookings that have entered their reminder
+// window and returns how many were sent
+func (s *ReminderService) SendDue(now time.Time) (int, error) {
+	defaultHours, err := s.settingsRepo.GetFloat(model.SettingsKeyReminderHoursBefore, model.DefaultReminderHoursBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	// Booking dates are UTC midnights of the salon-local date
+	local := now.In(s.location)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	maxDays := model.MaxReminderHoursBefore/24 + 1
+	bookings, err := s.bookingRepo.GetAwaitingReminder(today, today.AddDate(0, 0, maxDays))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range bookings {
+		booking := &bookings[i]
+		start, err := time.ParseInLocation("2006-01-02 15:04",
+			booking.BookingDate.Format("2006-01-02")+" "+booking.StartTime, s.location)
+		if err != nil || !start.After(now) {
+			continue
+		}
+
+		hours := int(defaultHours)
+		if booking.User.ReminderHoursBefore != nil {
+			hours = *booking.User.ReminderHoursBefore
+		}
+		if now.Before(start.Add(-time.Duration(hours) * time.Hour)) {
+			continue
+		}
+
+		if s.send(booking, now) {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// SendForDate sends reminders for every pending/confirmed booking on date
+// that hasn't had one yet, regardless of customer lead-time preferences.
+// Used by the once-a-day schedule.
+func (s *ReminderService) SendForDate(date time.Time, now time.Time) (int, error) {
+	bookings, err := s.bookingRepo.GetByDateAndStatuses(date,
+		[]string{model.BookingStatusPending, model.BookingStatusConfirmed})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range bookings {
+		if bookings[i].ReminderSentAt != nil {
+			continue
+		}
+		if s.send(&bookings[i], now) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// send claims the booking's reminder and emails it, reporting whether it
+// went out
+func (s *ReminderService) send(booking *model.Booking, now time.Time) bool {
+	to := booking.CustomerEmail
+	if to == "" {
+		to = booking.User.Email
+	}
+	if to == "" {
+		return false
+	}
+
+	// Claim the booking first so a second instance doesn't send it too
+	claimed, err := s.bookingRepo.MarkReminderSent(booking.ID, now)
+	if err != nil {
+		log.Printf("⚠️  Failed to mark reminder for booking #%d: %v", booking.ID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	if err := s.notifier.SendBookingReminder(to, booking); err != nil {
+		log.Printf("⚠️  Failed to send reminder for booking #%d: %v", booking.ID, err)
+		return false
+	}
+	return true
+}
+
+// NextDailyRun returns the next time at or after now that the clock in the
+// salon timezone reads sendTime (HH:MM)
+func (s *ReminderService) NextDailyRun(now time.Time, sendTime string) time.Time {
+	t, _ := time.Parse("15:04", sendTime)
+	local := now.In(s.location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), t.Hour(), t.Minute(), 0, 0, s.location)
+	if next.Before(local) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SalonDate returns the salon-local date `days` after now's, as a UTC
+// midnight like stored booking dates
+func (s *ReminderService) SalonDate(now time.Time, days int) time.Time {
+	local := now.In(s.location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+}
@@ -24,9 +24,12 @@ type S3Service struct {
 }
 
 func NewS3Service() (*S3Service, error) {
-	bucketName := os.Getenv("AWS_S3_BUCKET")
+	bucketName := os.Getenv("S3_BUCKET")
 	if bucketName == "" {
-		bucketName = "linda-salon-assets"
+		bucketName = os.Getenv("AWS_S3_BUCKET")
+	}
+	if bucketName == "" {
+		return nil, fmt.Errorf("S3_BUCKET is not set")
 	}
 
 	region := os.Getenv("AWS_REGION")
@@ -66,6 +69,15 @@ func NewS3Service() (*S3Service, error) {
 	}, nil
 }
 
+// NewS3ServiceWithClient 使用既有的 S3 client 與 bucket 設定建立服務
+func NewS3ServiceWithClient(client *s3.Client, bucketName, region string) *S3Service {
+	return &S3Service{
+		client:     client,
+		bucketName: bucketName,
+		region:     region,
+	}
+}
+
 // UploadFile 上傳檔案到 S3
 func (s *S3Service) UploadFile(ctx context.Context, file *multipart.FileHeader, folder string) (string, error) {
 	// 打開檔案
@@ -125,12 +137,39 @@ func (s *S3Service) DeleteFile(ctx context.Context, fileURL string) error {
 	return nil
 }
 
+// OwnsURL 判斷 URL 是否指向本服務的 bucket
+func (s *S3Service) OwnsURL(fileURL string) bool {
+	return s.extractKeyFromURL(fileURL) != ""
+}
+
+// KeyFromURL 回傳 URL 在本服務 bucket 內的 key，不屬於 bucket 時回傳空字串
+func (s *S3Service) KeyFromURL(fileURL string) string {
+	return s.extractKeyFromURL(fileURL)
+}
+
+// AvatarKeyPrefix 使用者上傳頭像的 key 前綴（avatars/<userID>/），
+// 刪除頭像時只會刪除此前綴下的檔案
+func AvatarKeyPrefix(userID uint) string {
+	return fmt.Sprintf("avatars/%d/", userID)
+}
+
+// IsUserAvatarKey 判斷 key 是否為該使用者上傳的頭像
+func IsUserAvatarKey(userID uint, key string) bool {
+	prefix := AvatarKeyPrefix(userID)
+	return strings.HasPrefix(key, prefix) && len(key) > len(prefix) && !strings.Contains(key, "..")
+}
+
 // extractKeyFromURL 從 S3 URL 提取 key
 func (s *S3Service) extractKeyFromURL(url string) string {
+	return S3KeyFromURL(s.bucketName, url)
+}
+
+// S3KeyFromURL 從 bucket 的公開網址提取 object key，網址不屬於該 bucket 時回傳空字串
+func S3KeyFromURL(bucketName, url string) string {
 	// 支援格式:
 	// https://bucket.s3.region.amazonaws.com/path/to/file
 	// https://bucket.s3.amazonaws.com/path/to/file
-	prefix := fmt.Sprintf("https://%s.s3", s.bucketName)
+	prefix := fmt.Sprintf("https://%s.s3.", bucketName)
 	if !strings.HasPrefix(url, prefix) {
 		return ""
 	}
@@ -0,0 +1,49 @@
+package service
+
+import "testing"
+
+func TestS3KeyFromURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"regional URL", "https://salon.s3.ap-northeast-1.amazonaws.com/avatars/7/a.jpg", "avatars/7/a.jpg"},
+		{"global URL", "https://salon.s3.amazonaws.com/services/b.png", "services/b.png"},
+		{"other bucket", "https://salon-other.s3.ap-northeast-1.amazonaws.com/avatars/7/a.jpg", ""},
+		{"external URL", "https://lh3.googleusercontent.com/a/photo.jpg", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := S3KeyFromURL("salon", tt.url); got != tt.want {
+				t.Errorf("S3KeyFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUserAvatarKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{"own avatar", "avatars/7/a.jpg", true},
+		{"another user's avatar", "avatars/8/a.jpg", false},
+		{"user ID prefix of another", "avatars/77/a.jpg", false},
+		{"legacy avatar without user folder", "avatars/a.jpg", false},
+		{"service image", "services/a.jpg", false},
+		{"thumbnail", "thumb/avatars/7/a.jpg", false},
+		{"prefix only", "avatars/7/", false},
+		{"path traversal", "avatars/7/../../services/a.jpg", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUserAvatarKey(7, tt.key); got != tt.want {
+				t.Errorf("IsUserAvatarKey(7, %q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// LoadSalonContact returns the salon's contact details. When no name is
+// configured the branding name (or its default) is used instead.
+func LoadSalonContact(settingsRepo *repository.SettingsRepository) (model.SalonContactConfig, error) {
+	var contact model.SalonContactConfig
+	if _, err := settingsRepo.GetValue(model.SettingsKeySalonContact, &contact); err != nil {
+		return contact, err
+	}
+
+	if contact.Name == "" {
+		branding := model.DefaultBrandingConfig()
+		if _, err := settingsRepo.GetValue(model.SettingsKeyBranding, &branding); err != nil {
+			return contact, err
+		}
+		contact.Name = branding.Name
+	}
+
+	return contact, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"linda-salon-api/internal/model"
+	"linda-salon-api/internal/repository"
+)
+
+// SeedDefaultSettings writes the canonical default settings that don't exist
+// yet. Existing rows are never touched, so admin customizations survive
+// restarts. Returns the number of settings created.
+func SeedDefaultSettings(settingsRepo *repository.SettingsRepository) (int, error) {
+	created := 0
+	for _, def := range model.DefaultSettings() {
+		value, err := json.Marshal(def.Value)
+		if err != nil {
+			return created, fmt.Errorf("failed to serialize default %s: %w", def.Key, err)
+		}
+
+		inserted, err := settingsRepo.CreateIfAbsent(&model.Settings{
+			Key:      def.Key,
+			Value:    string(value),
+			Category: def.Category,
+		})
+		if err != nil {
+			return created, fmt.Errorf("failed to seed default %s: %w", def.Key, err)
+		}
+		if inserted {
+			created++
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// SlotCacheTTL is how long generated day slots are reused. Bookings and holds
+// invalidate their stylist's date right away; the TTL only bounds staleness
+// from changes that don't (holds expiring, schedule edits by other instances).
+const SlotCacheTTL = 30 * time.Second
+
+// maxSlotCacheEntries caps the cache; expired entries are swept when it's
+// reached, and everything is dropped if that doesn't free room
+const maxSlotCacheEntries = 10000
+
+type slotCacheKey struct {
+	stylistID uint
+	date      string // YYYY-MM-DD
+	duration  int
+	interval  int
+	batch     bool // generated by rangeSlots rather than DaySlots
+}
+
+type slotCacheEntry struct {
+	slots     []TimeSlot
+	expiresAt time.Time
+}
+
+// slotCache keeps recently generated day slots in memory, keyed by stylist,
+// date, duration and slot interval, so browsing the booking calendar doesn't
+// recompute the same days from the database on every request.
+//
+// Every invalidation bumps a version; slots computed from data read before
+// an invalidation are not stored, so a booking made while a lookup is in
+// flight can't be masked by the stale result.
+type slotCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[slotCacheKey]slotCacheEntry
+	version uint64
+}
+
+func newSlotCache(ttl time.Duration) *slotCache {
+	return &slotCache{
+		ttl:     ttl,
+		entries: make(map[slotCacheKey]slotCacheEntry),
+	}
+}
+
+// currentVersion returns the version to pass to set for data read from now on
+func (c *slotCache) currentVersion() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// get returns a copy of the cached slots for key, if present and fresh
+func (c *slotCache) get(key slotCacheKey) ([]TimeSlot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return append([]TimeSlot(nil), entry.slots...), true
+}
+
+// set stores slots computed from data read at version; it's a no-op when
+// anything was invalidated since
+func (c *slotCache) set(key slotCacheKey, slots []TimeSlot, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version {
+		return
+	}
+
+	now := time.Now()
+	if len(c.entries) >= maxSlotCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxSlotCacheEntries {
+			c.entries = make(map[slotCacheKey]slotCacheEntry)
+		}
+	}
+	c.entries[key] = slotCacheEntry{
+		slots:     append([]TimeSlot(nil), slots...),
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// clear drops every cached entry
+func (c *slotCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.entries = make(map[slotCacheKey]slotCacheEntry)
+}
+
+// invalidate drops every cached duration of a stylist's date; an empty date
+// drops all of the stylist's dates
+func (c *slotCache) invalidate(stylistID uint, date string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	for key := range c.entries {
+		if key.stylistID == stylistID && (date == "" || key.date == date) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	_ "golang.org/x/image/webp" // register the WebP decoder for image.Decode
+)
+
+// ThumbnailPrefix is prepended to an uploaded image's key to store its thumbnail
+const ThumbnailPrefix = "thumb/"
+
+// DefaultThumbnailSize is the default longest edge of a thumbnail, in pixels
+const DefaultThumbnailSize = 300
+
+// maxThumbnailSourcePixels keeps a small file that decodes to a huge image
+// (a decompression bomb) from being decoded at all
+const maxThumbnailSourcePixels = 50_000_000
+
+// thumbnailJPEGQuality balances size and quality for list views
+const thumbnailJPEGQuality = 85
+
+// ErrThumbnailNotNeeded is returned when the image already fits the
+// thumbnail size, so the original can serve as its own thumbnail
+var ErrThumbnailNotNeeded = errors.New("image already fits the thumbnail size")
+
+// ThumbnailKey returns the S3 key of the thumbnail for an uploaded image key
+func ThumbnailKey(key string) string {
+	return ThumbnailPrefix + key
+}
+
+// ThumbnailURL returns the URL of the thumbnail for an uploaded image's S3 URL
+func ThumbnailURL(fileURL string) string {
+	return strings.Replace(fileURL, ".amazonaws.com/", ".amazonaws.com/"+ThumbnailPrefix, 1)
+}
+
+// ImageDimensions returns an image's width and height without decoding its
+// pixels. An error means the data isn't a readable JPEG, PNG, GIF or WebP.
+func ImageDimensions(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// MakeThumbnail scales an image down so its longest edge is size pixels,
+// keeping the aspect ratio. PNGs, GIFs and WebPs with transparency become
+// PNG (logos and icons keep their transparency; an animated GIF's thumbnail
+// is its first frame); everything else is encoded as JPEG. Images that
+// can't be decoded return an error, so the caller can fall back to the
+// original.
+func MakeThumbnail(data []byte, size int) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return nil, "", fmt.Errorf("image is %dx%d, too large to thumbnail", cfg.Width, cfg.Height)
+	}
+	if cfg.Width <= size && cfg.Height <= size {
+		return nil, "", ErrThumbnailNotNeeded
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	width, height := size, size
+	if cfg.Width > cfg.Height {
+		height = max(cfg.Height*size/cfg.Width, 1)
+	} else {
+		width = max(cfg.Width*size/cfg.Height, 1)
+	}
+	thumb := downscale(src, width, height)
+
+	var buf bytes.Buffer
+	if format == "png" || format == "gif" || (format == "webp" && !isOpaque(src)) {
+		if err := png.Encode(&buf, thumb); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// isOpaque reports whether every pixel of img is fully opaque, as far as its
+// type can tell
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// downscale resizes src to width x height by averaging the source pixels
+// each destination pixel covers (a box filter), which is cheap and avoids
+// the aliasing of nearest-neighbour sampling when shrinking a lot
+func downscale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	// Convert once so the loop below reads Pix directly; draw has fast
+	// paths for the JPEG (YCbCr) and PNG (RGBA/NRGBA) decoders' images
+	rgba := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max(y0+1, (y+1)*srcH/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max(x0+1, (x+1)*srcW/width)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// encodeTestImage returns a width x height image in the given format
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read testdata: %v", err)
+	}
+	return data
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		size            int
+		wantContentType string
+		wantWidth       int
+		wantHeight      int
+	}{
+		{"landscape JPEG", encodeTestImage(t, "jpeg", 400, 200), 100, "image/jpeg", 100, 50},
+		{"portrait PNG", encodeTestImage(t, "png", 200, 400), 100, "image/png", 50, 100},
+		{"GIF", encodeTestImage(t, "gif", 300, 300), 100, "image/png", 100, 100},
+		// 150x103 lossy WebP without an alpha channel
+		{"opaque WebP", readTestdata(t, "opaque.webp"), 75, "image/jpeg", 75, 51},
+		// 400x301 lossy WebP with an alpha channel
+		{"transparent WebP", readTestdata(t, "alpha.webp"), 100, "image/png", 100, 75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, contentType, err := MakeThumbnail(tt.data, tt.size)
+			if err != nil {
+				t.Fatalf("MakeThumbnail() error = %v", err)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("content type = %s, want %s", contentType, tt.wantContentType)
+			}
+			width, height, err := ImageDimensions(thumb)
+			if err != nil {
+				t.Fatalf("thumbnail doesn't decode: %v", err)
+			}
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestMakeThumbnailNotNeeded(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"small PNG":  encodeTestImage(t, "png", 80, 60),
+		"small WebP": readTestdata(t, "opaque.webp"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := MakeThumbnail(data, 300); !errors.Is(err, ErrThumbnailNotNeeded) {
+				t.Errorf("MakeThumbnail() error = %v, want ErrThumbnailNotNeeded", err)
+			}
+		})
+	}
+}
+
+func TestImageDimensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{"JPEG", encodeTestImage(t, "jpeg", 64, 48), 64, 48, false},
+		{"WebP", readTestdata(t, "opaque.webp"), 150, 103, false},
+		{"not an image", []byte("definitely not an image"), 0, 0, true},
+		{"truncated PNG", encodeTestImage(t, "png", 64, 48)[:20], 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, err := ImageDimensions(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImageDimensions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("ImageDimensions() = %dx%d, want %dx%d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}